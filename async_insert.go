@@ -0,0 +1,119 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	asyncInsertParamName        = "async_insert"
+	waitForAsyncInsertParamName = "wait_for_async_insert"
+	queryIDHeader               = "X-ClickHouse-Query-Id"
+)
+
+var (
+	// settingsClauseRe matches the SETTINGS clause of a query
+	settingsClauseRe = regexp.MustCompile(`(?is)\bSETTINGS\s+(\w+\s*=\s*(?:'[^']*'|[\w.+-]+)(?:\s*,\s*\w+\s*=\s*(?:'[^']*'|[\w.+-]+))*)`)
+	// settingRe matches a setting of the SETTINGS clause
+	settingRe = regexp.MustCompile(`(\w+)\s*=\s*('[^']*'|[\w.+-]+)`)
+)
+
+// asyncInsert are the settings of asynchronous inserts
+type asyncInsert struct {
+	enabled bool
+	wait    bool
+}
+
+// WithAsyncInsert returns a context which overrides the async insert settings
+// of the connection for INSERT statements. If wait is false, the INSERT
+// returns without waiting for the data to be written to the table.
+func WithAsyncInsert(ctx context.Context, enabled, wait bool) context.Context {
+	return context.WithValue(ctx, ctxAsyncInsertKey, asyncInsert{enabled: enabled, wait: wait})
+}
+
+// asyncInsertSettings returns the async insert settings of the query,
+// false is returned if the query is not an asynchronous INSERT
+func (c *conn) asyncInsertSettings(ctx context.Context, query string) (asyncInsert, bool) {
+	settings := asyncInsert{enabled: c.asyncInsert, wait: c.asyncInsertWait}
+	if ctx != nil {
+		if s, ok := ctx.Value(ctxAsyncInsertKey).(asyncInsert); ok {
+			settings = s
+		}
+	}
+	if !settings.enabled || statementKeyword(query) != "INSERT" {
+		return asyncInsert{}, false
+	}
+	return settings, true
+}
+
+// sentAsyncInsert returns the async insert settings which have been sent with
+// the request of the query, the SETTINGS clause of the query overrides the
+// parameters of the request. false is returned if the query is not an asynchronous INSERT
+func sentAsyncInsert(query string, params url.Values) (asyncInsert, bool) {
+	if statementKeyword(query) != "INSERT" {
+		return asyncInsert{}, false
+	}
+	settings := querySettings(query)
+	setting := func(name string) string {
+		if value, ok := settings[name]; ok {
+			return value
+		}
+		return params.Get(name)
+	}
+	if enabled, _ := strconv.ParseBool(setting(asyncInsertParamName)); !enabled {
+		return asyncInsert{}, false
+	}
+	// wait_for_async_insert is enabled by default
+	wait := true
+	if value := setting(waitForAsyncInsertParamName); value != "" {
+		wait, _ = strconv.ParseBool(value)
+	}
+	return asyncInsert{enabled: true, wait: wait}, true
+}
+
+// querySettings returns the settings of the SETTINGS clause of the query
+func querySettings(query string) map[string]string {
+	match := settingsClauseRe.FindStringSubmatch(query)
+	if match == nil {
+		return nil
+	}
+	settings := make(map[string]string)
+	for _, setting := range settingRe.FindAllStringSubmatch(match[1], -1) {
+		settings[setting[1]] = strings.Trim(setting[2], "'")
+	}
+	return settings
+}
+
+// AsyncInsertStatus describes an INSERT executed with async_insert
+type AsyncInsertStatus struct {
+	QueryID string
+	// Flushed reports whether the data has been written to the table when
+	// the INSERT returned, it is false if wait_for_async_insert is disabled
+	Flushed bool
+}
+
+// AsyncInsertReporter is implemented by the driver.Result of an INSERT
+// executed with async_insert. It is available through sql.Conn.Raw.
+type AsyncInsertReporter interface {
+	AsyncInsert() AsyncInsertStatus
+}
+
+// asyncInsertResult adds the status of an async insert to the result
+type asyncInsertResult struct {
+	driver.Result
+	status AsyncInsertStatus
+}
+
+// QueryStats implements QueryStatsReporter
+func (r *asyncInsertResult) QueryStats() (QueryStats, bool) {
+	return r.Result.(QueryStatsReporter).QueryStats()
+}
+
+// AsyncInsert implements AsyncInsertReporter
+func (r *asyncInsertResult) AsyncInsert() AsyncInsertStatus {
+	return r.status
+}
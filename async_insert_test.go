@@ -0,0 +1,162 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncInsertParams(t *testing.T) {
+	testCases := []struct {
+		dsn   string
+		ctx   func(context.Context) context.Context
+		query string
+		async string
+		wait  string
+	}{
+		{dsn: "", query: "INSERT INTO t VALUES (1)"},
+		{dsn: "async_insert=1", query: "INSERT INTO t VALUES (1)", async: "1", wait: "1"},
+		{dsn: "async_insert=1&wait_for_async_insert=0", query: "insert into t values (1)", async: "1", wait: "0"},
+		{dsn: "async_insert=1", query: "SELECT 1"},
+		{
+			dsn:   "",
+			ctx:   func(ctx context.Context) context.Context { return WithAsyncInsert(ctx, true, false) },
+			query: "INSERT INTO t VALUES (1)",
+			async: "1",
+			wait:  "0",
+		},
+		{
+			dsn:   "async_insert=1",
+			ctx:   func(ctx context.Context) context.Context { return WithAsyncInsert(ctx, false, false) },
+			query: "INSERT INTO t VALUES (1)",
+		},
+		{
+			// the settings passed explicitly are kept
+			dsn: "async_insert=1",
+			ctx: func(ctx context.Context) context.Context {
+				return context.WithValue(ctx, RequestQueryParams, map[string]string{asyncInsertParamName: "0"})
+			},
+			query: "INSERT INTO t VALUES (1)",
+			async: "0",
+		},
+	}
+	for _, tc := range testCases {
+		cfg, err := ParseDSN("http://localhost:8123/default?" + tc.dsn)
+		require.NoError(t, err)
+		c := newConn(cfg)
+		ctx := context.Background()
+		if tc.ctx != nil {
+			ctx = tc.ctx(ctx)
+		}
+		req, err := c.buildRequest(ctx, tc.query, nil)
+		if assert.NoError(t, err, tc.dsn) {
+			params := req.URL.Query()
+			assert.Equal(t, tc.async, params.Get(asyncInsertParamName), "%s: %s", tc.dsn, tc.query)
+			assert.Equal(t, tc.wait, params.Get(waitForAsyncInsertParamName), "%s: %s", tc.dsn, tc.query)
+		}
+	}
+}
+
+func TestSentAsyncInsert(t *testing.T) {
+	testCases := []struct {
+		query  string
+		params string
+		async  asyncInsert
+		ok     bool
+	}{
+		{query: "INSERT INTO t VALUES (1)", params: "async_insert=1", async: asyncInsert{enabled: true, wait: true}, ok: true},
+		{query: "INSERT INTO t VALUES (1)", params: "async_insert=1&wait_for_async_insert=0", async: asyncInsert{enabled: true}, ok: true},
+		{query: "INSERT INTO t VALUES (1)", params: "async_insert=0&wait_for_async_insert=1"},
+		{query: "INSERT INTO t VALUES (1)"},
+		{query: "SELECT 1", params: "async_insert=1"},
+		{query: "INSERT INTO t SETTINGS async_insert=0 VALUES (1)", params: "async_insert=1"},
+		{
+			query: "INSERT INTO t SETTINGS async_insert = 1, wait_for_async_insert = 'false' FORMAT TSV",
+			async: asyncInsert{enabled: true},
+			ok:    true,
+		},
+	}
+	for _, tc := range testCases {
+		params, err := url.ParseQuery(tc.params)
+		require.NoError(t, err)
+		async, ok := sentAsyncInsert(tc.query, params)
+		assert.Equal(t, tc.ok, ok, tc.query)
+		assert.Equal(t, tc.async, async, tc.query)
+	}
+}
+
+func TestAsyncInsertResult(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		params []url.Values
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		params = append(params, r.URL.Query())
+		mu.Unlock()
+		w.Header().Set(queryIDHeader, "query-1")
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open("chhttp", srv.URL+"/default?async_insert=1&wait_for_async_insert=0")
+	require.NoError(t, err)
+	defer db.Close()
+
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+	err = sqlConn.Raw(func(driverConn interface{}) error {
+		c := driverConn.(*conn)
+		res, err := c.ExecContext(context.Background(), "INSERT INTO t VALUES (1)", nil)
+		require.NoError(t, err)
+		reporter, ok := res.(AsyncInsertReporter)
+		if assert.True(t, ok) {
+			assert.Equal(t, AsyncInsertStatus{QueryID: "query-1"}, reporter.AsyncInsert())
+		}
+		_, ok = res.(QueryStatsReporter)
+		assert.True(t, ok)
+
+		res, err = c.ExecContext(WithAsyncInsert(context.Background(), true, true), "INSERT INTO t VALUES (1)", nil)
+		require.NoError(t, err)
+		assert.Equal(t, AsyncInsertStatus{QueryID: "query-1", Flushed: true}, res.(AsyncInsertReporter).AsyncInsert())
+
+		res, err = c.ExecContext(context.Background(), "ALTER TABLE t DELETE WHERE 1", nil)
+		require.NoError(t, err)
+		_, ok = res.(AsyncInsertReporter)
+		assert.False(t, ok)
+
+		// the status is derived from the settings which have been sent
+		res, err = c.ExecContext(context.WithValue(context.Background(), RequestQueryParams,
+			map[string]string{asyncInsertParamName: "0"}), "INSERT INTO t VALUES (1)", nil)
+		require.NoError(t, err)
+		_, ok = res.(AsyncInsertReporter)
+		assert.False(t, ok)
+
+		res, err = c.ExecContext(context.Background(), "INSERT INTO t SETTINGS async_insert = 0 VALUES (1)", nil)
+		require.NoError(t, err)
+		_, ok = res.(AsyncInsertReporter)
+		assert.False(t, ok)
+
+		res, err = c.ExecContext(context.WithValue(context.Background(), RequestQueryParams,
+			map[string]string{waitForAsyncInsertParamName: "1"}), "INSERT INTO t VALUES (1)", nil)
+		require.NoError(t, err)
+		assert.Equal(t, AsyncInsertStatus{QueryID: "query-1", Flushed: true}, res.(AsyncInsertReporter).AsyncInsert())
+		return nil
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, params, 6)
+	assert.Equal(t, []string{"1"}, params[5][waitForAsyncInsertParamName])
+	assert.Equal(t, "0", params[0].Get(waitForAsyncInsertParamName))
+	assert.Equal(t, "1", params[1].Get(waitForAsyncInsertParamName))
+	assert.Empty(t, params[2].Get(asyncInsertParamName))
+}
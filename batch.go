@@ -0,0 +1,532 @@
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errBatchAborted interrupts the request of a batch, which is rolled back
+var errBatchAborted = errors.New("clickhouse: batch is aborted")
+
+// quotedIdentifier matches a name quoted with backquotes or double quotes, which can contain escaped quotes
+const quotedIdentifier = "`(?:[^`\\\\]|\\\\.)*`" + `|"(?:[^"\\]|\\.)*"`
+
+var (
+	// insertTableRe splits an INSERT without data into the table and the list of columns
+	insertTableRe = regexp.MustCompile(`(?si)^\s*INSERT\s+INTO\s+(?:TABLE\s+)?((?:[\w.]|` + quotedIdentifier + `)+)\s*` +
+		`(?:\(((?:[^()` + "`" + `"]|` + quotedIdentifier + `)*)\))?\s*$`)
+	// columnNameRe matches the names in the list of columns
+	columnNameRe = regexp.MustCompile(quotedIdentifier + `|[^,\s]+`)
+	// escapedCharRe matches the escaped characters of a quoted name
+	escapedCharRe = regexp.MustCompile(`\\(.)`)
+	// rowPlaceholdersRe matches the rows consisting of placeholders only: (?, ?)
+	rowPlaceholdersRe = regexp.MustCompile(`^\(\s*\?(?:\s*,\s*\?)*\s*\)$`)
+)
+
+// tableColumn is a column returned by DESCRIBE TABLE
+type tableColumn struct {
+	name string
+	typ  string
+}
+
+// binaryColumn is a column of a batch INSERT encoded in RowBinary
+type binaryColumn struct {
+	name    string
+	encoder binaryEncoder
+}
+
+// batchWriter streams the rows of a batch INSERT to ClickHouse in the body of
+// a request, so the rows are not kept in memory till the commit
+type batchWriter struct {
+	pw        *io.PipeWriter
+	gz        *gzip.Writer
+	w         io.Writer // writes to pw, compressing the data if needed
+	separator string
+	rows      int
+	size      int64 // uncompressed size of the body
+	cancel    context.CancelFunc
+	done      chan error
+}
+
+// parseInsert returns the table and the listed columns of an INSERT without data
+func parseInsert(query string) (table string, names []string, ok bool) {
+	match := insertTableRe.FindStringSubmatch(query)
+	if match == nil {
+		return "", nil, false
+	}
+	for _, name := range columnNameRe.FindAllString(match[2], -1) {
+		names = append(names, unquoteIdentifier(name))
+	}
+	return match[1], names, true
+}
+
+// unquoteIdentifier removes the quotes and the escaping of a quoted name
+func unquoteIdentifier(name string) string {
+	if len(name) < 2 || name[0] != name[len(name)-1] || name[0] != '`' && name[0] != '"' {
+		return name
+	}
+	return escapedCharRe.ReplaceAllString(name[1:len(name)-1], "$1")
+}
+
+// describeInsert returns the columns of a batch INSERT like PrepareBatch does. The binary
+// columns are returned if its rows can be encoded in RowBinary, otherwise the rows are
+// encoded as text and the values of the Enum columns are checked by the enums columns.
+// Both are nil if the column types are unknown.
+func (c *conn) describeInsert(ctx context.Context, prefix, pattern string) (binary, enums []binaryColumn) {
+	table, names, ok := parseInsert(prefix[:len(prefix)-len("VALUES")])
+	if !ok || !rowPlaceholdersRe.MatchString(pattern) {
+		return nil, nil
+	}
+	described, location, err := c.describeTable(ctx, table, names)
+	if err != nil {
+		c.log("batch columns are unknown: ", err)
+		return nil, nil
+	}
+	if len(described) != len(placeholders(pattern)) {
+		c.log("batch columns are unknown: the number of columns does not match the number of placeholders")
+		return nil, nil
+	}
+	if c.rowBinaryInsert {
+		if binary, err = binaryColumns(described, location); err == nil {
+			return binary, nil
+		}
+		c.log("batch is encoded as text: ", err)
+	}
+	return nil, enumColumns(described)
+}
+
+// describeTable returns the columns of the table with the given names,
+// all the columns which can be inserted are returned if names are empty.
+// The time zone of the server is returned as well.
+func (c *conn) describeTable(ctx context.Context, table string, names []string) ([]tableColumn, *time.Location, error) {
+	req, err := c.buildRequest(ctx, "DESCRIBE TABLE "+table, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, header, err := c.doRequest(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := c.newRows(body, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[string]int)
+	for i, name := range rows.Columns() {
+		index[name] = i
+	}
+	for _, name := range []string{"name", "type", "default_type"} {
+		if _, ok := index[name]; !ok {
+			return nil, nil, fmt.Errorf("DESCRIBE TABLE has not returned the column %s", name)
+		}
+	}
+	var (
+		types    = make(map[string]string)
+		inserted []string
+		values   = make([]driver.Value, len(index))
+	)
+	for {
+		if err = rows.Next(values); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		name, _ := values[index["name"]].(string)
+		typ, _ := values[index["type"]].(string)
+		defaultType, _ := values[index["default_type"]].(string)
+		types[name] = typ
+		switch defaultType {
+		case "MATERIALIZED", "ALIAS", "EPHEMERAL":
+			// such columns are not inserted unless they are listed
+		default:
+			inserted = append(inserted, name)
+		}
+	}
+	if len(names) == 0 {
+		names = inserted
+	}
+
+	columns := make([]tableColumn, len(names))
+	for i, name := range names {
+		typ, ok := types[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown column %s", name)
+		}
+		columns[i] = tableColumn{name: name, typ: typ}
+	}
+	return columns, serverLocation(header), nil
+}
+
+// binaryColumns creates the encoders of the columns
+func binaryColumns(columns []tableColumn, location *time.Location) ([]binaryColumn, error) {
+	encoded := make([]binaryColumn, len(columns))
+	for i, column := range columns {
+		desc, err := ParseTypeDesc(column.typ)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the type of the column %s: %w", column.name, err)
+		}
+		encoded[i].name = column.name
+		if encoded[i].encoder, err = newBinaryEncoder(desc, location); err != nil {
+			return nil, fmt.Errorf("failed to create an encoder for the column %s: %w", column.name, err)
+		}
+	}
+	return encoded, nil
+}
+
+// startBatch sends a request whose body begins with the query,
+// the rows are written to the body by the returned batchWriter
+// and separated by the separator
+func (c *conn) startBatch(ctx context.Context, query, separator string) (*batchWriter, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	transport := c.transport
+	if transport == nil {
+		return nil, driver.ErrBadConn
+	}
+	c.log("batch: ", query)
+
+	bodyReader, bodyWriter := io.Pipe()
+	req, err := c.newRequest(ctx, query, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	b := &batchWriter{
+		pw:        bodyWriter,
+		w:         bodyWriter,
+		separator: separator,
+		cancel:    cancel,
+		done:      make(chan error, 1),
+	}
+	if c.useGzipCompression {
+		b.gz = gzip.NewWriter(bodyWriter)
+		b.w = b.gz
+	}
+	go func() {
+		// the body is streamed, so the request can't be repeated
+		body, _, err := c.sendRequest(ctx, transport, req, false)
+		if body != nil {
+			// Drain body to enable connection reuse
+			_, _ = io.Copy(io.Discard, body)
+			_ = body.Close()
+		}
+		// the rows written after the request has finished are not sent anywhere
+		_ = bodyReader.CloseWithError(err)
+		b.done <- err
+	}()
+	n, err := io.WriteString(b.w, query)
+	b.size = int64(n)
+	if err != nil {
+		return nil, b.fail(err)
+	}
+	return b, nil
+}
+
+// append writes the row to the request
+func (b *batchWriter) append(row []byte) error {
+	if b.rows > 0 && b.separator != "" {
+		n, err := io.WriteString(b.w, b.separator)
+		b.size += int64(n)
+		if err != nil {
+			return b.fail(err)
+		}
+	}
+	n, err := b.w.Write(row)
+	b.size += int64(n)
+	if err != nil {
+		return b.fail(err)
+	}
+	b.rows++
+	return nil
+}
+
+// full reports whether the batch has reached one of the limits, zero limits are ignored
+func (b *batchWriter) full(maxRows int, maxBytes int64) bool {
+	return (maxRows > 0 && b.rows >= maxRows) || (maxBytes > 0 && b.size >= maxBytes)
+}
+
+// finish completes the body of the request and waits for its result
+func (b *batchWriter) finish() error {
+	if b.gz != nil {
+		if err := b.gz.Close(); err != nil {
+			return b.fail(err)
+		}
+	}
+	_ = b.pw.Close()
+	err := <-b.done
+	b.cancel()
+	return err
+}
+
+// abort interrupts the request, so the body is never completed
+func (b *batchWriter) abort() {
+	_ = b.pw.CloseWithError(errBatchAborted)
+	b.cancel()
+	<-b.done
+}
+
+// fail interrupts the request after a failed write. The error of the request
+// is returned if there is any, since it explains why the write has failed.
+func (b *batchWriter) fail(err error) error {
+	_ = b.pw.CloseWithError(err)
+	reqErr := <-b.done
+	b.cancel()
+	if reqErr != nil {
+		return reqErr
+	}
+	return err
+}
+
+// Batch sends rows to a table in one INSERT, the rows are streamed to ClickHouse
+// as they are appended. It is created by Conn.PrepareBatch and must be used only
+// inside of the sql.Conn.Raw callback. A Batch is not safe for concurrent use.
+type Batch struct {
+	c       *conn
+	ctx     context.Context
+	query   string
+	columns []string
+	binary  []binaryColumn // nil if the rows are encoded as text
+	enums   []binaryColumn // the Enum columns whose values are checked if the rows are encoded as text
+	writer  *batchWriter
+	row     []byte
+	done    bool
+	err     error // the error of a failed write, the rows of the batch are lost then
+}
+
+// PrepareBatch implements Conn
+func (c *conn) PrepareBatch(ctx context.Context, query string) (*Batch, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	table, names, ok := parseInsert(query)
+	if !ok {
+		return nil, fmt.Errorf("clickhouse: batch query must be INSERT INTO table (columns), got: %s", query)
+	}
+	b := &Batch{c: c, ctx: ctx, query: strings.TrimSpace(query), columns: names}
+	described, location, err := c.describeTable(ctx, table, names)
+	if err != nil {
+		if len(names) > 0 && !c.rowBinaryInsert {
+			// the listed columns are enough to send the rows as text
+			c.log("batch columns are unknown: ", err)
+			c.openBatch(b)
+			return b, nil
+		}
+		return nil, fmt.Errorf("clickhouse: failed to get the columns of the table %s: %w", table, err)
+	}
+	b.columns = make([]string, len(described))
+	for i, column := range described {
+		b.columns[i] = column.name
+	}
+	if c.rowBinaryInsert {
+		if b.binary, err = binaryColumns(described, location); err != nil {
+			c.log("batch is encoded as text: ", err)
+		}
+	}
+	if b.binary == nil {
+		b.enums = enumColumns(described)
+	}
+	c.openBatch(b)
+	return b, nil
+}
+
+// openBatch keeps the batch till it is sent or aborted, so it is aborted by Close
+func (c *conn) openBatch(b *Batch) {
+	if c.batches == nil {
+		c.batches = make(map[*Batch]struct{})
+	}
+	c.batches[b] = struct{}{}
+}
+
+// checkEnum checks the value of an Enum column of a row encoded as text,
+// the values of the columns without encoders are not checked
+func (column binaryColumn) checkEnum(v interface{}) error {
+	if column.encoder == nil {
+		return nil
+	}
+	if _, err := encodeBinary(column.encoder, nil, v); err != nil {
+		return fmt.Errorf("clickhouse: invalid value of the column %s: %w", column.name, err)
+	}
+	return nil
+}
+
+// enumColumns creates the encoders of the Enum columns, which check the values
+// of the rows encoded as text, the other columns have nil encoders
+func enumColumns(columns []tableColumn) []binaryColumn {
+	var enums []binaryColumn
+	for i, column := range columns {
+		desc, err := ParseTypeDesc(column.typ)
+		if err != nil || enumType(desc) == nil {
+			continue
+		}
+		encoder, err := newBinaryEncoder(desc, nil)
+		if err != nil {
+			continue
+		}
+		if enums == nil {
+			enums = make([]binaryColumn, len(columns))
+		}
+		enums[i] = binaryColumn{name: column.name, encoder: encoder}
+	}
+	return enums
+}
+
+// Columns returns the names of the columns of the batch
+func (b *Batch) Columns() []string {
+	return b.columns
+}
+
+// Append adds a row with the values of all the columns of the batch.
+// When the batch reaches batch_max_rows or batch_max_bytes, the rows are
+// sent and the next rows start a new INSERT.
+func (b *Batch) Append(values ...interface{}) error {
+	if b.done {
+		return b.doneErr()
+	}
+	if len(values) != len(b.columns) {
+		return fmt.Errorf("clickhouse: batch has %d columns, but %d values are appended", len(b.columns), len(values))
+	}
+	if err := b.encodeRow(values); err != nil {
+		return err
+	}
+	if b.writer == nil {
+		query, separator := b.query+" VALUES ", ", "
+		if b.binary != nil {
+			query, separator = b.query+" FORMAT RowBinary\n", ""
+		}
+		var err error
+		if b.writer, err = b.c.startBatch(b.ctx, query, separator); err != nil {
+			return err
+		}
+	}
+	writer := b.writer
+	if err := writer.append(b.row); err != nil {
+		return b.fail(err)
+	}
+	if writer.full(b.c.batchMaxRows, b.c.batchMaxBytes) {
+		b.writer = nil
+		if err := writer.finish(); err != nil {
+			return b.fail(err)
+		}
+	}
+	return nil
+}
+
+// AppendStruct adds a row with the exported fields of the struct, they are
+// taken in order of declaration, the fields of embedded structs are included
+func (b *Batch) AppendStruct(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("clickhouse: expected struct, got %T", v)
+	}
+	fields := tupleFields(value, nil)
+	if len(fields) != len(b.columns) {
+		return fmt.Errorf("clickhouse: batch has %d columns, but the struct %T has %d fields", len(b.columns), v, len(fields))
+	}
+	return b.Append(fields...)
+}
+
+// Send completes the INSERT with the appended rows and waits for the result
+func (b *Batch) Send() error {
+	if b.done {
+		return b.doneErr()
+	}
+	b.done = true
+	delete(b.c.batches, b)
+	writer := b.writer
+	b.writer = nil
+	if writer == nil {
+		return nil
+	}
+	return writer.finish()
+}
+
+// Abort interrupts the INSERT, so the rows which have not been sent yet
+// are not inserted. The rows sent because of batch_max_rows or
+// batch_max_bytes are inserted anyway.
+func (b *Batch) Abort() {
+	b.done = true
+	delete(b.c.batches, b)
+	if b.writer != nil {
+		b.writer.abort()
+		b.writer = nil
+	}
+}
+
+// fail finishes the batch after a failed write, the error is returned by
+// the next calls, so the rest of the rows do not start a new INSERT
+func (b *Batch) fail(err error) error {
+	b.done = true
+	b.err = err
+	b.writer = nil
+	delete(b.c.batches, b)
+	return err
+}
+
+// doneErr returns the error of a failed batch or ErrBatchDone
+func (b *Batch) doneErr() error {
+	if b.err != nil {
+		return b.err
+	}
+	return ErrBatchDone
+}
+
+// encodeRow encodes the values in RowBinary or as a tuple of the VALUES clause
+func (b *Batch) encodeRow(values []interface{}) (err error) {
+	b.row = b.row[:0]
+	if b.binary != nil {
+		for i, column := range b.binary {
+			if b.row, err = encodeBinary(column.encoder, b.row, values[i]); err != nil {
+				return fmt.Errorf("clickhouse: failed to encode the value of the column %s: %w", column.name, err)
+			}
+		}
+		return nil
+	}
+	for i, column := range b.enums {
+		if err = column.checkEnum(values[i]); err != nil {
+			return err
+		}
+	}
+	b.row, err = appendTextRow(b.row, values, b.columns)
+	return err
+}
+
+// appendTextRow appends the values as a tuple of the VALUES clause,
+// the columns are used in the errors if they are known
+func appendTextRow(row []byte, values []interface{}, columns []string) ([]byte, error) {
+	row = append(row, '(')
+	for i, v := range values {
+		if i > 0 {
+			row = append(row, ", "...)
+		}
+		column := strconv.Itoa(i + 1)
+		if i < len(columns) {
+			column = columns[i]
+		}
+		value, err := converter{}.ConvertValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("clickhouse: failed to convert the value of the column %s: %w", column, err)
+		}
+		encoded, err := textEncode.Encode(value)
+		if err != nil {
+			return nil, fmt.Errorf("clickhouse: failed to encode the value of the column %s: %w", column, err)
+		}
+		row = append(row, encoded...)
+	}
+	return append(row, ')'), nil
+}
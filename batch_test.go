@@ -0,0 +1,397 @@
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchServer records the bodies of the requests which have been received completely
+type batchServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newBatchServer(t *testing.T, handler func(w http.ResponseWriter, body string)) *batchServer {
+	srv := new(batchServer)
+	srv.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			reader = gz
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			// the request has been aborted
+			return
+		}
+		srv.mu.Lock()
+		srv.bodies = append(srv.bodies, string(body))
+		srv.mu.Unlock()
+		if handler != nil {
+			handler(w, string(body))
+		}
+	}))
+	return srv
+}
+
+func (srv *batchServer) received() []string {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	return append([]string(nil), srv.bodies...)
+}
+
+// inserted returns the received queries except the ones describing the tables
+func (srv *batchServer) inserted() []string {
+	var inserted []string
+	for _, body := range srv.received() {
+		if !strings.HasPrefix(body, "DESCRIBE TABLE") {
+			inserted = append(inserted, body)
+		}
+	}
+	return inserted
+}
+
+func insertBatch(t *testing.T, db *sql.DB, values ...int) (*sql.Tx, error) {
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	st, err := tx.Prepare("INSERT INTO data (i64) VALUES (?)")
+	require.NoError(t, err)
+	for _, v := range values {
+		if _, err = st.Exec(v); err != nil {
+			return tx, err
+		}
+	}
+	return tx, nil
+}
+
+func TestParseInsert(t *testing.T) {
+	testCases := []struct {
+		query string
+		table string
+		names []string
+	}{
+		{"INSERT INTO data", "data", nil},
+		{"insert into table db.data (a, b)", "db.data", []string{"a", "b"}},
+		{"INSERT INTO `db`.`my data` (`a, b`, \"c\")", "`db`.`my data`", []string{"a, b", "c"}},
+		{"INSERT INTO `a\\`b` (`c\\`d`, \"e\\\"f\", `g\\\\`)", "`a\\`b`", []string{"c`d", "e\"f", "g\\"}},
+	}
+	for _, tc := range testCases {
+		table, names, ok := parseInsert(tc.query)
+		if assert.True(t, ok, tc.query) {
+			assert.Equal(t, tc.table, table, tc.query)
+			assert.Equal(t, tc.names, names, tc.query)
+		}
+	}
+	_, _, ok := parseInsert("INSERT INTO data (a, b) VALUES (1, 2)")
+	assert.False(t, ok)
+}
+
+func TestBatchFlush(t *testing.T) {
+	testCases := []struct {
+		params   string
+		flushed  []string
+		expected []string
+	}{
+		{
+			params:   "",
+			expected: []string{"INSERT INTO data (i64) VALUES(1), (2), (3), (4), (5)"},
+		},
+		{
+			params:  "?batch_max_rows=2",
+			flushed: []string{"INSERT INTO data (i64) VALUES(1), (2)", "INSERT INTO data (i64) VALUES(3), (4)"},
+			expected: []string{
+				"INSERT INTO data (i64) VALUES(1), (2)",
+				"INSERT INTO data (i64) VALUES(3), (4)",
+				"INSERT INTO data (i64) VALUES(5)",
+			},
+		},
+		{
+			params:   "?batch_max_bytes=40&enable_http_compression=1",
+			flushed:  []string{"INSERT INTO data (i64) VALUES(1), (2), (3)"},
+			expected: []string{"INSERT INTO data (i64) VALUES(1), (2), (3)", "INSERT INTO data (i64) VALUES(4), (5)"},
+		},
+	}
+	for _, tc := range testCases {
+		srv := newBatchServer(t, nil)
+		db, err := sql.Open("chhttp", srv.URL+"/default"+tc.params)
+		require.NoError(t, err)
+
+		tx, err := insertBatch(t, db, 1, 2, 3, 4, 5)
+		if assert.NoError(t, err, tc.params) {
+			// the full batches have been sent before the commit
+			assert.Equal(t, tc.flushed, srv.inserted(), tc.params)
+			assert.NoError(t, tx.Commit(), tc.params)
+			assert.Equal(t, tc.expected, srv.inserted(), tc.params)
+		}
+		assert.NoError(t, db.Close())
+		srv.Close()
+	}
+}
+
+func TestBatchRollback(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := insertBatch(t, db, 1, 2)
+	require.NoError(t, err)
+	assert.NoError(t, tx.Rollback())
+	assert.Empty(t, srv.inserted())
+
+	// the connection is usable after the rollback
+	tx, err = insertBatch(t, db, 3)
+	require.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"INSERT INTO data (i64) VALUES(3)"}, srv.inserted())
+}
+
+func TestBatchNamedParams(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	stmt, err := tx.Prepare("INSERT INTO data (i64, s) VALUES (?, {s:String})")
+	require.NoError(t, err)
+	_, err = stmt.Exec(1, sql.Named("s", "a"))
+	assert.Equal(t, ErrBatchNameParams, err)
+}
+
+func TestBatchServerError(t *testing.T) {
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Code: 60. DB::Exception: Table default.data does not exist. (UNKNOWN_TABLE) (version 23.8.1.1)"))
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default?batch_max_rows=2")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tx, err := insertBatch(t, db, 1, 2)
+	assert.Equal(t, &Error{Code: 60, Message: "Table default.data does not exist"}, err)
+	assert.NoError(t, tx.Rollback())
+
+	tx, err = insertBatch(t, db, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &Error{Code: 60, Message: "Table default.data does not exist"}, tx.Commit())
+}
+
+func TestBatchFailedWrite(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server fails without reading the rows
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Code: 241. DB::Exception: Memory limit (total) exceeded. (MEMORY_LIMIT_EXCEEDED) (version 23.8.1.1)"))
+	}))
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+	row := strings.Repeat("a", 1<<16)
+	expected := &Error{Code: 241, Message: "Memory limit (total) exceeded"}
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	st, err := tx.Prepare("INSERT INTO data (s) VALUES (?)")
+	require.NoError(t, err)
+	for i := 0; i < 1000 && err == nil; i++ {
+		_, err = st.Exec(row)
+	}
+	assert.Equal(t, expected, err)
+	// the rest of the rows don't start a new INSERT
+	_, err = st.Exec(row)
+	assert.Equal(t, expected, err)
+	assert.Equal(t, expected, tx.Commit())
+	// DESCRIBE TABLE and the failed INSERT
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+	atomic.StoreInt32(&requests, 0)
+	require.NoError(t, sqlConn.Raw(func(driverConn interface{}) error {
+		batch, err := driverConn.(Conn).PrepareBatch(context.Background(), "INSERT INTO data (s)")
+		require.NoError(t, err)
+		for i := 0; i < 1000 && err == nil; i++ {
+			err = batch.Append(row)
+		}
+		assert.Equal(t, expected, err)
+		assert.Equal(t, expected, batch.Append(row))
+		assert.Equal(t, expected, batch.Send())
+		return nil
+	}))
+	// DESCRIBE TABLE and the failed INSERT
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestBatchRowBinary(t *testing.T) {
+	describe := rowBinary(uint8(3), "name", "type", "default_type", "String", "String", "String",
+		"i64", "Int64", "",
+		"s", "Nullable(String)", "",
+		"m", "Int64", "MATERIALIZED",
+		"a", "AggregateFunction(uniq, String)", "",
+	)
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			w.Header().Set(formatHeader, "RowBinaryWithNamesAndTypes")
+			_, _ = w.Write(describe)
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default?insert_format=rowbinary")
+	require.NoError(t, err)
+	defer db.Close()
+
+	testCases := []struct {
+		query    string
+		rows     [][]interface{}
+		expected []string
+	}{
+		{
+			query: "INSERT INTO data (i64, s) VALUES (?, ?)",
+			rows:  [][]interface{}{{1, "a"}, {int64(2), nil}},
+			expected: []string{
+				"DESCRIBE TABLE data",
+				"INSERT INTO data (i64, s) FORMAT RowBinary\n" +
+					string(rowBinary(int64(1), uint8(0), "a", int64(2), uint8(1))),
+			},
+		},
+		{
+			query: "INSERT INTO `data` (`s`, m) VALUES (?, ?)",
+			rows:  [][]interface{}{{[]byte("b"), "3"}},
+			expected: []string{
+				"DESCRIBE TABLE `data`",
+				"INSERT INTO `data` (`s`, m) FORMAT RowBinary\n" + string(rowBinary(uint8(0), "b", int64(3))),
+			},
+		},
+		{
+			// the column of an unsupported type is not inserted
+			query: "INSERT INTO data VALUES (?, ?)",
+			rows:  [][]interface{}{{1, "a"}},
+			expected: []string{
+				"DESCRIBE TABLE data",
+				"INSERT INTO data VALUES(1, 'a')",
+			},
+		},
+		{
+			// the rows are encoded as text if they have expressions
+			query:    "INSERT INTO data (i64) VALUES (? + 1)",
+			rows:     [][]interface{}{{Array([]int{1})}},
+			expected: []string{"INSERT INTO data (i64) VALUES([1] + 1)"},
+		},
+	}
+	for _, tc := range testCases {
+		before := len(srv.received())
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		st, err := tx.Prepare(tc.query)
+		require.NoError(t, err)
+		for _, row := range tc.rows {
+			_, err = st.Exec(row...)
+			require.NoError(t, err, tc.query)
+		}
+		require.NoError(t, tx.Commit(), tc.query)
+		assert.Equal(t, tc.expected, srv.received()[before:], tc.query)
+	}
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	st, err := tx.Prepare("INSERT INTO data (i64) VALUES (?)")
+	require.NoError(t, err)
+	_, err = st.Exec("a")
+	assert.EqualError(t, err, `clickhouse: failed to encode the value of the column i64: strconv.ParseUint: parsing "a": invalid syntax`)
+	assert.NoError(t, tx.Rollback())
+}
+
+func TestPrepareBatch(t *testing.T) {
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			_, _ = w.Write([]byte("name\ttype\tdefault_type\nString\tString\tString\n" +
+				"i64\tInt64\t\ns\tString\t\nm\tInt64\tMATERIALIZED\n"))
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+
+	type embedded struct {
+		S string
+	}
+	row := struct {
+		I64 int64
+		embedded
+		hidden bool
+	}{1, embedded{"a"}, true}
+
+	require.NoError(t, sqlConn.Raw(func(driverConn interface{}) error {
+		batch, err := driverConn.(Conn).PrepareBatch(context.Background(), "INSERT INTO data")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"i64", "s"}, batch.Columns())
+		assert.EqualError(t, batch.Append(1), "clickhouse: batch has 2 columns, but 1 values are appended")
+		assert.NoError(t, batch.AppendStruct(&row))
+		assert.NoError(t, batch.Append(2, "b'"))
+		assert.NoError(t, batch.Send())
+		assert.Equal(t, ErrBatchDone, batch.Append(3, "c"))
+		assert.Equal(t, ErrBatchDone, batch.Send())
+
+		batch, err = driverConn.(Conn).PrepareBatch(context.Background(), "INSERT INTO data (s)")
+		require.NoError(t, err)
+		assert.EqualError(t, batch.AppendStruct(row),
+			"clickhouse: batch has 1 columns, but the struct struct { I64 int64; clickhouse.embedded; hidden bool } has 2 fields")
+		assert.NoError(t, batch.Append("c"))
+		batch.Abort()
+		assert.Equal(t, ErrBatchDone, batch.Send())
+
+		_, err = driverConn.(Conn).PrepareBatch(context.Background(), "INSERT INTO data VALUES (1)")
+		assert.EqualError(t, err, "clickhouse: batch query must be INSERT INTO table (columns), got: INSERT INTO data VALUES (1)")
+		return nil
+	}))
+	assert.Equal(t, []string{"DESCRIBE TABLE data", "INSERT INTO data VALUES (1, 'a'), (2, 'b\\'')", "DESCRIBE TABLE data"}, srv.received())
+}
+
+func TestBatchAbortedByClose(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	defer srv.Close()
+	cfg, err := ParseDSN(srv.URL + "/default")
+	require.NoError(t, err)
+	c := newConn(cfg)
+
+	// the batch is neither sent nor aborted
+	batch, err := c.PrepareBatch(context.Background(), "INSERT INTO data (i64)")
+	require.NoError(t, err)
+	require.NoError(t, batch.Append(1))
+	require.NotNil(t, batch.writer)
+
+	// Close waits for the aborted request
+	require.NoError(t, c.Close())
+	assert.Nil(t, batch.writer)
+	assert.Empty(t, c.batches)
+	assert.Equal(t, ErrBatchDone, batch.Send())
+	assert.Empty(t, srv.inserted())
+}
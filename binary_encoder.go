@@ -0,0 +1,999 @@
+package clickhouse
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// binaryEncoder appends a value encoded in RowBinary format to b.
+// The value is normalized by encodeBinary before it is passed to encode.
+type binaryEncoder interface {
+	encode(b []byte, v interface{}) ([]byte, error)
+}
+
+// encodeBinary normalizes the value and encodes it with e
+func encodeBinary(e binaryEncoder, b []byte, v interface{}) ([]byte, error) {
+	v, err := binaryValue(v)
+	if err != nil {
+		return nil, err
+	}
+	switch e.(type) {
+	case *binaryVariantEncoder, *binaryDynamicEncoder:
+	default:
+		// the values read from Variant and JSON columns may be written to the columns of other types
+		switch x := v.(type) {
+		case Variant:
+			return encodeBinary(e, b, x.Value)
+		case jsonObject:
+			if _, ok := e.(*binaryJSONEncoder); !ok {
+				data, err := marshalJSON(x.v)
+				if err != nil {
+					return nil, err
+				}
+				v = string(data)
+			}
+		}
+	}
+	if v == nil {
+		switch e.(type) {
+		case *binaryNullableEncoder, *binaryVariantEncoder, *binaryDynamicEncoder:
+		default:
+			return nil, fmt.Errorf("unexpected NULL")
+		}
+	}
+	return e.encode(b, v)
+}
+
+// binaryValue unwraps the values created by the helpers of the package, pointers
+// and driver.Valuer, so the encoders get the values they can handle
+func binaryValue(v interface{}) (interface{}, error) {
+	for {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return nil, nil
+		}
+		switch x := v.(type) {
+		case nil, string, []byte, time.Time, *big.Int, Decimal, Variant, jsonObject,
+			Point, Ring, Polygon, MultiPolygon:
+			// Decimal is encoded by its text representation instead of Value,
+			// Variant keeps its type, JSON is encoded as an object and
+			// the geo types as tuples and arrays
+			return v, nil
+		case big.Int:
+			return &x, nil
+		case bigInt:
+			v = x.v
+		case array:
+			v = x.v
+		case tuple:
+			v = x.v
+		case mapp:
+			v = x.v
+		case decimal:
+			v = x.v
+		case date:
+			return time.Time(x), nil
+		case bigUint64:
+			return uint64(x), nil
+		case ip:
+			return net.IP(x), nil
+		case driver.Valuer:
+			vv, err := x.Value()
+			if err != nil {
+				return nil, err
+			}
+			v = vv
+		default:
+			rv := reflect.ValueOf(v)
+			if rv.Kind() != reflect.Ptr {
+				return v, nil
+			}
+			v = rv.Elem().Interface()
+		}
+	}
+}
+
+type binaryNullableEncoder struct {
+	binaryEncoder
+}
+
+func (e *binaryNullableEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	if v == nil {
+		return append(b, 1), nil
+	}
+	return e.binaryEncoder.encode(append(b, 0), v)
+}
+
+type binaryBoolEncoder struct{}
+
+func (e *binaryBoolEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Bool {
+		if rv.Bool() {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	}
+	n, negative, err := integerValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if n != 0 || negative {
+		return append(b, 1), nil
+	}
+	return append(b, 0), nil
+}
+
+type binaryIntEncoder struct {
+	signed  bool
+	bitSize int
+}
+
+func (e *binaryIntEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	n, negative, err := integerValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var overflow bool
+	switch {
+	case !e.signed:
+		overflow = negative || e.bitSize < 64 && n >= 1<<e.bitSize
+	case negative:
+		overflow = int64(n) < -1<<(e.bitSize-1)
+	default:
+		overflow = n >= 1<<(e.bitSize-1)
+	}
+	if overflow {
+		return nil, fmt.Errorf("value %v is out of range of %d bit integer", v, e.bitSize)
+	}
+	b = binary.LittleEndian.AppendUint64(b, n)
+	return b[:len(b)-8+e.bitSize/8], nil
+}
+
+type binaryBigIntEncoder struct {
+	signed  bool
+	bitSize int
+}
+
+func (e *binaryBigIntEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	n, ok := v.(*big.Int)
+	if !ok {
+		s, isString := stringValue(v)
+		if !isString {
+			i, negative, err := integerValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if negative {
+				n = big.NewInt(int64(i))
+			} else {
+				n = new(big.Int).SetUint64(i)
+			}
+		} else if n, ok = new(big.Int).SetString(s, 10); !ok {
+			return nil, fmt.Errorf("malformed %d bit integer %s", e.bitSize, s)
+		}
+	}
+	if err := checkBigIntRange(n, e.signed, e.bitSize); err != nil {
+		return nil, err
+	}
+	if e.signed {
+		return appendLittleEndianInt(b, n, e.bitSize/8)
+	}
+	be := n.FillBytes(make([]byte, e.bitSize/8))
+	for i := len(be) - 1; i >= 0; i-- {
+		b = append(b, be[i])
+	}
+	return b, nil
+}
+
+// integerValue converts an integer value to uint64, negative reports
+// whether it is a negative int64
+func integerValue(v interface{}) (n uint64, negative bool, err error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		return uint64(i), i < 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), false, nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1, false, nil
+		}
+		return 0, false, nil
+	}
+	s, ok := stringValue(v)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected type %T of an integer", v)
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return uint64(i), i < 0, nil
+	}
+	if n, err = strconv.ParseUint(s, 10, 64); err != nil {
+		return 0, false, err
+	}
+	return n, false, nil
+}
+
+// stringValue returns the value of strings and byte slices
+func stringValue(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case []byte:
+		return string(x), true
+	case netip.Addr:
+		return x.String(), true
+	case netip.Prefix:
+		return x.String(), true
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.String:
+		return rv.String(), true
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		return string(rv.Bytes()), true
+	}
+	return "", false
+}
+
+type binaryFloatEncoder struct {
+	bitSize int
+}
+
+func (e *binaryFloatEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	var f float64
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f = float64(rv.Uint())
+	default:
+		s, ok := stringValue(v)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T of a float", v)
+		}
+		var err error
+		if f, err = strconv.ParseFloat(s, e.bitSize); err != nil {
+			return nil, err
+		}
+	}
+	if e.bitSize == 32 {
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f))), nil
+	}
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(f)), nil
+}
+
+type binaryStringEncoder struct {
+	length int // length of FixedString, 0 for String
+}
+
+func (e *binaryStringEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	s, ok := stringValue(v)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T of a string", v)
+	}
+	if e.length == 0 {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		return append(b, s...), nil
+	}
+	if len(s) > e.length {
+		return nil, fmt.Errorf("string of %d bytes is too long for FixedString(%d)", len(s), e.length)
+	}
+	b = append(b, s...)
+	return append(b, make([]byte, e.length-len(s))...), nil
+}
+
+type binaryDecimalEncoder struct {
+	size  int
+	scale int
+}
+
+func (e *binaryDecimalEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	var s string
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s = strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		s = strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+	default:
+		var ok bool
+		if s, ok = stringValue(v); !ok {
+			if stringer, isStringer := v.(fmt.Stringer); isStringer {
+				s = stringer.String()
+			} else {
+				return nil, fmt.Errorf("unexpected type %T of a decimal", v)
+			}
+		}
+	}
+	n, err := parseDecimal(s, e.scale)
+	if err != nil {
+		return nil, err
+	}
+	return appendLittleEndianInt(b, n, e.size)
+}
+
+// parseDecimal parses the text representation of a decimal into its unscaled value
+func parseDecimal(s string, scale int) (*big.Int, error) {
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > scale {
+		return nil, fmt.Errorf("decimal %s has more than %d digits after the point", s, scale)
+	}
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+	n, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok || strings.ContainsAny(fracPart, "+-") {
+		return nil, fmt.Errorf("malformed decimal %s", s)
+	}
+	return n, nil
+}
+
+// appendLittleEndianInt appends n as a little endian two's complement integer of the given size
+func appendLittleEndianInt(b []byte, n *big.Int, size int) ([]byte, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(size*8-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("value %s is out of range of %d bit integer", n, size*8)
+	}
+	if n.Sign() < 0 {
+		n = new(big.Int).Add(n, limit.Lsh(limit, 1))
+	}
+	be := n.FillBytes(make([]byte, size))
+	for i := len(be) - 1; i >= 0; i-- {
+		b = append(b, be[i])
+	}
+	return b, nil
+}
+
+type binaryEnumEncoder struct {
+	size   int
+	values map[string]int16
+	names  map[int16]string
+}
+
+func (e *binaryEnumEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	var value int16
+	if s, ok := stringValue(v); ok {
+		if value, ok = e.values[s]; !ok {
+			return nil, fmt.Errorf("unknown enum name '%s'", s)
+		}
+	} else {
+		n, _, err := integerValue(v)
+		if err != nil {
+			return nil, err
+		}
+		value = int16(n)
+		if _, ok := e.names[value]; !ok || int64(n) != int64(value) {
+			return nil, fmt.Errorf("unknown enum value %v", v)
+		}
+	}
+	if e.size == 1 {
+		return append(b, byte(value)), nil
+	}
+	return binary.LittleEndian.AppendUint16(b, uint16(value)), nil
+}
+
+type binaryUUIDEncoder struct{}
+
+func (e *binaryUUIDEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	var u uuid.UUID
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.Array && rv.Len() == len(u) && rv.Type().Elem().Kind() == reflect.Uint8:
+		reflect.Copy(reflect.ValueOf(u[:]), rv)
+	default:
+		s, ok := stringValue(v)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type %T of UUID", v)
+		}
+		var err error
+		if u, err = uuid.Parse(s); err != nil {
+			return nil, err
+		}
+	}
+	// UUID is written as two little endian UInt64
+	for i := 7; i >= 0; i-- {
+		b = append(b, u[i])
+	}
+	for i := 15; i >= 8; i-- {
+		b = append(b, u[i])
+	}
+	return b, nil
+}
+
+// ipValue converts the value of an IP address to netip.Addr
+func ipValue(v interface{}) (netip.Addr, error) {
+	switch x := v.(type) {
+	case netip.Addr:
+		return x, nil
+	case net.IP:
+		addr, ok := netip.AddrFromSlice(x)
+		if !ok {
+			return netip.Addr{}, fmt.Errorf("malformed IP address %v", x)
+		}
+		return addr, nil
+	}
+	s, ok := stringValue(v)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("unexpected type %T of an IP address", v)
+	}
+	return netip.ParseAddr(s)
+}
+
+type binaryIPv4Encoder struct{}
+
+func (e *binaryIPv4Encoder) encode(b []byte, v interface{}) ([]byte, error) {
+	addr, err := ipValue(v)
+	if err != nil {
+		return nil, err
+	}
+	addr = addr.Unmap()
+	if !addr.Is4() {
+		return nil, fmt.Errorf("%s is not an IPv4 address", addr)
+	}
+	ip := addr.As4()
+	return append(b, ip[3], ip[2], ip[1], ip[0]), nil
+}
+
+type binaryIPv6Encoder struct{}
+
+func (e *binaryIPv6Encoder) encode(b []byte, v interface{}) ([]byte, error) {
+	addr, err := ipValue(v)
+	if err != nil {
+		return nil, err
+	}
+	ip := addr.As16()
+	return append(b, ip[:]...), nil
+}
+
+type binaryDateEncoder struct{}
+
+func (e *binaryDateEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	t, ok := v.(time.Time)
+	if !ok {
+		s, isString := stringValue(v)
+		if !isString {
+			return nil, fmt.Errorf("unexpected type %T of a date", v)
+		}
+		var err error
+		if t, err = time.Parse(dateFormat, s); err != nil {
+			return nil, err
+		}
+	}
+	// the text encoder writes the date in the location of the value
+	year, month, day := t.Date()
+	days := time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	if days < 0 || days > math.MaxUint16 {
+		return nil, fmt.Errorf("date %s is out of range", t.Format(dateFormat))
+	}
+	return binary.LittleEndian.AppendUint16(b, uint16(days)), nil
+}
+
+// binaryDateTimeEncoder encodes DateTime and DateTime64. The text encoder writes
+// the local time of the value, which is parsed by the server in the time zone of
+// the column or the server, so the same conversion is made here.
+type binaryDateTimeEncoder struct {
+	precision int // -1 for DateTime
+	location  *time.Location
+}
+
+func (e *binaryDateTimeEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	t, ok := v.(time.Time)
+	if !ok {
+		s, isString := stringValue(v)
+		if !isString {
+			if e.precision >= 0 {
+				return nil, fmt.Errorf("unexpected type %T of DateTime64", v)
+			}
+			n, negative, err := integerValue(v)
+			if err != nil {
+				return nil, err
+			}
+			if negative || n > math.MaxUint32 {
+				return nil, fmt.Errorf("timestamp %v is out of range of DateTime", v)
+			}
+			return binary.LittleEndian.AppendUint32(b, uint32(n)), nil
+		}
+		loc := e.location
+		if loc == nil {
+			loc = time.UTC
+		}
+		var err error
+		if t, err = time.ParseInLocation(dateTime64Format, s, loc); err != nil {
+			return nil, err
+		}
+	} else if e.location != nil {
+		year, month, day := t.Date()
+		hour, minute, sec := t.Clock()
+		t = time.Date(year, month, day, hour, minute, sec, t.Nanosecond(), e.location)
+	}
+
+	if e.precision < 0 {
+		if t.Unix() < 0 || t.Unix() > math.MaxUint32 {
+			return nil, fmt.Errorf("time %s is out of range of DateTime", t)
+		}
+		return binary.LittleEndian.AppendUint32(b, uint32(t.Unix())), nil
+	}
+	scale := int64(math.Pow10(e.precision))
+	ticks := t.Unix()*scale + int64(t.Nanosecond())/int64(math.Pow10(9-e.precision))
+	return binary.LittleEndian.AppendUint64(b, uint64(ticks)), nil
+}
+
+type binaryArrayEncoder struct {
+	elem binaryEncoder
+}
+
+func (e *binaryArrayEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected array or slice, got %T", v)
+	}
+	b = binary.AppendUvarint(b, uint64(rv.Len()))
+	var err error
+	for i := 0; i < rv.Len(); i++ {
+		if b, err = encodeBinary(e.elem, b, rv.Index(i).Interface()); err != nil {
+			return nil, fmt.Errorf("failed to encode array element: %w", err)
+		}
+	}
+	return b, nil
+}
+
+type binaryTupleEncoder struct {
+	elems []binaryEncoder
+	names []string // the names of the elements of a named tuple
+}
+
+func (e *binaryTupleEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	var fields []interface{}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		// the values of named tuples are keyed by the names of the elements
+		if e.names == nil || rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("expected struct, got %T", v)
+		}
+		if rv.Len() != len(e.names) {
+			return nil, fmt.Errorf("tuple of %d elements got %d values", len(e.elems), rv.Len())
+		}
+		for _, name := range e.names {
+			field := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !field.IsValid() {
+				return nil, fmt.Errorf("no value of the tuple element %s", name)
+			}
+			fields = append(fields, field.Interface())
+		}
+	case reflect.Struct:
+		fields = tupleFields(rv, nil)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			fields = append(fields, rv.Index(i).Interface())
+		}
+	default:
+		return nil, fmt.Errorf("expected struct, got %T", v)
+	}
+	if len(fields) != len(e.elems) {
+		return nil, fmt.Errorf("tuple of %d elements got %d values", len(e.elems), len(fields))
+	}
+	var err error
+	for i, elem := range e.elems {
+		if b, err = encodeBinary(elem, b, fields[i]); err != nil {
+			return nil, fmt.Errorf("failed to encode tuple element: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// tupleFields appends the exported fields of a struct to fields,
+// the fields of embedded structs are added as the fields of the struct
+func tupleFields(value reflect.Value, fields []interface{}) []interface{} {
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	t := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		if t.Field(i).Anonymous && value.Field(i).Kind() == reflect.Struct {
+			fields = tupleFields(value.Field(i), fields)
+			continue
+		}
+		if value.Field(i).CanInterface() {
+			fields = append(fields, value.Field(i).Interface())
+		}
+	}
+	return fields
+}
+
+type binaryMapEncoder struct {
+	key   binaryEncoder
+	value binaryEncoder
+}
+
+func (e *binaryMapEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return nil, fmt.Errorf("expected map, got %T", v)
+	}
+	b = binary.AppendUvarint(b, uint64(rv.Len()))
+	var err error
+	iter := rv.MapRange()
+	for iter.Next() {
+		if b, err = encodeBinary(e.key, b, iter.Key().Interface()); err != nil {
+			return nil, fmt.Errorf("failed to encode map key: %w", err)
+		}
+		if b, err = encodeBinary(e.value, b, iter.Value().Interface()); err != nil {
+			return nil, fmt.Errorf("failed to encode map value: %w", err)
+		}
+	}
+	return b, nil
+}
+
+// binaryVariantEncoder encodes the values of Variant columns with the index of
+// their type. The type is taken from Variant values or found by the Go type of
+// the value, otherwise the types are tried in order with the strings last.
+type binaryVariantEncoder struct {
+	types   []string
+	args    []binaryEncoder
+	strings []bool // whether the values of the type are strings
+}
+
+func (e *binaryVariantEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	if x, ok := v.(Variant); ok {
+		value, err := binaryValue(x.Value)
+		if err != nil {
+			return nil, err
+		}
+		if x.Type != "" && value != nil {
+			for i, typ := range e.types {
+				if typ == x.Type {
+					return encodeBinary(e.args[i], append(b, byte(i)), value)
+				}
+			}
+			return nil, fmt.Errorf("type %s is not one of the types of Variant", x.Type)
+		}
+		v = value
+	}
+	if v == nil {
+		return append(b, binaryVariantNullDiscriminator), nil
+	}
+	if t, err := inferType(v); err == nil {
+		for i, typ := range e.types {
+			if typ == t.String() {
+				return encodeBinary(e.args[i], append(b, byte(i)), v)
+			}
+		}
+	}
+	// the strings are tried first for the string values, which could be parsed as numbers
+	_, isString := stringValue(v)
+	for _, wantString := range []bool{isString, !isString} {
+		for i, arg := range e.args {
+			if e.strings[i] != wantString {
+				continue
+			}
+			if encoded, err := encodeBinary(arg, append(b, byte(i)), v); err == nil {
+				return encoded, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("value of type %T has none of the types of Variant", v)
+}
+
+// binaryDynamicEncoder encodes the values of Dynamic columns with their types,
+// which are taken from Variant values or inferred from the Go types of the values
+type binaryDynamicEncoder struct {
+	serverLocation *time.Location
+	encoders       map[string]binaryEncoder // the encoders of the types met before
+}
+
+func (e *binaryDynamicEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	var (
+		t   *TypeDesc
+		err error
+	)
+	if x, ok := v.(Variant); ok {
+		if x.Type != "" {
+			if t, err = ParseTypeDesc(x.Type); err != nil {
+				return nil, err
+			}
+		}
+		if v, err = binaryValue(x.Value); err != nil {
+			return nil, err
+		}
+	}
+	if v == nil {
+		return append(b, binaryTypeCodes["Nothing"]), nil
+	}
+	if t == nil {
+		if t, err = inferType(v); err != nil {
+			return nil, err
+		}
+	}
+	typ := t.String()
+	encoder, ok := e.encoders[typ]
+	if !ok {
+		if encoder, err = newBinaryEncoder(t, e.serverLocation); err != nil {
+			return nil, fmt.Errorf("failed to create encoder for Dynamic value: %v", err)
+		}
+		if e.encoders == nil {
+			e.encoders = make(map[string]binaryEncoder)
+		}
+		e.encoders[typ] = encoder
+	}
+	if b, err = appendBinaryType(b, t); err != nil {
+		return nil, err
+	}
+	return encodeBinary(encoder, b, v)
+}
+
+// binaryJSONEncoder encodes maps and structs into JSON objects, which are written
+// as the values of their paths. The values of the typed paths are written as their
+// types and the others as Dynamic, the skipped paths are not written.
+type binaryJSONEncoder struct {
+	typed       map[string]binaryEncoder
+	skip        []string
+	skipRegexps []*regexp.Regexp
+	dynamic     *binaryDynamicEncoder
+}
+
+func (e *binaryJSONEncoder) encode(b []byte, v interface{}) ([]byte, error) {
+	if x, ok := v.(jsonObject); ok {
+		v = x.v
+	}
+	values, err := jsonPaths(v)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		if !e.skipped(path) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	b = binary.AppendUvarint(b, uint64(len(paths)))
+	for _, path := range paths {
+		b = appendBinaryString(b, path)
+		var encoder binaryEncoder = e.dynamic
+		if typed, ok := e.typed[path]; ok {
+			encoder = typed
+		}
+		if b, err = encodeBinary(encoder, b, jsonNumbers(values[path])); err != nil {
+			return nil, fmt.Errorf("failed to encode JSON path %s: %w", path, err)
+		}
+	}
+	return b, nil
+}
+
+// skipped reports whether the path is skipped by SKIP or SKIP REGEXP
+func (e *binaryJSONEncoder) skipped(path string) bool {
+	for _, skip := range e.skip {
+		if path == skip || strings.HasPrefix(path, skip+".") {
+			return true
+		}
+	}
+	for _, re := range e.skipRegexps {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// newBinaryEncoder creates a binaryEncoder for the given TypeDesc.
+// serverLocation is the time zone used by the server for DateTime columns
+// without their own time zone, it may be nil if it is unknown.
+func newBinaryEncoder(t *TypeDesc, serverLocation *time.Location) (binaryEncoder, error) {
+	switch t.Name {
+	case "Nullable":
+		if len(t.Args) == 0 {
+			return nil, fmt.Errorf("Nullable should pass original type")
+		}
+		e, err := newBinaryEncoder(t.Args[0], serverLocation)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNullableEncoder{e}, nil
+	case "Date":
+		return &binaryDateEncoder{}, nil
+	case "DateTime", "DateTime64":
+		e := &binaryDateTimeEncoder{precision: -1, location: serverLocation}
+		tzArg := 0
+		if t.Name == "DateTime64" {
+			if len(t.Args) == 0 {
+				return nil, fmt.Errorf("precision not specified for DateTime64")
+			}
+			precision, err := strconv.Atoi(t.Args[0].Name)
+			if err != nil {
+				return nil, fmt.Errorf("malformed precision specified for DateTime64: %v", err)
+			}
+			if precision < 0 || precision > 9 {
+				return nil, fmt.Errorf("unsupported precision specified for DateTime64: %d", precision)
+			}
+			e.precision, tzArg = precision, 1
+		}
+		if len(t.Args) > tzArg {
+			loc, err := time.LoadLocation(t.Args[tzArg].Name)
+			if err != nil {
+				return nil, err
+			}
+			e.location = loc
+		}
+		return e, nil
+	case "Bool":
+		return &binaryBoolEncoder{}, nil
+	case "UInt8":
+		return &binaryIntEncoder{false, 8}, nil
+	case "UInt16":
+		return &binaryIntEncoder{false, 16}, nil
+	case "UInt32":
+		return &binaryIntEncoder{false, 32}, nil
+	case "UInt64":
+		return &binaryIntEncoder{false, 64}, nil
+	case "Int8":
+		return &binaryIntEncoder{true, 8}, nil
+	case "Int16":
+		return &binaryIntEncoder{true, 16}, nil
+	case "Int32":
+		return &binaryIntEncoder{true, 32}, nil
+	case "Int64":
+		return &binaryIntEncoder{true, 64}, nil
+	case "UInt128":
+		return &binaryBigIntEncoder{false, 128}, nil
+	case "UInt256":
+		return &binaryBigIntEncoder{false, 256}, nil
+	case "Int128":
+		return &binaryBigIntEncoder{true, 128}, nil
+	case "Int256":
+		return &binaryBigIntEncoder{true, 256}, nil
+	case "Float32":
+		return &binaryFloatEncoder{32}, nil
+	case "Float64":
+		return &binaryFloatEncoder{64}, nil
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		precision, scale, err := decimalArgs(t)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryDecimalEncoder{size: decimalSize(precision), scale: scale}, nil
+	case "Enum8", "Enum16":
+		if t.Enum == nil {
+			return nil, fmt.Errorf("no values of %s", t.Name)
+		}
+		e := &binaryEnumEncoder{size: 1, values: t.Enum, names: enumNames(t.Enum)}
+		if t.Name == "Enum16" {
+			e.size = 2
+		}
+		return e, nil
+	case "String":
+		return &binaryStringEncoder{}, nil
+	case "FixedString":
+		p, err := newBinaryParser(t, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryStringEncoder{length: p.(*binaryStringParser).length}, nil
+	case "UUID":
+		return &binaryUUIDEncoder{}, nil
+	case "IPv4":
+		return &binaryIPv4Encoder{}, nil
+	case "IPv6":
+		return &binaryIPv6Encoder{}, nil
+	case "Array":
+		if len(t.Args) != 1 {
+			return nil, fmt.Errorf("element type not specified for Array")
+		}
+		elem, err := newBinaryEncoder(t.Args[0], serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encoder for array elements: %v", err)
+		}
+		return &binaryArrayEncoder{elem}, nil
+	case "Tuple":
+		if len(t.Args) < 1 {
+			return nil, fmt.Errorf("element types not specified for Tuple")
+		}
+		elems := make([]binaryEncoder, len(t.Args))
+		for i, arg := range t.Args {
+			elem, err := newBinaryEncoder(arg, serverLocation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create encoder for tuple element: %v", err)
+			}
+			elems[i] = elem
+		}
+		return &binaryTupleEncoder{elems, elemNames(t)}, nil
+	case "Nested":
+		// Nested is written as an array of named tuples
+		elem, err := newBinaryEncoder(&TypeDesc{Name: "Tuple", Args: t.Args}, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encoder for Nested: %v", err)
+		}
+		return &binaryArrayEncoder{elem}, nil
+	case "LowCardinality":
+		// LowCardinality is written as its element type
+		if len(t.Args) != 1 {
+			return nil, fmt.Errorf("element type not specified for LowCardinality")
+		}
+		return newBinaryEncoder(t.Args[0], serverLocation)
+	case "SimpleAggregateFunction":
+		if len(t.Args) != 2 {
+			return nil, fmt.Errorf("incorrect number of arguments for SimpleAggregateFunction")
+		}
+		return newBinaryEncoder(t.Args[1], serverLocation)
+	case "Map":
+		if len(t.Args) != 2 {
+			return nil, fmt.Errorf("incorrect number of arguments for Map")
+		}
+		key, err := newBinaryEncoder(t.Args[0], serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encoder for map keys: %v", err)
+		}
+		value, err := newBinaryEncoder(t.Args[1], serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create encoder for map values: %v", err)
+		}
+		return &binaryMapEncoder{key, value}, nil
+	case "Variant":
+		if len(t.Args) < 1 {
+			return nil, fmt.Errorf("types not specified for Variant")
+		}
+		args := variantArgs(t)
+		e := &binaryVariantEncoder{
+			types:   make([]string, len(args)),
+			args:    make([]binaryEncoder, len(args)),
+			strings: make([]bool, len(args)),
+		}
+		for i, arg := range args {
+			argEncoder, err := newBinaryEncoder(arg, serverLocation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create encoder for Variant type: %v", err)
+			}
+			e.types[i], e.args[i], e.strings[i] = arg.String(), argEncoder, stringVariant(arg)
+		}
+		return e, nil
+	case "Dynamic":
+		return &binaryDynamicEncoder{serverLocation: serverLocation}, nil
+	case "Point", "Ring", "Polygon", "MultiPolygon":
+		// the geo types are written as the tuples and arrays they are aliases of
+		return newBinaryEncoder(geoTypeDesc(t.Name), serverLocation)
+	case "JSON":
+		e := &binaryJSONEncoder{
+			typed:   make(map[string]binaryEncoder),
+			dynamic: &binaryDynamicEncoder{serverLocation: serverLocation},
+		}
+		for _, arg := range t.Args {
+			switch {
+			case arg.ElemName != "":
+				typed, err := newBinaryEncoder(arg, serverLocation)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create encoder for JSON path %s: %v", arg.ElemName, err)
+				}
+				e.typed[arg.ElemName] = typed
+			case arg.Name == "SKIP" && len(arg.Args) == 1:
+				e.skip = append(e.skip, arg.Args[0].Name)
+			case arg.Name == "SKIP REGEXP" && len(arg.Args) == 1:
+				re, err := regexp.Compile(arg.Args[0].Name)
+				if err != nil {
+					return nil, fmt.Errorf("malformed SKIP REGEXP of JSON: %v", err)
+				}
+				e.skipRegexps = append(e.skipRegexps, re)
+			}
+		}
+		return e, nil
+	}
+
+	return nil, fmt.Errorf("type %s is not supported", t.Name)
+}
@@ -0,0 +1,271 @@
+package clickhouse
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryEncoder(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("failed to load time zone Europe/Moscow: %v", err)
+	}
+	dateTime := time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC)
+	str := "x"
+	testCases := []struct {
+		typ       string
+		value     interface{}
+		binary    []byte
+		serverLoc *time.Location
+	}{
+		{typ: "Int8", value: -1, binary: rowBinary(int8(-1))},
+		{typ: "Int16", value: "-300", binary: rowBinary(int16(-300))},
+		{typ: "Int32", value: int64(-70000), binary: rowBinary(int32(-70000))},
+		{typ: "Int64", value: uint32(5000000), binary: rowBinary(int64(5000000))},
+		{typ: "UInt8", value: true, binary: rowBinary(uint8(1))},
+		{typ: "UInt64", value: UInt64(18446744073709551615), binary: rowBinary(uint64(18446744073709551615))},
+		{typ: "UInt64", value: []byte("18446744073709551615"), binary: rowBinary(uint64(18446744073709551615))},
+		{typ: "Int128", value: big.NewInt(-1), binary: leInt(-1, 16)},
+		{typ: "Int128", value: -2, binary: leInt(-2, 16)},
+		{typ: "UInt128", value: "340282366920938463463374607431768211455", binary: leInt(-1, 16)},
+		{typ: "Int256", value: Int256(big.NewInt(5)), binary: leInt(5, 32)},
+		{typ: "UInt256", value: uint64(18446744073709551615), binary: rowBinary(uint64(18446744073709551615), uint64(0), uint64(0), uint64(0))},
+		{typ: "Float32", value: 1.5, binary: rowBinary(float32(1.5))},
+		{typ: "Float64", value: 2, binary: rowBinary(float64(2))},
+		{typ: "Bool", value: false, binary: rowBinary(uint8(0))},
+		{typ: "String", value: "a\tb\\c", binary: rowBinary("a\tb\\c")},
+		{typ: "String", value: []byte("bytes"), binary: rowBinary("bytes")},
+		{typ: "String", value: &str, binary: rowBinary("x")},
+		{typ: "FixedString(3)", value: "a", binary: rowBinary([]byte("a\x00\x00"))},
+		{typ: "Nullable(String)", value: nil, binary: rowBinary(uint8(1))},
+		{typ: "Nullable(String)", value: (*string)(nil), binary: rowBinary(uint8(1))},
+		{typ: "Nullable(String)", value: "x", binary: rowBinary(uint8(0), "x")},
+		{typ: "Decimal(9, 4)", value: "10.1111", binary: rowBinary(int32(101111))},
+		{typ: "Decimal(9, 4)", value: -0.5, binary: rowBinary(int32(-5000))},
+		{typ: "Decimal(18, 4)", value: Decimal64(-100, 4), binary: rowBinary(int64(-1000000))},
+		{typ: "Decimal(38, 4)", value: "1000.1111", binary: leInt(10001111, 16)},
+		{typ: "Decimal(76, 2)", value: "-1.01", binary: leInt(-101, 32)},
+		{typ: "Decimal(9, 4)", value: NewDecimal(big.NewInt(15), 1), binary: rowBinary(int32(15000))},
+		{typ: "Decimal256(2)", value: Decimal256("-1.01", 2), binary: leInt(-101, 32)},
+		{typ: "Enum8('hello' = 1, 'world' = 2)", value: "world", binary: rowBinary(int8(2))},
+		{typ: "Enum16('a' = -1000, 'b' = 1000)", value: -1000, binary: rowBinary(int16(-1000))},
+		{
+			typ:    "UUID",
+			value:  "123e4567-e89b-12d3-a456-426614174000",
+			binary: rowBinary(uint64(0x123e4567e89b12d3), uint64(0xa456426614174000)),
+		},
+		{
+			typ:    "UUID",
+			value:  uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+			binary: rowBinary(uint64(0x123e4567e89b12d3), uint64(0xa456426614174000)),
+		},
+		{typ: "IPv4", value: "127.0.0.1", binary: rowBinary(uint32(0x7f000001))},
+		{typ: "IPv4", value: IP(net.IPv4(127, 0, 0, 1)), binary: rowBinary(uint32(0x7f000001))},
+		{typ: "String", value: netip.MustParsePrefix("10.0.0.0/8"), binary: rowBinary("10.0.0.0/8")},
+		{
+			typ:    "IPv6",
+			value:  netip.MustParseAddr("2001:db8::1"),
+			binary: rowBinary([]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}),
+		},
+		{typ: "Date", value: Date(dateTime.In(moscow)), binary: rowBinary(uint16(dateTime.Unix() / 86400))},
+		{typ: "Date", value: "2011-03-06", binary: rowBinary(uint16(dateTime.Unix() / 86400))},
+		{typ: "DateTime", value: dateTime, binary: rowBinary(uint32(dateTime.Unix()))},
+		{typ: "DateTime", value: dateTime.Unix(), binary: rowBinary(uint32(dateTime.Unix()))},
+		{
+			// the local time is written like the text encoder does
+			typ:       "DateTime",
+			value:     time.Date(2011, 3, 6, 9, 20, 0, 0, time.UTC),
+			binary:    rowBinary(uint32(dateTime.Unix())),
+			serverLoc: moscow,
+		},
+		{
+			typ:    "DateTime('Europe/Moscow')",
+			value:  "2011-03-06 09:20:00",
+			binary: rowBinary(uint32(dateTime.Unix())),
+		},
+		{
+			typ:    "DateTime64(3, 'Europe/Moscow')",
+			value:  time.Date(2011, 3, 6, 9, 20, 0, 123456789, time.UTC),
+			binary: rowBinary(dateTime.UnixMilli() + 123),
+		},
+		{
+			typ:    "DateTime64(6)",
+			value:  time.Unix(0, -500000000).UTC(),
+			binary: rowBinary(int64(-500000)),
+		},
+		{typ: "Array(Int16)", value: []int{1, 2}, binary: rowBinary(uint8(2), int16(1), int16(2))},
+		{typ: "Array(Int16)", value: Array([]int16{}), binary: rowBinary(uint8(0))},
+		{
+			typ:    "Array(Nullable(Int8))",
+			value:  []interface{}{1, nil},
+			binary: rowBinary(uint8(2), uint8(0), int8(1), uint8(1)),
+		},
+		{
+			typ: "Tuple(String, UInt8)",
+			value: Tuple(struct {
+				A string
+				b int
+				B uint8
+			}{"a", 0, 1}),
+			binary: rowBinary("a", uint8(1)),
+		},
+		{typ: "Tuple(String, UInt8)", value: []interface{}{"a", 1}, binary: rowBinary("a", uint8(1))},
+		{
+			typ:    "Tuple(a String, `b c` UInt8)",
+			value:  map[string]interface{}{"b c": 1, "a": "a"},
+			binary: rowBinary("a", uint8(1)),
+		},
+		{
+			typ: "Nested(a String, b Int8)",
+			value: []interface{}{
+				map[string]interface{}{"a": "x", "b": 1},
+				struct {
+					A string
+					B int8
+				}{"y", 2},
+			},
+			binary: rowBinary(uint8(2), "x", int8(1), "y", int8(2)),
+		},
+		{
+			typ:    "Map(String, Array(Int64))",
+			value:  Map(map[string][]int64{"k": {1}}),
+			binary: rowBinary(uint8(1), "k", uint8(1), int64(1)),
+		},
+		{typ: "LowCardinality(Nullable(String))", value: nil, binary: rowBinary(uint8(1))},
+		{typ: "SimpleAggregateFunction(sum, UInt64)", value: 5, binary: rowBinary(uint64(5))},
+		{typ: "Variant(UInt64, String)", value: 1, binary: rowBinary(uint8(1), uint64(1))},
+		{typ: "Variant(UInt64, String)", value: "1", binary: rowBinary(uint8(0), "1")},
+		{typ: "Variant(UInt64, String)", value: nil, binary: rowBinary(uint8(255))},
+		{typ: "Variant(Int64, UInt8)", value: uint8(1), binary: rowBinary(uint8(1), uint8(1))},
+		{typ: "Variant(Int64, String)", value: Variant{Type: "String", Value: "1"}, binary: rowBinary(uint8(1), "1")},
+		{typ: "Variant(Date, String)", value: "2012-05-31", binary: rowBinary(uint8(1), "2012-05-31")},
+		{typ: "String", value: Variant{Type: "String", Value: "a"}, binary: rowBinary("a")},
+		{typ: "Dynamic", value: int64(-1), binary: rowBinary(uint8(0x0a), int64(-1))},
+		{typ: "Dynamic", value: nil, binary: rowBinary(uint8(0x00))},
+		{typ: "Dynamic", value: Variant{}, binary: rowBinary(uint8(0x00))},
+		{
+			typ:    "Dynamic",
+			value:  Variant{Type: "DateTime64(3, 'UTC')", Value: time.Unix(0, -500000000).UTC()},
+			binary: rowBinary([]byte{0x14, 3}, "UTC", int64(-500)),
+		},
+		{
+			typ:    "Dynamic",
+			value:  []interface{}{"a", nil},
+			binary: rowBinary([]byte{0x1e, 0x23, 0x15}, uint8(2), uint8(0), "a", uint8(1)),
+		},
+		{
+			typ:    "JSON",
+			value:  map[string]interface{}{"c": "x", "a": map[string]interface{}{"b": -1}, "d": nil},
+			binary: rowBinary(uint8(2), "a.b", uint8(0x0a), int64(-1), "c", uint8(0x15), "x"),
+		},
+		{
+			typ: "JSON(a.b UInt32, SKIP c, SKIP REGEXP '^d')",
+			value: JSON(struct {
+				A struct {
+					B int `json:"b"`
+				} `json:"a"`
+				C string  `json:"c"`
+				D string  `json:"d"`
+				E float64 `json:"e"`
+			}{C: "x", D: "y", E: 1.5}),
+			binary: rowBinary(uint8(2), "a.b", uint32(0), "e", uint8(0x0e), 1.5),
+		},
+		{typ: "JSON", value: `{"a":true}`, binary: rowBinary(uint8(1), "a", uint8(0x2d), uint8(1))},
+		{typ: "String", value: JSON(map[string]int{"a": 1}), binary: rowBinary(`{"a":1}`)},
+		{typ: "Point", value: Point{1.5, -2}, binary: rowBinary(1.5, -2.0)},
+		{typ: "Point", value: []float64{1.5, -2}, binary: rowBinary(1.5, -2.0)},
+		{typ: "Ring", value: Ring{{1, 2}}, binary: rowBinary(uint8(1), 1.0, 2.0)},
+		{typ: "Polygon", value: Polygon{{{1, 2}}, {}}, binary: rowBinary(uint8(2), uint8(1), 1.0, 2.0, uint8(0))},
+		{
+			typ:    "MultiPolygon",
+			value:  MultiPolygon{{{{1, 2}}}},
+			binary: rowBinary(uint8(1), uint8(1), uint8(1), 1.0, 2.0),
+		},
+		{typ: "Dynamic", value: Point{1, 2}, binary: rowBinary(uint8(0x2c), "Point", 1.0, 2.0)},
+	}
+
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		e, err := newBinaryEncoder(desc, tc.serverLoc)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		b, err := encodeBinary(e, nil, tc.value)
+		if assert.NoError(t, err, tc.typ) {
+			assert.Equal(t, tc.binary, b, tc.typ)
+		}
+
+		// the encoded value is decoded back
+		p, err := newBinaryParser(desc, nil, tc.serverLoc)
+		if assert.NoError(t, err, tc.typ) {
+			r := newBinaryReader(bytes.NewReader(b))
+			_, err = p.decode(r)
+			assert.NoError(t, err, tc.typ)
+			assert.True(t, r.atEOF(), "%s: trailing data", tc.typ)
+		}
+	}
+}
+
+func TestBinaryEncoderErrors(t *testing.T) {
+	testCases := []struct {
+		typ   string
+		value interface{}
+		err   string
+	}{
+		{"Int8", 128, "value 128 is out of range of 8 bit integer"},
+		{"Int8", -129, "value -129 is out of range of 8 bit integer"},
+		{"UInt8", -1, "value -1 is out of range of 8 bit integer"},
+		{"UInt64", 1.5, "unexpected type float64 of an integer"},
+		{"String", nil, "unexpected NULL"},
+		{"FixedString(2)", "abc", "string of 3 bytes is too long for FixedString(2)"},
+		{"Decimal(9, 2)", "1.001", "decimal 1.001 has more than 2 digits after the point"},
+		{"Decimal(9, 2)", "10000000000", "value 1000000000000 is out of range of 32 bit integer"},
+		{"Int128", "170141183460469231731687303715884105728", "value 170141183460469231731687303715884105728 is out of range of 128 bit integer"},
+		{"UInt128", -1, "value -1 is out of range of 128 bit integer"},
+		{"UInt256", "x", "malformed 256 bit integer x"},
+		{"Enum8('a' = 1)", "b", "unknown enum name 'b'"},
+		{"Enum8('a' = 1)", 2, "unknown enum value 2"},
+		{"IPv4", "::1", "::1 is not an IPv4 address"},
+		{"Array(String)", []interface{}{nil}, "failed to encode array element: unexpected NULL"},
+		{"Tuple(String, UInt8)", []interface{}{"a"}, "tuple of 2 elements got 1 values"},
+		{"Tuple(String, UInt8)", map[string]interface{}{"a": "a"}, "expected struct, got map[string]interface {}"},
+		{"Tuple(a String, b UInt8)", map[string]interface{}{"a": "a", "c": 1}, "no value of the tuple element b"},
+		{"Nested(a String)", []map[string]string{{}}, "failed to encode array element: tuple of 1 elements got 0 values"},
+		{"Variant(UInt64, String)", 1.5, "value of type float64 has none of the types of Variant"},
+		{"Variant(UInt64, String)", Variant{Type: "Int8", Value: 1}, "type Int8 is not one of the types of Variant"},
+		{"Dynamic", struct{}{}, "unsupported type struct {} of a Dynamic value"},
+		{"Dynamic", []interface{}{1, "a"}, "array of values of different types Int64 and String"},
+		{"JSON", "[1]", "malformed JSON object: json: cannot unmarshal array into Go value of type map[string]interface {}"},
+		{"JSON(a Int8)", map[string]int{"a": 300}, "failed to encode JSON path a: value 300 is out of range of 8 bit integer"},
+	}
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		e, err := newBinaryEncoder(desc, nil)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		_, err = encodeBinary(e, nil, tc.value)
+		assert.EqualError(t, err, tc.err, tc.typ)
+	}
+
+	for _, typ := range []string{"Nothing", "AggregateFunction(uniq, String)", "Enum8()"} {
+		desc, err := ParseTypeDesc(typ)
+		if assert.NoError(t, err, typ) {
+			_, err = newBinaryEncoder(desc, nil)
+			assert.Error(t, err, typ)
+		}
+	}
+}
@@ -0,0 +1,763 @@
+package clickhouse
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// binaryParser decodes a value encoded in RowBinary format.
+// Type returns the same type as the DataParser of the same ClickHouse type,
+// so the rows look the same whatever format is used.
+type binaryParser interface {
+	decode(r *binaryReader) (driver.Value, error)
+	Type() reflect.Type
+}
+
+type binaryNothingParser struct {
+	nothingParser
+}
+
+func (p *binaryNothingParser) decode(r *binaryReader) (driver.Value, error) {
+	return nil, nil
+}
+
+type binaryNullableParser struct {
+	binaryParser
+}
+
+func (p *binaryNullableParser) decode(r *binaryReader) (driver.Value, error) {
+	isNull, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if isNull != 0 {
+		return nil, nil
+	}
+	return p.binaryParser.decode(r)
+}
+
+type binaryBoolParser struct {
+	boolParser
+}
+
+func (p *binaryBoolParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	return b != 0, nil
+}
+
+type binaryIntParser struct {
+	intParser
+}
+
+func (p *binaryIntParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(p.bitSize / 8)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.bitSize == 8 && p.signed:
+		return int8(b[0]), nil
+	case p.bitSize == 8:
+		return b[0], nil
+	case p.bitSize == 16 && p.signed:
+		return int16(binary.LittleEndian.Uint16(b)), nil
+	case p.bitSize == 16:
+		return binary.LittleEndian.Uint16(b), nil
+	case p.bitSize == 32 && p.signed:
+		return int32(binary.LittleEndian.Uint32(b)), nil
+	case p.bitSize == 32:
+		return binary.LittleEndian.Uint32(b), nil
+	case p.bitSize == 64 && p.signed:
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	case p.bitSize == 64:
+		return binary.LittleEndian.Uint64(b), nil
+	default:
+		panic("unsupported bit size")
+	}
+}
+
+type binaryBigIntParser struct {
+	bigIntParser
+}
+
+func (p *binaryBigIntParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(p.bitSize / 8)
+	if err != nil {
+		return nil, err
+	}
+	if p.signed {
+		return littleEndianInt(b), nil
+	}
+	return littleEndianUint(b), nil
+}
+
+type binaryFloatParser struct {
+	floatParser
+}
+
+func (p *binaryFloatParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(p.bitSize / 8)
+	if err != nil {
+		return nil, err
+	}
+	switch p.bitSize {
+	case 32:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case 64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	default:
+		panic("unsupported bit size")
+	}
+}
+
+type binaryStringParser struct {
+	stringParser
+}
+
+func (p *binaryStringParser) decode(r *binaryReader) (driver.Value, error) {
+	if p.length > 0 {
+		b, err := r.readFull(p.length)
+		return string(b), err
+	}
+	return r.readString()
+}
+
+// binaryDecimalParser decodes a decimal into Decimal
+type binaryDecimalParser struct {
+	decimalParser
+	size int
+}
+
+func (p *binaryDecimalParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(p.size)
+	if err != nil {
+		return nil, err
+	}
+	var unscaled *big.Int
+	switch p.size {
+	case 4:
+		unscaled = big.NewInt(int64(int32(binary.LittleEndian.Uint32(b))))
+	case 8:
+		unscaled = big.NewInt(int64(binary.LittleEndian.Uint64(b)))
+	default:
+		unscaled = littleEndianInt(b)
+	}
+	return p.value(unscaled), nil
+}
+
+// littleEndianInt converts a little endian two's complement integer to big.Int
+func littleEndianInt(b []byte) *big.Int {
+	v := littleEndianUint(b)
+	if len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return v
+}
+
+// littleEndianUint converts a little endian unsigned integer to big.Int
+func littleEndianUint(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i, c := range b {
+		be[len(b)-1-i] = c
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+// formatDecimal formats the unscaled value of a decimal like ClickHouse does,
+// without trailing zeros
+func formatDecimal(digits string, scale int) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	intPart, fracPart := digits[:len(digits)-scale], strings.TrimRight(digits[len(digits)-scale:], "0")
+	if fracPart == "" {
+		return sign + intPart
+	}
+	return sign + intPart + "." + fracPart
+}
+
+// enumNames maps the codes of the values of an enum to their names
+func enumNames(values map[string]int16) map[int16]string {
+	names := make(map[int16]string, len(values))
+	for name, code := range values {
+		names[code] = name
+	}
+	return names
+}
+
+// binaryEnumParser decodes the value of an enum into its name
+type binaryEnumParser struct {
+	stringParser
+	size   int
+	values map[int16]string
+}
+
+func (p *binaryEnumParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(p.size)
+	if err != nil {
+		return nil, err
+	}
+	v := int16(int8(b[0]))
+	if p.size == 2 {
+		v = int16(binary.LittleEndian.Uint16(b))
+	}
+	name, ok := p.values[v]
+	if !ok {
+		return nil, fmt.Errorf("unknown enum value %d", v)
+	}
+	return name, nil
+}
+
+type binaryUUIDParser struct {
+	uuidParser
+}
+
+func (p *binaryUUIDParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(16)
+	if err != nil {
+		return nil, err
+	}
+	// UUID is written as two little endian UInt64
+	var u uuid.UUID
+	for i := 0; i < 8; i++ {
+		u[i] = b[7-i]
+		u[8+i] = b[15-i]
+	}
+	if p.asString {
+		return u.String(), nil
+	}
+	return u, nil
+}
+
+type binaryIPv4Parser struct {
+	ipParser
+}
+
+func (p *binaryIPv4Parser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(4)
+	if err != nil {
+		return nil, err
+	}
+	return p.value(netip.AddrFrom4([4]byte{b[3], b[2], b[1], b[0]})), nil
+}
+
+type binaryIPv6Parser struct {
+	ipParser
+}
+
+func (p *binaryIPv6Parser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(16)
+	if err != nil {
+		return nil, err
+	}
+	return p.value(netip.AddrFrom16(*(*[16]byte)(b))), nil
+}
+
+type binaryDateParser struct {
+	dateTimeParser
+}
+
+func (p *binaryDateParser) decode(r *binaryReader) (driver.Value, error) {
+	b, err := r.readFull(2)
+	if err != nil {
+		return nil, err
+	}
+	days := int(binary.LittleEndian.Uint16(b))
+	return time.Date(1970, 1, 1+days, 0, 0, 0, 0, p.location), nil
+}
+
+// binaryDateTimeParser decodes DateTime and DateTime64. Their text representation
+// is the local time in formatLocation, which is parsed in location by DataParser,
+// so the same conversion is made here.
+type binaryDateTimeParser struct {
+	dateTimeParser
+	formatLocation *time.Location
+}
+
+func (p *binaryDateTimeParser) decode(r *binaryReader) (driver.Value, error) {
+	if p.format != dateTime64Format {
+		b, err := r.readFull(4)
+		if err != nil {
+			return nil, err
+		}
+		return p.toLocation(time.Unix(int64(binary.LittleEndian.Uint32(b)), 0)), nil
+	}
+	b, err := r.readFull(8)
+	if err != nil {
+		return nil, err
+	}
+	ticks := int64(binary.LittleEndian.Uint64(b))
+	scale := int64(math.Pow10(p.precision))
+	sec, frac := ticks/scale, ticks%scale
+	if frac < 0 {
+		sec, frac = sec-1, frac+scale
+	}
+	nsec := frac
+	if p.precision <= 9 {
+		nsec *= int64(math.Pow10(9 - p.precision))
+	} else {
+		nsec /= int64(math.Pow10(p.precision - 9))
+	}
+	return p.toLocation(time.Unix(sec, nsec)), nil
+}
+
+func (p *binaryDateTimeParser) toLocation(t time.Time) time.Time {
+	if p.formatLocation == nil {
+		return t.In(p.location)
+	}
+	t = t.In(p.formatLocation)
+	year, month, day := t.Date()
+	hour, minute, sec := t.Clock()
+	return time.Date(year, month, day, hour, minute, sec, t.Nanosecond(), p.location)
+}
+
+type binaryArrayParser struct {
+	arg binaryParser
+}
+
+func (p *binaryArrayParser) Type() reflect.Type {
+	return reflect.SliceOf(p.arg.Type())
+}
+
+func (p *binaryArrayParser) decode(r *binaryReader) (driver.Value, error) {
+	n, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBinaryStringSize {
+		return nil, fmt.Errorf("too long array: %d elements", n)
+	}
+	slice := reflect.MakeSlice(p.Type(), 0, int(n))
+	for i := uint64(0); i < n; i++ {
+		v, err := p.arg.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode array element: %w", err)
+		}
+		if v == nil {
+			if p.arg.Type() == reflectTypeVariant {
+				slice = reflect.Append(slice, reflect.Zero(reflectTypeVariant))
+				continue
+			}
+			if _, ok := p.arg.(*binaryNullableParser); !ok {
+				return nil, fmt.Errorf("unexpected nil element")
+			}
+			// the same as arrayParser does
+			continue
+		}
+		slice = reflect.Append(slice, reflect.ValueOf(v))
+	}
+	return slice.Interface(), nil
+}
+
+type binaryTupleParser struct {
+	args  []binaryParser
+	names []string // the names of the elements of a named tuple
+}
+
+func (p *binaryTupleParser) Type() reflect.Type {
+	if p.names != nil {
+		return reflectTypeNamedTuple
+	}
+	types := make([]reflect.Type, len(p.args))
+	for i, arg := range p.args {
+		types[i] = arg.Type()
+	}
+	return tupleType(types)
+}
+
+func (p *binaryTupleParser) decode(r *binaryReader) (driver.Value, error) {
+	values := make([]driver.Value, len(p.args))
+	for i, arg := range p.args {
+		v, err := arg.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tuple element: %w", err)
+		}
+		values[i] = v
+	}
+	return tupleValue(p.Type(), p.names, values), nil
+}
+
+type binaryMapParser struct {
+	key   binaryParser
+	value binaryParser
+}
+
+func (p *binaryMapParser) Type() reflect.Type {
+	return reflect.MapOf(p.key.Type(), p.value.Type())
+}
+
+func (p *binaryMapParser) decode(r *binaryReader) (driver.Value, error) {
+	n, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBinaryStringSize {
+		return nil, fmt.Errorf("too large map: %d elements", n)
+	}
+	m := reflect.MakeMapWithSize(p.Type(), int(n))
+	for i := uint64(0); i < n; i++ {
+		k, err := p.key.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode map key: %w", err)
+		}
+		v, err := p.value.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode map value: %w", err)
+		}
+		m.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(v))
+	}
+	return m.Interface(), nil
+}
+
+// binaryVariantParser decodes the values of Variant columns, which are
+// preceded by the index of their type in the arguments of Variant
+type binaryVariantParser struct {
+	types []string
+	args  []binaryParser
+}
+
+func (p *binaryVariantParser) Type() reflect.Type {
+	return reflectTypeVariant
+}
+
+func (p *binaryVariantParser) decode(r *binaryReader) (driver.Value, error) {
+	discriminator, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if discriminator == binaryVariantNullDiscriminator {
+		return nil, nil
+	}
+	if int(discriminator) >= len(p.args) {
+		return nil, fmt.Errorf("unknown discriminator %d of Variant", discriminator)
+	}
+	v, err := p.args[discriminator].decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Variant value: %w", err)
+	}
+	return Variant{Type: p.types[discriminator], Value: v}, nil
+}
+
+// binaryDynamicParser decodes the values of Dynamic columns,
+// which are preceded by their types in the binary encoding
+type binaryDynamicParser struct {
+	opt            *DataParserOptions
+	serverLocation *time.Location
+	parsers        map[string]binaryParser // the parsers of the types met before
+}
+
+func (p *binaryDynamicParser) Type() reflect.Type {
+	return reflectTypeVariant
+}
+
+func (p *binaryDynamicParser) decode(r *binaryReader) (driver.Value, error) {
+	desc, err := readBinaryType(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the type of Dynamic value: %w", err)
+	}
+	if desc.Name == "Nothing" {
+		return nil, nil
+	}
+	typ := desc.String()
+	parser, ok := p.parsers[typ]
+	if !ok {
+		if parser, err = newBinaryParser(desc, p.opt, p.serverLocation); err != nil {
+			return nil, fmt.Errorf("failed to create parser for Dynamic value: %w", err)
+		}
+		if p.parsers == nil {
+			p.parsers = make(map[string]binaryParser)
+		}
+		p.parsers[typ] = parser
+	}
+	v, err := parser.decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Dynamic value: %w", err)
+	}
+	return Variant{Type: typ, Value: v}, nil
+}
+
+// binaryJSONParser decodes JSON objects, which are written as the values of their paths.
+// The values of the typed paths are written as their types and the others as Dynamic.
+type binaryJSONParser struct {
+	jsonParser
+	typed   map[string]binaryParser
+	dynamic *binaryDynamicParser
+}
+
+func (p *binaryJSONParser) decode(r *binaryReader) (driver.Value, error) {
+	n, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBinaryStringSize {
+		return nil, fmt.Errorf("too many JSON paths: %d", n)
+	}
+	object := make(map[string]interface{})
+	for i := uint64(0); i < n; i++ {
+		path, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		var parser binaryParser = p.dynamic
+		if typed, ok := p.typed[path]; ok {
+			parser = typed
+		}
+		v, err := parser.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode JSON path %s: %w", path, err)
+		}
+		if v = jsonValue(v); v != nil {
+			setJSONPath(object, path, v)
+		}
+	}
+	if p.raw {
+		b, err := json.Marshal(object)
+		if err != nil {
+			return nil, err
+		}
+		return json.RawMessage(b), nil
+	}
+	return object, nil
+}
+
+// binaryGeoParser decodes the values of geo columns
+type binaryGeoParser struct {
+	binaryParser
+	typ reflect.Type
+}
+
+func (p *binaryGeoParser) Type() reflect.Type {
+	return p.typ
+}
+
+func (p *binaryGeoParser) decode(r *binaryReader) (driver.Value, error) {
+	v, err := p.binaryParser.decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return geoValue(p.typ, v), nil
+}
+
+// newBinaryParser creates a binaryParser for the given TypeDesc.
+// serverLocation is the time zone used by the server for DateTime columns
+// without their own time zone, it may be nil if it is unknown.
+func newBinaryParser(t *TypeDesc, opt *DataParserOptions, serverLocation *time.Location) (binaryParser, error) {
+	switch t.Name {
+	case "Nothing":
+		return &binaryNothingParser{}, nil
+	case "Nullable":
+		if len(t.Args) == 0 {
+			return nil, fmt.Errorf("Nullable should pass original type")
+		}
+		p, err := newBinaryParser(t.Args[0], opt, serverLocation)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryNullableParser{p}, nil
+	case "Date":
+		loc := time.UTC
+		if opt != nil && opt.Location != nil {
+			loc = opt.Location
+		}
+		return &binaryDateParser{dateTimeParser{location: loc}}, nil
+	case "DateTime", "DateTime64":
+		// the arguments are checked in the same way as for text
+		p, err := newDataParser(t, false, opt)
+		if err != nil {
+			return nil, err
+		}
+		formatLocation := serverLocation
+		if tz := len(t.Args); t.Name == "DateTime" && tz > 0 || t.Name == "DateTime64" && tz > 1 {
+			if formatLocation, err = time.LoadLocation(t.Args[tz-1].Name); err != nil {
+				return nil, err
+			}
+		}
+		return &binaryDateTimeParser{dateTimeParser: *p.(*dateTimeParser), formatLocation: formatLocation}, nil
+	case "Bool":
+		return &binaryBoolParser{}, nil
+	case "UInt8":
+		return &binaryIntParser{intParser{false, 8}}, nil
+	case "UInt16":
+		return &binaryIntParser{intParser{false, 16}}, nil
+	case "UInt32":
+		return &binaryIntParser{intParser{false, 32}}, nil
+	case "UInt64":
+		return &binaryIntParser{intParser{false, 64}}, nil
+	case "Int8":
+		return &binaryIntParser{intParser{true, 8}}, nil
+	case "Int16":
+		return &binaryIntParser{intParser{true, 16}}, nil
+	case "Int32":
+		return &binaryIntParser{intParser{true, 32}}, nil
+	case "Int64":
+		return &binaryIntParser{intParser{true, 64}}, nil
+	case "UInt128":
+		return &binaryBigIntParser{bigIntParser{false, 128}}, nil
+	case "UInt256":
+		return &binaryBigIntParser{bigIntParser{false, 256}}, nil
+	case "Int128":
+		return &binaryBigIntParser{bigIntParser{true, 128}}, nil
+	case "Int256":
+		return &binaryBigIntParser{bigIntParser{true, 256}}, nil
+	case "Float32":
+		return &binaryFloatParser{floatParser{32}}, nil
+	case "Float64":
+		return &binaryFloatParser{floatParser{64}}, nil
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		precision, scale, err := decimalArgs(t)
+		if err != nil {
+			return nil, err
+		}
+		return &binaryDecimalParser{
+			decimalParser{scale: scale, asString: opt != nil && opt.DecimalAsString},
+			decimalSize(precision),
+		}, nil
+	case "Enum8", "Enum16":
+		if t.Enum == nil {
+			return nil, fmt.Errorf("no values of %s", t.Name)
+		}
+		size := 1
+		if t.Name == "Enum16" {
+			size = 2
+		}
+		return &binaryEnumParser{size: size, values: enumNames(t.Enum)}, nil
+	case "String":
+		return &binaryStringParser{}, nil
+	case "UUID":
+		return &binaryUUIDParser{uuidParser{asString: opt != nil && opt.UUIDAndIPAsString}}, nil
+	case "IPv4":
+		return &binaryIPv4Parser{ipParser{asString: opt != nil && opt.UUIDAndIPAsString}}, nil
+	case "IPv6":
+		return &binaryIPv6Parser{ipParser{asString: opt != nil && opt.UUIDAndIPAsString}}, nil
+	case "FixedString":
+		if len(t.Args) != 1 {
+			return nil, fmt.Errorf("length not specified for FixedString")
+		}
+		length, err := strconv.Atoi(t.Args[0].Name)
+		if err != nil {
+			return nil, fmt.Errorf("malformed length specified for FixedString: %v", err)
+		}
+		if length <= 0 {
+			return nil, fmt.Errorf("malformed length specified for FixedString: %d", length)
+		}
+		return &binaryStringParser{stringParser{length: length}}, nil
+	case "Array":
+		if len(t.Args) != 1 {
+			return nil, fmt.Errorf("element type not specified for Array")
+		}
+		subParser, err := newBinaryParser(t.Args[0], opt, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for array elements: %v", err)
+		}
+		return &binaryArrayParser{subParser}, nil
+	case "Tuple":
+		if len(t.Args) < 1 {
+			return nil, fmt.Errorf("element types not specified for Tuple")
+		}
+		subParsers := make([]binaryParser, len(t.Args))
+		for i, arg := range t.Args {
+			subParser, err := newBinaryParser(arg, opt, serverLocation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create parser for tuple element: %v", err)
+			}
+			subParsers[i] = subParser
+		}
+		return &binaryTupleParser{subParsers, elemNames(t)}, nil
+	case "Nested":
+		// Nested is written as an array of named tuples
+		subParser, err := newBinaryParser(&TypeDesc{Name: "Tuple", Args: t.Args}, opt, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for Nested: %v", err)
+		}
+		return &binaryArrayParser{subParser}, nil
+	case "LowCardinality":
+		// LowCardinality is written as its element type
+		if len(t.Args) != 1 {
+			return nil, fmt.Errorf("element type not specified for LowCardinality")
+		}
+		return newBinaryParser(t.Args[0], opt, serverLocation)
+	case "SimpleAggregateFunction":
+		if len(t.Args) != 2 {
+			return nil, fmt.Errorf("incorrect number of arguments for SimpleAggregateFunction")
+		}
+		return newBinaryParser(t.Args[1], opt, serverLocation)
+	case "Map":
+		if len(t.Args) != 2 {
+			return nil, fmt.Errorf("incorrect number of arguments for Map")
+		}
+		keyParser, err := newBinaryParser(t.Args[0], opt, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for map keys: %v", err)
+		}
+		valueParser, err := newBinaryParser(t.Args[1], opt, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for map values: %v", err)
+		}
+		return &binaryMapParser{
+			key:   keyParser,
+			value: valueParser,
+		}, nil
+	case "Variant":
+		if len(t.Args) < 1 {
+			return nil, fmt.Errorf("types not specified for Variant")
+		}
+		args := variantArgs(t)
+		p := &binaryVariantParser{types: make([]string, len(args)), args: make([]binaryParser, len(args))}
+		for i, arg := range args {
+			argParser, err := newBinaryParser(arg, opt, serverLocation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create parser for Variant type: %v", err)
+			}
+			p.types[i], p.args[i] = arg.String(), argParser
+		}
+		return p, nil
+	case "Dynamic":
+		return &binaryDynamicParser{opt: opt, serverLocation: serverLocation}, nil
+	case "Point", "Ring", "Polygon", "MultiPolygon":
+		p, err := newBinaryParser(geoTypeDesc(t.Name), opt, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for %s: %v", t.Name, err)
+		}
+		return &binaryGeoParser{p, geoTypes[t.Name]}, nil
+	case "JSON":
+		p := &binaryJSONParser{
+			jsonParser: jsonParser{raw: opt != nil && opt.JSONAsRaw},
+			typed:      make(map[string]binaryParser),
+			dynamic:    &binaryDynamicParser{opt: opt, serverLocation: serverLocation},
+		}
+		for _, arg := range t.Args {
+			if arg.ElemName == "" {
+				continue
+			}
+			typed, err := newBinaryParser(arg, opt, serverLocation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create parser for JSON path %s: %v", arg.ElemName, err)
+			}
+			p.typed[arg.ElemName] = typed
+		}
+		return p, nil
+	default:
+		return nil, fmt.Errorf("type %s is not supported", t.Name)
+	}
+}
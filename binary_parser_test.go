@@ -0,0 +1,336 @@
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rowBinary encodes the values in RowBinary format
+func rowBinary(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			var n [binary.MaxVarintLen64]byte
+			buf.Write(n[:binary.PutUvarint(n[:], uint64(len(v)))])
+			buf.WriteString(v)
+		case []byte:
+			buf.Write(v)
+		default:
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+// leInt encodes v as a little endian integer of the given size
+func leInt(v int64, size int) []byte {
+	b := make([]byte, size)
+	binary.LittleEndian.PutUint64(b, uint64(v))
+	if v < 0 {
+		for i := 8; i < size; i++ {
+			b[i] = 0xff
+		}
+	}
+	return b
+}
+
+func TestBinaryParser(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("failed to load time zone Europe/Moscow: %v", err)
+	}
+	dateTime := time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC)
+	testCases := []struct {
+		typ       string
+		text      string
+		binary    []byte
+		opt       *DataParserOptions
+		serverLoc *time.Location
+	}{
+		{typ: "Int8", text: "-1", binary: rowBinary(int8(-1))},
+		{typ: "Int16", text: "-300", binary: rowBinary(int16(-300))},
+		{typ: "Int32", text: "-70000", binary: rowBinary(int32(-70000))},
+		{typ: "Int64", text: "-5000000000", binary: rowBinary(int64(-5000000000))},
+		{typ: "UInt8", text: "255", binary: rowBinary(uint8(255))},
+		{typ: "UInt16", text: "65535", binary: rowBinary(uint16(65535))},
+		{typ: "UInt32", text: "4294967295", binary: rowBinary(uint32(math.MaxUint32))},
+		{typ: "UInt64", text: "18446744073709551615", binary: rowBinary(uint64(math.MaxUint64))},
+		{typ: "Int128", text: "-1", binary: leInt(-1, 16)},
+		{typ: "Int128", text: "18446744073709551616", binary: rowBinary(uint64(0), uint64(1))},
+		{typ: "UInt128", text: "340282366920938463463374607431768211455", binary: leInt(-1, 16)},
+		{typ: "Int256", text: "-2", binary: leInt(-2, 32)},
+		{typ: "UInt256", text: "258", binary: leInt(258, 32)},
+		{typ: "Float32", text: "1.5", binary: rowBinary(float32(1.5))},
+		{typ: "Float64", text: "-0.1", binary: rowBinary(float64(-0.1))},
+		{typ: "Float64", text: "inf", binary: rowBinary(math.Inf(1))},
+		{typ: "Bool", text: "true", binary: rowBinary(uint8(1))},
+		{typ: "Bool", text: "false", binary: rowBinary(uint8(0))},
+		{typ: "String", text: `a\tb\\c`, binary: rowBinary("a\tb\\c")},
+		{typ: "String", text: "", binary: rowBinary("")},
+		{typ: "FixedString(3)", text: "abc", binary: rowBinary([]byte("abc"))},
+		{typ: "FixedString(3)", text: `a\0\0`, binary: rowBinary([]byte("a\x00\x00"))},
+		{typ: "Nullable(String)", text: `\N`, binary: rowBinary(uint8(1))},
+		{typ: "Nullable(String)", text: "x", binary: rowBinary(uint8(0), "x")},
+		{typ: "Nullable(UInt8)", text: "5", binary: rowBinary(uint8(0), uint8(5))},
+		{typ: "Nullable(Nothing)", text: `\N`, binary: rowBinary(uint8(1))},
+		{typ: "Decimal(9, 4)", text: "10.1111", binary: rowBinary(int32(101111))},
+		{typ: "Decimal(9, 4)", text: "0.0001", binary: rowBinary(int32(1))},
+		{typ: "Decimal(9, 4)", text: "-0.5", binary: rowBinary(int32(-5000))},
+		{typ: "Decimal(9, 0)", text: "42", binary: rowBinary(int32(42))},
+		{typ: "Decimal(18, 4)", text: "-100", binary: rowBinary(int64(-1000000))},
+		{typ: "Decimal(38, 4)", text: "1000.1111", binary: leInt(10001111, 16)},
+		{typ: "Decimal(76, 2)", text: "-1.01", binary: leInt(-101, 32)},
+		{typ: "Decimal32(2)", text: "1.5", binary: rowBinary(int32(150))},
+		{typ: "Decimal256(3)", text: "-7", binary: leInt(-7000, 32)},
+		{typ: "Decimal(9, 4)", text: "10.1111", binary: rowBinary(int32(101111)), opt: &DataParserOptions{DecimalAsString: true}},
+		{typ: "Enum8('hello' = 1, 'world' = 2)", text: "world", binary: rowBinary(int8(2))},
+		{typ: "Enum16('a' = -1000, 'b' = 1000)", text: "a", binary: rowBinary(int16(-1000))},
+		{
+			typ:    "UUID",
+			text:   "123e4567-e89b-12d3-a456-426614174000",
+			binary: rowBinary(uint64(0x123e4567e89b12d3), uint64(0xa456426614174000)),
+		},
+		{
+			typ:    "UUID",
+			text:   "123e4567-e89b-12d3-a456-426614174000",
+			binary: rowBinary(uint64(0x123e4567e89b12d3), uint64(0xa456426614174000)),
+			opt:    &DataParserOptions{UUIDAndIPAsString: true},
+		},
+		{typ: "IPv4", text: "127.0.0.1", binary: rowBinary(uint32(0x7f000001))},
+		{typ: "IPv4", text: "8.8.8.8", binary: rowBinary(uint32(0x08080808)), opt: &DataParserOptions{UUIDAndIPAsString: true}},
+		{
+			typ:    "IPv6",
+			text:   "2001:db8::1",
+			binary: rowBinary([]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}),
+		},
+		{
+			typ:    "IPv6",
+			text:   "::ffff:127.0.0.1",
+			binary: rowBinary([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 127, 0, 0, 1}),
+		},
+		{
+			typ:    "Date",
+			text:   "2011-03-06",
+			binary: rowBinary(uint16(dateTime.Unix() / 86400)),
+			opt:    &DataParserOptions{Location: moscow},
+		},
+		{typ: "DateTime", text: "2011-03-06 06:20:00", binary: rowBinary(uint32(dateTime.Unix()))},
+		{
+			// the server formats the time in its time zone, which is parsed in the location of the connection
+			typ:       "DateTime",
+			text:      "2011-03-06 09:20:00",
+			binary:    rowBinary(uint32(dateTime.Unix())),
+			opt:       &DataParserOptions{Location: time.UTC},
+			serverLoc: moscow,
+		},
+		{
+			typ:    "DateTime('Europe/Moscow')",
+			text:   "2011-03-06 09:20:00",
+			binary: rowBinary(uint32(dateTime.Unix())),
+			opt:    &DataParserOptions{Location: time.UTC, UseDBLocation: true},
+		},
+		{
+			typ:    "DateTime('Europe/Moscow')",
+			text:   "2011-03-06 09:20:00",
+			binary: rowBinary(uint32(dateTime.Unix())),
+			opt:    &DataParserOptions{Location: time.UTC},
+		},
+		{
+			typ:    "DateTime64(3, 'Europe/Moscow')",
+			text:   "2011-03-06 09:20:00.123",
+			binary: rowBinary(dateTime.UnixMilli() + 123),
+		},
+		{
+			typ:    "DateTime64(6)",
+			text:   "1969-12-31 23:59:59.500000",
+			binary: rowBinary(int64(-500000)),
+		},
+		{typ: "Array(Int16)", text: "[1,2]", binary: rowBinary(uint8(2), int16(1), int16(2))},
+		{typ: "Array(Nothing)", text: "[]", binary: rowBinary(uint8(0))},
+		{
+			typ:    "Array(Nullable(Int8))",
+			text:   `[1,\N,2]`,
+			binary: rowBinary(uint8(3), uint8(0), int8(1), uint8(1), uint8(0), int8(2)),
+		},
+		{typ: "Array(String)", text: `['a','b\'c']`, binary: rowBinary(uint8(2), "a", "b'c")},
+		{typ: "Tuple(String, UInt8)", text: "('a',1)", binary: rowBinary("a", uint8(1))},
+		{typ: "Tuple(a String, `b c` UInt8)", text: "('a',1)", binary: rowBinary("a", uint8(1))},
+		{
+			typ:    "Nested(a String, b Nullable(Int8))",
+			text:   `[('x',1),('y',\N)]`,
+			binary: rowBinary(uint8(2), "x", uint8(0), int8(1), "y", uint8(1)),
+		},
+		{
+			typ:    "Map(String, Array(Int64))",
+			text:   "{'k':[1],'l':[]}",
+			binary: rowBinary(uint8(2), "k", uint8(1), int64(1), "l", uint8(0)),
+		},
+		{typ: "LowCardinality(String)", text: "x", binary: rowBinary("x")},
+		{typ: "LowCardinality(Nullable(String))", text: `\N`, binary: rowBinary(uint8(1))},
+		{typ: "SimpleAggregateFunction(sum, UInt64)", text: "5", binary: rowBinary(uint64(5))},
+		{typ: "Variant(UInt64, String)", text: "1", binary: rowBinary(uint8(1), uint64(1))},
+		{typ: "Variant(UInt64, String)", text: "a", binary: rowBinary(uint8(0), "a")},
+		{typ: "Variant(UInt64, String)", text: `\N`, binary: rowBinary(uint8(255))},
+		{
+			typ:    "Array(Variant(Int8, String))",
+			text:   "[1,NULL]",
+			binary: rowBinary(uint8(2), uint8(0), int8(1), uint8(255)),
+		},
+		{typ: "Point", text: "(1.5,-2)", binary: rowBinary(1.5, -2.0)},
+		{typ: "Ring", text: "[(1,2)]", binary: rowBinary(uint8(1), 1.0, 2.0)},
+		{
+			typ:    "Polygon",
+			text:   "[[(1,2)],[]]",
+			binary: rowBinary(uint8(2), uint8(1), 1.0, 2.0, uint8(0)),
+		},
+		{typ: "MultiPolygon", text: "[[[(1,2)]]]", binary: rowBinary(uint8(1), uint8(1), uint8(1), 1.0, 2.0)},
+		{
+			typ:    "JSON",
+			text:   `{"a":{"b":-1},"c":"x"}`,
+			binary: rowBinary(uint8(2), "a.b", uint8(0x0a), int64(-1), "c", uint8(0x15), "x"),
+		},
+		{
+			typ:    "JSON",
+			text:   `{"a":[1,2]}`,
+			binary: rowBinary(uint8(1), "a", []byte{0x1e, 0x23, 0x0a}, uint8(2), uint8(0), int64(1), uint8(0), int64(2)),
+			opt:    &DataParserOptions{JSONAsRaw: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		textParser, err := NewDataParser(desc, tc.opt)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		expected, err := textParser.Parse(strings.NewReader(tc.text))
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+
+		p, err := newBinaryParser(desc, tc.opt, tc.serverLoc)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		assert.Equal(t, textParser.Type(), p.Type(), tc.typ)
+		r := newBinaryReader(bytes.NewReader(tc.binary))
+		v, err := p.decode(r)
+		if assert.NoError(t, err, tc.typ) {
+			assert.Equal(t, expected, v, tc.typ)
+			assert.True(t, r.atEOF(), "%s: trailing data", tc.typ)
+		}
+	}
+}
+
+func TestBinaryParserErrors(t *testing.T) {
+	testCases := []struct {
+		typ    string
+		binary []byte
+		err    string
+	}{
+		{"Int64", rowBinary(int32(1)), "unexpected EOF"},
+		{"Int128", rowBinary(int64(1)), "unexpected EOF"},
+		{"String", rowBinary(uint8(5), []byte("abc")), "unexpected EOF"},
+		{"Enum8('a' = 1)", rowBinary(int8(2)), "unknown enum value 2"},
+		{"Array(Int8)", rowBinary([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x0f}), "too long array: 70368744177663 elements"},
+		{"Variant(Int8, String)", rowBinary(uint8(2)), "unknown discriminator 2 of Variant"},
+		{"Dynamic", rowBinary(uint8(0xff)), "failed to decode the type of Dynamic value: unsupported binary type 0xff"},
+	}
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		p, err := newBinaryParser(desc, nil, nil)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		_, err = p.decode(newBinaryReader(bytes.NewReader(tc.binary)))
+		assert.EqualError(t, err, tc.err, tc.typ)
+	}
+
+	for _, typ := range []string{"Enum8()", "AggregateFunction(uniq, String)", "FixedString(0)"} {
+		desc, err := ParseTypeDesc(typ)
+		if assert.NoError(t, err, typ) {
+			_, err = newBinaryParser(desc, nil, nil)
+			assert.Error(t, err, typ)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	testCases := []struct {
+		digits   string
+		scale    int
+		expected string
+	}{
+		{"0", 0, "0"},
+		{"0", 2, "0"},
+		{"5", 2, "0.05"},
+		{"-5", 2, "-0.05"},
+		{"120", 2, "1.2"},
+		{"-12000", 3, "-12"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, formatDecimal(tc.digits, tc.scale))
+	}
+}
+
+func TestBinaryParserDynamic(t *testing.T) {
+	dateTime := time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC)
+	testCases := []struct {
+		typ      string
+		binary   []byte
+		expected interface{}
+	}{
+		{"Dynamic", rowBinary(uint8(0x0a), int64(-1)), Variant{Type: "Int64", Value: int64(-1)}},
+		{"Dynamic", rowBinary(uint8(0x00)), nil},
+		{
+			"Dynamic",
+			rowBinary([]byte{0x14, 3}, "UTC", dateTime.UnixMilli()),
+			Variant{Type: "DateTime64(3, 'UTC')", Value: dateTime},
+		},
+		{
+			"Dynamic",
+			rowBinary([]byte{0x1e, 0x23, 0x15}, uint8(2), uint8(0), "a", uint8(1)),
+			Variant{Type: "Array(Nullable(String))", Value: []string{"a"}},
+		},
+		{"Dynamic", rowBinary(uint8(0x2c), "Point", 1.0, 2.0), Variant{Type: "Point", Value: Point{1, 2}}},
+		{
+			"Array(Dynamic)",
+			rowBinary(uint8(2), uint8(0x01), uint8(1), uint8(0x00)),
+			[]interface{}{Variant{Type: "UInt8", Value: uint8(1)}, nil},
+		},
+		{
+			"JSON(a.b UInt32, SKIP c)",
+			rowBinary(uint8(2), "a.b", uint32(1), "a.c", uint8(0x15), "x"),
+			map[string]interface{}{"a": map[string]interface{}{"b": uint32(1), "c": "x"}},
+		},
+	}
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		p, err := newBinaryParser(desc, nil, nil)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		r := newBinaryReader(bytes.NewReader(tc.binary))
+		v, err := p.decode(r)
+		if assert.NoError(t, err, tc.typ) {
+			assert.Equal(t, tc.expected, v, tc.typ)
+			assert.True(t, r.atEOF(), "%s: trailing data", tc.typ)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxBinaryStringSize limits the length of a string in RowBinary,
+	// a larger length means that the data is malformed
+	maxBinaryStringSize = 1 << 30
+	// maxBinaryTailSize limits the size of the data kept to find an exception
+	maxBinaryTailSize = 64 << 10
+)
+
+// binaryReader reads values encoded in RowBinary format
+type binaryReader struct {
+	r   *bufio.Reader
+	buf []byte
+	// tail keeps the data of the last rows, it is needed to find an exception,
+	// since its text could be decoded as a few rows before the decoding fails
+	tail []byte
+}
+
+func newBinaryReader(r io.Reader) *binaryReader {
+	return &binaryReader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// startRow is called before reading every row
+func (r *binaryReader) startRow() {
+	if len(r.tail) > 2*maxBinaryTailSize {
+		r.tail = r.tail[:copy(r.tail, r.tail[len(r.tail)-maxBinaryTailSize:])]
+	}
+}
+
+// readFull reads n bytes, the result is valid till the next call
+func (r *binaryReader) readFull(n int) ([]byte, error) {
+	if cap(r.buf) < n {
+		r.buf = make([]byte, n)
+	}
+	buf := r.buf[:n]
+	read, err := io.ReadFull(r.r, buf)
+	r.tail = append(r.tail, buf[:read]...)
+	if err == io.EOF && n > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf, err
+}
+
+func (r *binaryReader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	r.tail = append(r.tail, b)
+	return b, nil
+}
+
+func (r *binaryReader) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(r)
+}
+
+// ReadByte implements io.ByteReader
+func (r *binaryReader) ReadByte() (byte, error) {
+	return r.readByte()
+}
+
+func (r *binaryReader) readString() (string, error) {
+	n, err := r.readUvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > maxBinaryStringSize {
+		return "", fmt.Errorf("too long string: %d bytes", n)
+	}
+	b, err := r.readFull(int(n))
+	return string(b), err
+}
+
+// atEOF reports whether all the data has been read
+func (r *binaryReader) atEOF() bool {
+	_, err := r.r.Peek(1)
+	return err == io.EOF
+}
+
+// readRest returns the rest of the data, but not more than maxExceptionSize
+func (r *binaryReader) readRest() (string, error) {
+	rest, err := io.ReadAll(io.LimitReader(r.r, maxExceptionSize))
+	return string(rest), err
+}
+
+// exception returns the exception written by ClickHouse at the end of the response
+// if the data which is not decoded yet ends with it, it is checked before every row,
+// since the text of an exception could be decoded as a few valid rows
+func (r *binaryReader) exception() error {
+	n := r.r.Buffered()
+	for {
+		data, _ := r.r.Peek(n)
+		text := data[bytes.LastIndexFunc(data, notText)+1:]
+		loc := exceptionStartRe.FindIndex(text)
+		if loc == nil {
+			return nil
+		}
+		start := n - len(text) + loc[0]
+		// the data could end with the exception, so it is checked when the rest
+		// of the data arrives, which doesn't block while it is not a text,
+		// the peeked data is taken again since reading more could move it
+		data, err := r.r.Peek(n + 1)
+		switch err {
+		case nil:
+			n = r.r.Buffered()
+			continue
+		case io.EOF:
+		case bufio.ErrBufferFull:
+			if !bytes.Contains(data[start:], []byte(exceptionMarker)) {
+				return nil
+			}
+			return readBinaryException(r)
+		default:
+			return nil
+		}
+		if !bytes.Contains(data[start:], []byte(exceptionMarker)) {
+			return nil
+		}
+		// a string value which is the text of an exception follows its length
+		if bytes.HasSuffix(data[:start], binary.AppendUvarint(nil, uint64(len(data)-start))) {
+			return nil
+		}
+		return newError(string(data[start:]))
+	}
+}
+
+func notText(r rune) bool {
+	return r < ' ' && r != '\n' && r != '\r' && r != '\t'
+}
@@ -0,0 +1,456 @@
+package clickhouse
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the codes of the types in the binary encoding of the types of Dynamic values,
+// see https://clickhouse.com/docs/en/sql-reference/data-types/data-types-binary-encoding
+var binaryTypeCodes = map[string]byte{
+	"Nothing":  0x00,
+	"UInt8":    0x01,
+	"UInt16":   0x02,
+	"UInt32":   0x03,
+	"UInt64":   0x04,
+	"UInt128":  0x05,
+	"UInt256":  0x06,
+	"Int8":     0x07,
+	"Int16":    0x08,
+	"Int32":    0x09,
+	"Int64":    0x0a,
+	"Int128":   0x0b,
+	"Int256":   0x0c,
+	"Float32":  0x0d,
+	"Float64":  0x0e,
+	"Date":     0x0f,
+	"Date32":   0x10,
+	"String":   0x15,
+	"UUID":     0x1d,
+	"IPv4":     0x28,
+	"IPv6":     0x29,
+	"Bool":     0x2d,
+	"BFloat16": 0x31,
+}
+
+// binaryTypeNames are the names of the types by their codes
+var binaryTypeNames = func() map[byte]string {
+	names := make(map[byte]string, len(binaryTypeCodes))
+	for name, code := range binaryTypeCodes {
+		names[code] = name
+	}
+	return names
+}()
+
+const (
+	binaryTypeDateTime             = 0x11
+	binaryTypeDateTimeWithTZ       = 0x12
+	binaryTypeDateTime64           = 0x13
+	binaryTypeDateTime64WithTZ     = 0x14
+	binaryTypeFixedString          = 0x16
+	binaryTypeEnum8                = 0x17
+	binaryTypeEnum16               = 0x18
+	binaryTypeDecimal32            = 0x19
+	binaryTypeDecimal64            = 0x1a
+	binaryTypeDecimal128           = 0x1b
+	binaryTypeDecimal256           = 0x1c
+	binaryTypeArray                = 0x1e
+	binaryTypeTuple                = 0x1f
+	binaryTypeNamedTuple           = 0x20
+	binaryTypeNullable             = 0x23
+	binaryTypeLowCardinality       = 0x26
+	binaryTypeMap                  = 0x27
+	binaryTypeVariant              = 0x2a
+	binaryTypeDynamic              = 0x2b
+	binaryTypeCustom               = 0x2c
+	binaryTypeNested               = 0x2f
+	binaryTypeJSON                 = 0x30
+	defaultDynamicMaxTypes         = 32
+	defaultJSONMaxDynamicPaths     = 1024
+	defaultJSONMaxDynamicTypes     = 32
+	maxBinaryTypeElements          = 1 << 16
+	maxBinaryTypeNestingLevel      = 64
+	binaryJSONSerializationV1      = 0
+	binaryVariantNullDiscriminator = 255
+)
+
+// readBinaryType reads a type in the binary encoding
+func readBinaryType(r *binaryReader) (*TypeDesc, error) {
+	return readNestedBinaryType(r, 0)
+}
+
+func readNestedBinaryType(r *binaryReader, level int) (*TypeDesc, error) {
+	if level > maxBinaryTypeNestingLevel {
+		return nil, fmt.Errorf("too deeply nested type")
+	}
+	code, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := binaryTypeNames[code]; ok {
+		return &TypeDesc{Name: name}, nil
+	}
+	// readArgs reads n types of the arguments
+	readArgs := func(name string, n uint64, named bool) (*TypeDesc, error) {
+		if n > maxBinaryTypeElements {
+			return nil, fmt.Errorf("too many arguments of %s: %d", name, n)
+		}
+		desc := &TypeDesc{Name: name}
+		for i := uint64(0); i < n; i++ {
+			var elemName string
+			if named {
+				if elemName, err = r.readString(); err != nil {
+					return nil, err
+				}
+			}
+			arg, err := readNestedBinaryType(r, level+1)
+			if err != nil {
+				return nil, err
+			}
+			arg.ElemName = elemName
+			desc.Args = append(desc.Args, arg)
+		}
+		return desc, nil
+	}
+	switch code {
+	case binaryTypeDateTime:
+		return &TypeDesc{Name: "DateTime"}, nil
+	case binaryTypeDateTimeWithTZ:
+		tz, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		return &TypeDesc{Name: "DateTime", Args: []*TypeDesc{{Name: tz}}}, nil
+	case binaryTypeDateTime64, binaryTypeDateTime64WithTZ:
+		precision, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		desc := &TypeDesc{Name: "DateTime64", Args: []*TypeDesc{{Name: strconv.Itoa(int(precision))}}}
+		if code == binaryTypeDateTime64WithTZ {
+			tz, err := r.readString()
+			if err != nil {
+				return nil, err
+			}
+			desc.Args = append(desc.Args, &TypeDesc{Name: tz})
+		}
+		return desc, nil
+	case binaryTypeFixedString:
+		n, err := r.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		return &TypeDesc{Name: "FixedString", Args: []*TypeDesc{{Name: strconv.FormatUint(n, 10)}}}, nil
+	case binaryTypeEnum8, binaryTypeEnum16:
+		n, err := r.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > maxBinaryTypeElements {
+			return nil, fmt.Errorf("too many enum values: %d", n)
+		}
+		desc := &TypeDesc{Name: "Enum8", Enum: make(map[string]int16, n)}
+		size := 1
+		if code == binaryTypeEnum16 {
+			desc.Name, size = "Enum16", 2
+		}
+		for i := uint64(0); i < n; i++ {
+			name, err := r.readString()
+			if err != nil {
+				return nil, err
+			}
+			b, err := r.readFull(size)
+			if err != nil {
+				return nil, err
+			}
+			if size == 1 {
+				desc.Enum[name] = int16(int8(b[0]))
+			} else {
+				desc.Enum[name] = int16(binary.LittleEndian.Uint16(b))
+			}
+		}
+		return desc, nil
+	case binaryTypeDecimal32, binaryTypeDecimal64, binaryTypeDecimal128, binaryTypeDecimal256:
+		b, err := r.readFull(2)
+		if err != nil {
+			return nil, err
+		}
+		return &TypeDesc{Name: "Decimal", Args: []*TypeDesc{
+			{Name: strconv.Itoa(int(b[0]))},
+			{Name: strconv.Itoa(int(b[1]))},
+		}}, nil
+	case binaryTypeArray:
+		return readArgs("Array", 1, false)
+	case binaryTypeNullable:
+		return readArgs("Nullable", 1, false)
+	case binaryTypeLowCardinality:
+		return readArgs("LowCardinality", 1, false)
+	case binaryTypeMap:
+		return readArgs("Map", 2, false)
+	case binaryTypeTuple, binaryTypeNamedTuple, binaryTypeVariant, binaryTypeNested:
+		n, err := r.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		switch code {
+		case binaryTypeTuple:
+			return readArgs("Tuple", n, false)
+		case binaryTypeNamedTuple:
+			return readArgs("Tuple", n, true)
+		case binaryTypeVariant:
+			return readArgs("Variant", n, false)
+		}
+		return readArgs("Nested", n, true)
+	case binaryTypeDynamic:
+		maxTypes, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		return &TypeDesc{Name: "Dynamic", Args: []*TypeDesc{{Name: "max_types=" + strconv.Itoa(int(maxTypes))}}}, nil
+	case binaryTypeCustom:
+		// the custom types like Point and Ring are referred to by their names
+		name, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		return &TypeDesc{Name: name}, nil
+	case binaryTypeJSON:
+		return readBinaryJSONType(r, level)
+	}
+	return nil, fmt.Errorf("unsupported binary type 0x%02x", code)
+}
+
+// readBinaryJSONType reads the arguments of a JSON type in the binary encoding
+func readBinaryJSONType(r *binaryReader, level int) (*TypeDesc, error) {
+	b, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	if b != binaryJSONSerializationV1 {
+		return nil, fmt.Errorf("unsupported serialization version %d of JSON type", b)
+	}
+	maxPaths, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	maxTypes, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	desc := &TypeDesc{Name: "JSON", Args: []*TypeDesc{
+		{Name: "max_dynamic_paths=" + strconv.FormatUint(maxPaths, 10)},
+		{Name: "max_dynamic_types=" + strconv.Itoa(int(maxTypes))},
+	}}
+	n, err := r.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > maxBinaryTypeElements {
+		return nil, fmt.Errorf("too many typed paths of JSON: %d", n)
+	}
+	for i := uint64(0); i < n; i++ {
+		path, err := r.readString()
+		if err != nil {
+			return nil, err
+		}
+		arg, err := readNestedBinaryType(r, level+1)
+		if err != nil {
+			return nil, err
+		}
+		arg.ElemName = path
+		desc.Args = append(desc.Args, arg)
+	}
+	for _, skip := range []string{"SKIP", "SKIP REGEXP"} {
+		n, err := r.readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > maxBinaryTypeElements {
+			return nil, fmt.Errorf("too many skipped paths of JSON: %d", n)
+		}
+		for i := uint64(0); i < n; i++ {
+			path, err := r.readString()
+			if err != nil {
+				return nil, err
+			}
+			desc.Args = append(desc.Args, &TypeDesc{Name: skip, Args: []*TypeDesc{{Name: path}}})
+		}
+	}
+	return desc, nil
+}
+
+// appendBinaryType appends the binary encoding of the type to b
+func appendBinaryType(b []byte, t *TypeDesc) ([]byte, error) {
+	if code, ok := binaryTypeCodes[t.Name]; ok && len(t.Args) == 0 {
+		return append(b, code), nil
+	}
+	var err error
+	// appendArgs appends the types of the arguments
+	appendArgs := func(b []byte, named bool) ([]byte, error) {
+		for _, arg := range t.Args {
+			if named {
+				b = appendBinaryString(b, arg.ElemName)
+			}
+			if b, err = appendBinaryType(b, arg); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	switch t.Name {
+	case "DateTime":
+		switch len(t.Args) {
+		case 0:
+			return append(b, binaryTypeDateTime), nil
+		case 1:
+			return appendBinaryString(append(b, binaryTypeDateTimeWithTZ), t.Args[0].Name), nil
+		}
+	case "DateTime64":
+		if len(t.Args) < 1 || len(t.Args) > 2 {
+			break
+		}
+		precision, err := strconv.ParseUint(t.Args[0].Name, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("malformed precision of DateTime64: %v", err)
+		}
+		if len(t.Args) == 1 {
+			return append(b, binaryTypeDateTime64, byte(precision)), nil
+		}
+		return appendBinaryString(append(b, binaryTypeDateTime64WithTZ, byte(precision)), t.Args[1].Name), nil
+	case "FixedString":
+		if len(t.Args) != 1 {
+			break
+		}
+		n, err := strconv.ParseUint(t.Args[0].Name, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed length of FixedString: %v", err)
+		}
+		return binary.AppendUvarint(append(b, binaryTypeFixedString), n), nil
+	case "Enum8", "Enum16":
+		code := byte(binaryTypeEnum8)
+		if t.Name == "Enum16" {
+			code = binaryTypeEnum16
+		}
+		names := make([]string, 0, len(t.Enum))
+		for name := range t.Enum {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return t.Enum[names[i]] < t.Enum[names[j]] })
+		b = binary.AppendUvarint(append(b, code), uint64(len(names)))
+		for _, name := range names {
+			b = appendBinaryString(b, name)
+			if code == binaryTypeEnum8 {
+				b = append(b, byte(t.Enum[name]))
+			} else {
+				b = binary.LittleEndian.AppendUint16(b, uint16(t.Enum[name]))
+			}
+		}
+		return b, nil
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		precision, scale, err := decimalArgs(t)
+		if err != nil {
+			return nil, err
+		}
+		var code byte
+		switch decimalSize(precision) {
+		case 4:
+			code = binaryTypeDecimal32
+		case 8:
+			code = binaryTypeDecimal64
+		case 16:
+			code = binaryTypeDecimal128
+		default:
+			code = binaryTypeDecimal256
+		}
+		return append(b, code, byte(precision), byte(scale)), nil
+	case "Array", "Nullable", "LowCardinality":
+		if len(t.Args) != 1 {
+			break
+		}
+		code := map[string]byte{"Array": binaryTypeArray, "Nullable": binaryTypeNullable, "LowCardinality": binaryTypeLowCardinality}[t.Name]
+		return appendArgs(append(b, code), false)
+	case "Map":
+		if len(t.Args) != 2 {
+			break
+		}
+		return appendArgs(append(b, binaryTypeMap), false)
+	case "Tuple", "Variant", "Nested":
+		code := map[string]byte{"Tuple": binaryTypeTuple, "Variant": binaryTypeVariant, "Nested": binaryTypeNested}[t.Name]
+		named := t.Name == "Nested"
+		if t.Name == "Tuple" && elemNames(t) != nil {
+			code, named = binaryTypeNamedTuple, true
+		}
+		return appendArgs(binary.AppendUvarint(append(b, code), uint64(len(t.Args))), named)
+	case "Dynamic":
+		maxTypes, err := typeSetting(t, "max_types", defaultDynamicMaxTypes)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, binaryTypeDynamic, byte(maxTypes)), nil
+	case "JSON":
+		return appendBinaryJSONType(b, t)
+	case "Point", "Ring", "Polygon", "MultiPolygon":
+		return appendBinaryString(append(b, binaryTypeCustom), t.Name), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// appendBinaryJSONType appends the binary encoding of a JSON type to b
+func appendBinaryJSONType(b []byte, t *TypeDesc) ([]byte, error) {
+	maxPaths, err := typeSetting(t, "max_dynamic_paths", defaultJSONMaxDynamicPaths)
+	if err != nil {
+		return nil, err
+	}
+	maxTypes, err := typeSetting(t, "max_dynamic_types", defaultJSONMaxDynamicTypes)
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, binaryTypeJSON, binaryJSONSerializationV1)
+	b = append(binary.AppendUvarint(b, uint64(maxPaths)), byte(maxTypes))
+	var typed, skipped, regexps []*TypeDesc
+	for _, arg := range t.Args {
+		switch {
+		case arg.ElemName != "":
+			typed = append(typed, arg)
+		case arg.Name == "SKIP":
+			skipped = append(skipped, arg)
+		case arg.Name == "SKIP REGEXP":
+			regexps = append(regexps, arg)
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(len(typed)))
+	for _, arg := range typed {
+		if b, err = appendBinaryType(appendBinaryString(b, arg.ElemName), arg); err != nil {
+			return nil, err
+		}
+	}
+	for _, args := range [][]*TypeDesc{skipped, regexps} {
+		b = binary.AppendUvarint(b, uint64(len(args)))
+		for _, arg := range args {
+			b = appendBinaryString(b, arg.Args[0].Name)
+		}
+	}
+	return b, nil
+}
+
+// typeSetting returns the value of the setting like max_types=10 in the arguments of the type
+func typeSetting(t *TypeDesc, name string, defaultValue int) (int, error) {
+	for _, arg := range t.Args {
+		if value, ok := strings.CutPrefix(arg.Name, name+"="); ok && arg.ElemName == "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, fmt.Errorf("malformed %s of %s: %v", name, t.Name, err)
+			}
+			return n, nil
+		}
+	}
+	return defaultValue, nil
+}
+
+// appendBinaryString appends a string with its length to b
+func appendBinaryString(b []byte, s string) []byte {
+	return append(binary.AppendUvarint(b, uint64(len(s))), s...)
+}
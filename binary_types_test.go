@@ -0,0 +1,58 @@
+package clickhouse
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinaryType(t *testing.T) {
+	testCases := []struct {
+		typ    string
+		binary []byte
+	}{
+		{"UInt64", []byte{0x04}},
+		{"String", []byte{0x15}},
+		{"FixedString(300)", []byte{0x16, 0xac, 0x02}},
+		{"DateTime('UTC')", []byte{0x12, 3, 'U', 'T', 'C'}},
+		{"DateTime64(3)", []byte{0x13, 3}},
+		{"Decimal(9, 2)", []byte{0x19, 9, 2}},
+		{"Enum8('b' = 2, 'a' = 1)", []byte{0x17, 2, 1, 'a', 1, 1, 'b', 2}},
+		{"Array(Nullable(Int8))", []byte{0x1e, 0x23, 0x07}},
+		{"Tuple(UInt8, String)", []byte{0x1f, 2, 0x01, 0x15}},
+		{"Tuple(a UInt8)", []byte{0x20, 1, 1, 'a', 0x01}},
+		{"Map(String, LowCardinality(String))", []byte{0x27, 0x15, 0x26, 0x15}},
+		{"Variant(String, UInt64)", []byte{0x2a, 2, 0x15, 0x04}},
+		{"Dynamic(max_types=8)", []byte{0x2b, 8}},
+		{"Ring", []byte{0x2c, 4, 'R', 'i', 'n', 'g'}},
+		{
+			"JSON(max_dynamic_paths=1024, max_dynamic_types=32, a UInt8, SKIP b)",
+			[]byte{0x30, 0, 0x80, 0x08, 32, 1, 1, 'a', 0x01, 1, 1, 'b', 0},
+		},
+	}
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		b, err := appendBinaryType(nil, desc)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		assert.Equal(t, tc.binary, b, tc.typ)
+
+		decoded, err := readBinaryType(newBinaryReader(bytes.NewReader(b)))
+		if assert.NoError(t, err, tc.typ) {
+			assert.Equal(t, desc.String(), decoded.String(), tc.typ)
+		}
+	}
+
+	for _, typ := range []string{"AggregateFunction(uniq, String)", "Decimal(100, 2)"} {
+		desc, err := ParseTypeDesc(typ)
+		if assert.NoError(t, err, typ) {
+			_, err = appendBinaryType(nil, desc)
+			assert.Error(t, err, typ)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package clickhouse
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// bufferedBody is a response body which has been completely read
+// before returning any row, either into memory or into a temp file
+type bufferedBody struct {
+	io.ReadSeeker
+	file *os.File
+}
+
+// Close implements io.Closer, it removes the temp file
+func (b *bufferedBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	file := b.file
+	b.file = nil
+	err := file.Close()
+	if rmErr := os.Remove(file.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// bufferBody reads the whole body. The data exceeding maxMemory bytes
+// is spilled to a temp file, maxMemory <= 0 keeps everything in memory.
+func bufferBody(body io.Reader, maxMemory int64) (*bufferedBody, error) {
+	var buf bytes.Buffer
+	if maxMemory <= 0 {
+		if _, err := buf.ReadFrom(body); err != nil {
+			return nil, fmt.Errorf("bufferBody: failed to read the response: %w", err)
+		}
+		return &bufferedBody{ReadSeeker: bytes.NewReader(buf.Bytes())}, nil
+	}
+
+	n, err := buf.ReadFrom(io.LimitReader(body, maxMemory+1))
+	if err != nil {
+		return nil, fmt.Errorf("bufferBody: failed to read the response: %w", err)
+	}
+	if n <= maxMemory {
+		return &bufferedBody{ReadSeeker: bytes.NewReader(buf.Bytes())}, nil
+	}
+
+	file, err := os.CreateTemp("", "clickhouse-response-*")
+	if err != nil {
+		return nil, fmt.Errorf("bufferBody: failed to create a temp file: %w", err)
+	}
+	b := &bufferedBody{ReadSeeker: file, file: file}
+	if _, err = io.Copy(file, io.MultiReader(&buf, body)); err != nil {
+		_ = b.Close()
+		return nil, fmt.Errorf("bufferBody: failed to write the response into a temp file: %w", err)
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		_ = b.Close()
+		return nil, fmt.Errorf("bufferBody: failed to rewind the temp file: %w", err)
+	}
+	return b, nil
+}
@@ -21,3 +21,18 @@ func (d *Driver) Open(dsn string) (driver.Conn, error) {
 	}
 	return newConn(cfg), nil
 }
+
+// OpenConnector implements driver.DriverContext, so all connections
+// of sql.DB share the same transport
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	cfg, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.driver = d
+	return c, nil
+}
@@ -158,3 +158,11 @@ func parseDate(s string) time.Time {
 func parseDateTime(s string) time.Time {
 	return parseTime(timeFormat, s)
 }
+
+func parseDecimalValue(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -5,15 +5,53 @@ import (
 	"net"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// HostStrategy defines the order in which the hosts of a multi-host DSN are tried
+type HostStrategy string
+
+const (
+	// HostStrategyInOrder always tries the hosts in the order they are listed in the DSN
+	HostStrategyInOrder HostStrategy = "in_order"
+	// HostStrategyRoundRobin starts every request from the next host in the list
+	HostStrategyRoundRobin HostStrategy = "round_robin"
+	// HostStrategyRandom starts every request from a randomly chosen host
+	HostStrategyRandom HostStrategy = "random"
+)
+
+// ResultFormat defines the format in which the results of queries are received
+type ResultFormat string
+
+const (
+	// ResultFormatTSV receives the results in TabSeparatedWithNamesAndTypes format
+	ResultFormatTSV ResultFormat = "tsv"
+	// ResultFormatRowBinary receives the results in RowBinaryWithNamesAndTypes format,
+	// which is much faster to decode
+	ResultFormatRowBinary ResultFormat = "rowbinary"
+)
+
+// InsertFormat defines the format in which the rows of batch INSERTs are sent
+type InsertFormat string
+
+const (
+	// InsertFormatValues sends the rows as the text of VALUES
+	InsertFormatValues InsertFormat = "values"
+	// InsertFormatRowBinary sends the rows in RowBinary format, which is encoded
+	// by the types of the columns fetched with DESCRIBE TABLE
+	InsertFormatRowBinary InsertFormat = "rowbinary"
+)
+
 // Config is a configuration parsed from a DSN string
 type Config struct {
 	User             string
 	Password         string
 	Scheme           string
 	Host             string
+	Hosts            []string // all hosts of a multi-host DSN, Host is the first one
+	HostStrategy     HostStrategy
+	HostCooldown     time.Duration // how long a failed host is skipped
 	Database         string
 	Timeout          time.Duration
 	IdleTimeout      time.Duration
@@ -27,6 +65,22 @@ type Config struct {
 	TLSConfig        string
 	KillQueryOnErr   bool // kill query on the server side if we have error from transport
 	KillQueryTimeout time.Duration
+	RetryPolicy      RetryPolicy
+	WaitEndOfQuery   bool  // read the whole response before returning any row
+	BufferMaxMemory  int64 // the part of the response exceeding it is kept in a temp file, 0 means no limit
+	Format           ResultFormat
+	InsertFormat     InsertFormat // the format of the rows of batch INSERTs, it doesn't depend on Format
+	BatchMaxRows     int          // a batch INSERT is sent in a new request after this number of rows, 0 means no limit
+	BatchMaxBytes    int64        // a batch INSERT is sent in a new request after this size of rows, 0 means no limit
+	AsyncInsert      bool         // INSERT statements are executed with async_insert
+	// AsyncInsertNoWait makes async INSERTs return without waiting for the data to be written (wait_for_async_insert=0)
+	AsyncInsertNoWait bool
+	// UUIDAndIPAsString makes UUID, IPv4 and IPv6 values scanned as strings instead of uuid.UUID and netip.Addr
+	UUIDAndIPAsString bool
+	// JSONAsRaw makes the values of JSON columns scanned as json.RawMessage instead of map[string]interface{}
+	JSONAsRaw bool
+	// DecimalAsString makes the values of Decimal columns scanned as strings instead of Decimal
+	DecimalAsString bool
 }
 
 // NewConfig creates a new config with default values
@@ -40,6 +94,17 @@ func NewConfig() *Config {
 	}
 }
 
+func (cfg *Config) clone() *Config {
+	c := *cfg
+	c.Hosts = append([]string(nil), cfg.Hosts...)
+	c.RetryPolicy.RetryableCodes = append([]int(nil), cfg.RetryPolicy.RetryableCodes...)
+	c.Params = make(map[string]string, len(cfg.Params))
+	for k, v := range cfg.Params {
+		c.Params[k] = v
+	}
+	return &c
+}
+
 // FormatDSN formats the given Config into a DSN string which can be passed to
 // the driver.
 func (cfg *Config) FormatDSN() string {
@@ -75,17 +140,78 @@ func (cfg *Config) FormatDSN() string {
 	if cfg.TLSConfig != "" {
 		query.Set("tls_config", cfg.TLSConfig)
 	}
+	if cfg.HostStrategy != "" {
+		query.Set("host_strategy", string(cfg.HostStrategy))
+	}
+	if cfg.HostCooldown != 0 {
+		query.Set("host_cooldown", cfg.HostCooldown.String())
+	}
+	if cfg.RetryPolicy.MaxRetries != 0 {
+		query.Set("max_retries", strconv.Itoa(cfg.RetryPolicy.MaxRetries))
+	}
+	if cfg.RetryPolicy.Backoff != 0 {
+		query.Set("retry_backoff", cfg.RetryPolicy.Backoff.String())
+	}
+	if cfg.WaitEndOfQuery {
+		query.Set("wait_end_of_query", "1")
+	}
+	if cfg.BufferMaxMemory != 0 {
+		query.Set("buffer_max_memory", strconv.FormatInt(cfg.BufferMaxMemory, 10))
+	}
+	if cfg.Format != "" {
+		query.Set("format", string(cfg.Format))
+	}
+	if cfg.InsertFormat != "" {
+		query.Set("insert_format", string(cfg.InsertFormat))
+	}
+	if cfg.BatchMaxRows != 0 {
+		query.Set("batch_max_rows", strconv.Itoa(cfg.BatchMaxRows))
+	}
+	if cfg.BatchMaxBytes != 0 {
+		query.Set("batch_max_bytes", strconv.FormatInt(cfg.BatchMaxBytes, 10))
+	}
+	if cfg.AsyncInsert {
+		query.Set("async_insert", "1")
+	}
+	if cfg.AsyncInsertNoWait {
+		query.Set("wait_for_async_insert", "0")
+	}
+	if cfg.UUIDAndIPAsString {
+		query.Set("uuid_ip_as_string", "1")
+	}
+	if cfg.JSONAsRaw {
+		query.Set("json_as_raw", "1")
+	}
+	if cfg.DecimalAsString {
+		query.Set("decimal_as_string", "1")
+	}
 
 	u.RawQuery = query.Encode()
 	return u.String()
 }
 
+// hosts returns the list of hosts with ports
+func (cfg *Config) hosts() []string {
+	if len(cfg.Hosts) == 0 {
+		return []string{ensureHavePort(cfg.Host)}
+	}
+	hosts := make([]string, len(cfg.Hosts))
+	for i, host := range cfg.Hosts {
+		hosts[i] = ensureHavePort(host)
+	}
+	return hosts
+}
+
 func (cfg *Config) url(extra map[string]string, dsn bool) *url.URL {
+	hosts := cfg.hosts()
 	u := &url.URL{
-		Host:   ensureHavePort(cfg.Host),
+		Host:   hosts[0],
 		Scheme: cfg.Scheme,
 		Path:   "/",
 	}
+	if dsn {
+		u.Host = strings.Join(hosts, ",")
+	}
 	if len(cfg.User) > 0 {
 		if len(cfg.Password) > 0 {
 			u.User = url.UserPassword(cfg.User, cfg.Password)
@@ -114,6 +240,7 @@ func (cfg *Config) url(extra map[string]string, dsn bool) *url.URL {
 
 // ParseDSN parses the DSN string to a Config
 func ParseDSN(dsn string) (*Config, error) {
+	dsn, hosts := splitDSNHosts(dsn)
 	u, err := url.Parse(dsn)
 	if err != nil {
 		return nil, err
@@ -126,6 +253,14 @@ func ParseDSN(dsn string) (*Config, error) {
 	cfg := NewConfig()
 
 	cfg.Scheme, cfg.Host = u.Scheme, u.Host
+	if len(hosts) > 1 {
+		for _, host := range hosts {
+			if host == "" {
+				return nil, fmt.Errorf("invalid host")
+			}
+		}
+		cfg.Hosts = hosts
+	}
 	if len(u.Path) > 1 {
 		// skip '/'
 		cfg.Database = u.Path[1:]
@@ -175,6 +310,38 @@ func parseDSNParams(cfg *Config, params map[string][]string) (err error) {
 			cfg.KillQueryOnErr, err = strconv.ParseBool(v[0])
 		case "kill_query_timeout":
 			cfg.KillQueryTimeout, err = time.ParseDuration(v[0])
+		case "host_strategy":
+			cfg.HostStrategy, err = parseHostStrategy(v[0])
+		case "host_cooldown":
+			cfg.HostCooldown, err = time.ParseDuration(v[0])
+		case "max_retries":
+			cfg.RetryPolicy.MaxRetries, err = strconv.Atoi(v[0])
+		case "retry_backoff":
+			cfg.RetryPolicy.Backoff, err = time.ParseDuration(v[0])
+		case "wait_end_of_query":
+			cfg.WaitEndOfQuery, err = strconv.ParseBool(v[0])
+		case "buffer_max_memory":
+			cfg.BufferMaxMemory, err = strconv.ParseInt(v[0], 10, 64)
+		case "format":
+			cfg.Format, err = parseResultFormat(v[0])
+		case "insert_format":
+			cfg.InsertFormat, err = parseInsertFormat(v[0])
+		case "batch_max_rows":
+			cfg.BatchMaxRows, err = strconv.Atoi(v[0])
+		case "batch_max_bytes":
+			cfg.BatchMaxBytes, err = strconv.ParseInt(v[0], 10, 64)
+		case "async_insert":
+			cfg.AsyncInsert, err = strconv.ParseBool(v[0])
+		case "wait_for_async_insert":
+			var wait bool
+			wait, err = strconv.ParseBool(v[0])
+			cfg.AsyncInsertNoWait = !wait
+		case "uuid_ip_as_string":
+			cfg.UUIDAndIPAsString, err = strconv.ParseBool(v[0])
+		case "json_as_raw":
+			cfg.JSONAsRaw, err = strconv.ParseBool(v[0])
+		case "decimal_as_string":
+			cfg.DecimalAsString, err = strconv.ParseBool(v[0])
 		default:
 			cfg.Params[k] = v[0]
 		}
@@ -186,6 +353,52 @@ func parseDSNParams(cfg *Config, params map[string][]string) (err error) {
 	return
 }
 
+func parseHostStrategy(s string) (HostStrategy, error) {
+	switch strategy := HostStrategy(s); strategy {
+	case HostStrategyInOrder, HostStrategyRoundRobin, HostStrategyRandom:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown host strategy '%s'", s)
+}
+
+func parseResultFormat(s string) (ResultFormat, error) {
+	switch format := ResultFormat(s); format {
+	case ResultFormatTSV, ResultFormatRowBinary:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown format '%s'", s)
+}
+
+func parseInsertFormat(s string) (InsertFormat, error) {
+	switch format := InsertFormat(s); format {
+	case InsertFormatValues, InsertFormatRowBinary:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown insert format '%s'", s)
+}
+
+// splitDSNHosts extracts the comma separated list of hosts from the DSN,
+// since url.Parse does not accept it. The DSN is returned with the first host only.
+func splitDSNHosts(dsn string) (string, []string) {
+	start := strings.Index(dsn, "://")
+	if start < 0 {
+		return dsn, nil
+	}
+	start += len("://")
+	end := len(dsn)
+	if i := strings.IndexAny(dsn[start:], "/?#"); i >= 0 {
+		end = start + i
+	}
+	if i := strings.LastIndex(dsn[start:end], "@"); i >= 0 {
+		start += i + 1
+	}
+	if !strings.Contains(dsn[start:end], ",") {
+		return dsn, nil
+	}
+	hosts := strings.Split(dsn[start:end], ",")
+	return dsn[:start] + hosts[0] + dsn[end:], hosts
+}
+
 func ensureHavePort(addr string) string {
 	if _, _, err := net.SplitHostPort(addr); err != nil {
 		// we get the missing port error here
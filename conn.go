@@ -36,6 +36,8 @@ const (
 
 	quotaKeyParamName = "quota_key"
 	queryIDParamName  = "query_id"
+
+	timezoneHeader = "X-ClickHouse-Timezone"
 )
 
 // errors
@@ -45,6 +47,24 @@ var (
 
 var defaultKillQueryTimeout = time.Duration(time.Second)
 
+// Conn provides the features of the driver which are beyond database/sql.
+// It is implemented by the driver connections available through sql.Conn.Raw:
+//
+//	err := sqlConn.Raw(func(driverConn interface{}) error {
+//		batch, err := driverConn.(clickhouse.Conn).PrepareBatch(ctx, "INSERT INTO t (a, b)")
+//		...
+//	})
+type Conn interface {
+	// PrepareBatch creates a Batch for the query INSERT INTO table [(columns)]
+	PrepareBatch(ctx context.Context, query string) (*Batch, error)
+	// InsertFrom executes the query INSERT INTO table [(columns)] FORMAT format
+	// with the data in this format read from r, for example a CSV file
+	InsertFrom(ctx context.Context, query string, r io.Reader) (driver.Result, error)
+	// QueryTo executes the query and copies the result in the format to w,
+	// the arguments may be sql.NamedArg for {name:Type} query parameters
+	QueryTo(ctx context.Context, w io.Writer, query, format string, args ...interface{}) error
+}
+
 // conn implements an interface sql.Conn
 type conn struct {
 	url                *url.URL
@@ -52,40 +72,73 @@ type conn struct {
 	location           *time.Location
 	useDBLocation      bool
 	useGzipCompression bool
-	transport          *http.Transport
+	transport          http.RoundTripper
+	ownTransport       bool // transport is not shared with other connections
+	hosts              *hostPool
 	cancel             context.CancelFunc
 	txCtx              context.Context
 	stmts              []*stmt
+	batches            map[*Batch]struct{} // the batches which are neither sent nor aborted
 	logger             *log.Logger
 	closed             int32
 	killQueryOnErr     bool
 	killQueryTimeout   time.Duration
+	retryPolicy        RetryPolicy
+	waitEndOfQuery     bool
+	bufferMaxMemory    int64
+	rowBinary          bool
+	rowBinaryInsert    bool
+	batchMaxRows       int
+	batchMaxBytes      int64
+	asyncInsert        bool
+	asyncInsertWait    bool
+	uuidAndIPAsString  bool
+	jsonAsRaw          bool
+	decimalAsString    bool
 }
 
 func newConn(cfg *Config) *conn {
+	c := newConnWithTransport(cfg, newTransport(cfg, 1), newHostPool(cfg.hosts(), cfg.HostStrategy, cfg.HostCooldown))
+	c.ownTransport = true
+	return c
+}
+
+// newConnWithTransport creates a connection which uses the given transport and hosts,
+// so they could be shared by all connections of a Connector
+func newConnWithTransport(cfg *Config, transport http.RoundTripper, hosts *hostPool) *conn {
 	var logger *log.Logger
 	if cfg.Debug {
 		logger = log.New(os.Stderr, "clickhouse: ", log.LstdFlags)
 	}
+	params := map[string]string{"default_format": "TabSeparatedWithNamesAndTypes"}
+	if cfg.Format == ResultFormatRowBinary {
+		params["default_format"] = "RowBinaryWithNamesAndTypes"
+	}
+	if cfg.WaitEndOfQuery {
+		params["wait_end_of_query"] = "1"
+	}
 	c := &conn{
-		url:                cfg.url(map[string]string{"default_format": "TabSeparatedWithNamesAndTypes"}, false),
+		url:                cfg.url(params, false),
 		location:           cfg.Location,
 		useDBLocation:      cfg.UseDBLocation,
 		useGzipCompression: cfg.GzipCompression,
 		killQueryOnErr:     cfg.KillQueryOnErr,
 		killQueryTimeout:   cfg.KillQueryTimeout,
-		transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   cfg.Timeout,
-				KeepAlive: cfg.IdleTimeout,
-				DualStack: true,
-			}).DialContext,
-			MaxIdleConns:          1,
-			IdleConnTimeout:       cfg.IdleTimeout,
-			ResponseHeaderTimeout: cfg.ReadTimeout,
-			TLSClientConfig:       getTLSConfigClone(cfg.TLSConfig),
-		},
-		logger: logger,
+		retryPolicy:        cfg.RetryPolicy,
+		waitEndOfQuery:     cfg.WaitEndOfQuery,
+		bufferMaxMemory:    cfg.BufferMaxMemory,
+		rowBinary:          cfg.Format == ResultFormatRowBinary,
+		rowBinaryInsert:    cfg.InsertFormat == InsertFormatRowBinary,
+		batchMaxRows:       cfg.BatchMaxRows,
+		batchMaxBytes:      cfg.BatchMaxBytes,
+		asyncInsert:        cfg.AsyncInsert,
+		asyncInsertWait:    !cfg.AsyncInsertNoWait,
+		uuidAndIPAsString:  cfg.UUIDAndIPAsString,
+		jsonAsRaw:          cfg.JSONAsRaw,
+		decimalAsString:    cfg.DecimalAsString,
+		transport:          transport,
+		hosts:              hosts,
+		logger:             logger,
 	}
 	// store userinfo in separate member, we will handle it manually
 	c.user = c.url.User
@@ -94,6 +147,21 @@ func newConn(cfg *Config) *conn {
 	return c
 }
 
+func newTransport(cfg *Config, maxIdleConns int) *progressTransport {
+	return &progressTransport{Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   cfg.Timeout,
+			KeepAlive: cfg.IdleTimeout,
+			DualStack: true,
+		}).DialContext,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConns,
+		IdleConnTimeout:       cfg.IdleTimeout,
+		ResponseHeaderTimeout: cfg.ReadTimeout,
+		TLSClientConfig:       getTLSConfigClone(cfg.TLSConfig),
+	}}
+}
+
 func (c *conn) log(msg ...interface{}) {
 	if c.logger != nil {
 		c.logger.Println(msg...)
@@ -116,11 +184,19 @@ func (c *conn) Close() error {
 		c.transport = nil
 		c.cancel = nil
 
+		for _, stmt := range c.stmts {
+			stmt.abort()
+		}
+		// the requests of the batches would wait for the rest of the rows forever
+		for batch := range c.batches {
+			batch.Abort()
+		}
+
 		if cancel != nil {
 			cancel()
 		}
-		if transport != nil {
-			transport.CloseIdleConnections()
+		if t, ok := transport.(interface{ CloseIdleConnections() }); ok && c.ownTransport {
+			t.CloseIdleConnections()
 		}
 	}
 	return nil
@@ -139,7 +215,6 @@ func (c *conn) Commit() (err error) {
 	if c.txCtx == nil {
 		return sql.ErrTxDone
 	}
-	ctx := c.txCtx
 	stmts := c.stmts
 	c.txCtx = nil
 	c.stmts = stmts[:0]
@@ -147,9 +222,13 @@ func (c *conn) Commit() (err error) {
 	if len(stmts) == 0 {
 		return nil
 	}
-	for _, stmt := range stmts {
+	for i, stmt := range stmts {
 		c.log("commit statement: ", stmt.prefix, stmt.pattern)
-		if err = stmt.commit(ctx); err != nil {
+		if err = stmt.commit(); err != nil {
+			// the requests of the rest statements must not be left hanging
+			for _, rest := range stmts[i+1:] {
+				rest.abort()
+			}
 			break
 		}
 	}
@@ -172,6 +251,9 @@ func (c *conn) Rollback() error {
 		// there is no statements, so nothing to rollback
 		return sql.ErrTxDone
 	}
+	for _, stmt := range stmts {
+		stmt.abort()
+	}
 	// the statements will be closed by sql.Tx
 	return nil
 }
@@ -213,7 +295,7 @@ func (c *conn) killQuery(req *http.Request) error {
 	if err != nil {
 		return err
 	}
-	body, err := c.doRequest(ctx, req)
+	body, _, err := c.doRequest(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -231,7 +313,7 @@ func (c *conn) query(ctx context.Context, query string, args []driver.Value) (dr
 	if err != nil {
 		return nil, err
 	}
-	body, err := c.doRequest(ctx, req)
+	body, header, err := c.doRequest(ctx, req)
 	if err != nil {
 		if _, ok := err.(*Error); !ok && err != driver.ErrBadConn {
 			killErr := c.killQuery(req)
@@ -241,9 +323,86 @@ func (c *conn) query(ctx context.Context, query string, args []driver.Value) (dr
 		}
 		return nil, err
 	}
-	// Not closing body now - it will be closed when rows are closed.
+	var rows queryRows
+	if c.waitEndOfQuery {
+		rows, err = c.bufferedRows(body, header)
+	} else {
+		// Not closing body now - it will be closed when rows are closed.
+		if rows, err = c.newRows(body, header); err != nil {
+			c.cancel = nil
+			_ = body.Close()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows.setStats(header)
+	return rows, nil
+}
 
-	return newTextRows(c, body, c.location, c.useDBLocation)
+// newRows reads the result in the format requested by the connection,
+// or in the format of an explicit FORMAT clause reported by the server
+func (c *conn) newRows(body io.ReadCloser, header http.Header) (queryRows, error) {
+	rowBinary := c.rowBinary
+	switch format := header.Get(formatHeader); format {
+	case "":
+	case "TabSeparatedWithNamesAndTypes", "TSVWithNamesAndTypes":
+		rowBinary = false
+	case "RowBinaryWithNamesAndTypes":
+		rowBinary = true
+	default:
+		return nil, fmt.Errorf("clickhouse: the result in %s format can't be read as rows, use QueryTo to copy it", format)
+	}
+	if rowBinary {
+		rows, err := newBinaryRows(c, body, c.location, c.useDBLocation, serverLocation(header))
+		if err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+	rows, err := newTextRows(c, body, c.location, c.useDBLocation)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// bufferedRows reads the whole response and checks it before returning any row
+func (c *conn) bufferedRows(body io.ReadCloser, header http.Header) (queryRows, error) {
+	buffered, err := bufferBody(body, c.bufferMaxMemory)
+	_ = body.Close()
+	c.cancel = nil
+	if err != nil {
+		return nil, err
+	}
+	rows, err := c.newRows(buffered, header)
+	if err == nil {
+		err = rows.validate()
+	}
+	if err == nil {
+		_, err = buffered.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		rows, err = c.newRows(buffered, header)
+	}
+	if err != nil {
+		_ = buffered.Close()
+		return nil, err
+	}
+	return rows, nil
+}
+
+// serverLocation returns the time zone of the server sent in X-ClickHouse-Timezone header
+func serverLocation(header http.Header) *time.Location {
+	tz := header.Get(timezoneHeader)
+	if tz == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil
+	}
+	return loc
 }
 
 func (c *conn) exec(ctx context.Context, query string, args []driver.Value) (driver.Result, error) {
@@ -254,13 +413,28 @@ func (c *conn) exec(ctx context.Context, query string, args []driver.Value) (dri
 	if err != nil {
 		return nil, err
 	}
-	body, err := c.doRequest(ctx, req)
+	body, header, err := c.doRequest(ctx, req)
 	if body != nil {
 		defer body.Close()
 		// Drain body to enable connection reuse
 		_, _ = io.Copy(io.Discard, body)
 	}
-	return emptyResult, err
+	if err != nil {
+		return emptyResult, err
+	}
+	return newExecResult(query, req.URL.Query(), header), nil
+}
+
+// newExecResult creates the result of the query from the parameters
+// of its request and the response headers
+func newExecResult(query string, params url.Values, header http.Header) driver.Result {
+	if async, ok := sentAsyncInsert(query, params); ok {
+		return &asyncInsertResult{
+			Result: newResult(query, header),
+			status: AsyncInsertStatus{QueryID: header.Get(queryIDHeader), Flushed: async.wait},
+		}
+	}
+	return newResult(query, header)
 }
 
 type cancellingReadCloser struct {
@@ -273,7 +447,7 @@ func (crc cancellingReadCloser) Close() error {
 	return crc.ReadCloser.Close()
 }
 
-func (c *conn) doRequest(ctx context.Context, req *http.Request) (body io.ReadCloser, err error) {
+func (c *conn) doRequest(ctx context.Context, req *http.Request) (body io.ReadCloser, header http.Header, err error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer func() {
 		if err != nil {
@@ -288,33 +462,118 @@ func (c *conn) doRequest(ctx context.Context, req *http.Request) (body io.ReadCl
 	c.cancel = cancel
 
 	if transport == nil {
-		return nil, driver.ErrBadConn
+		return nil, nil, driver.ErrBadConn
 	}
 
-	req = req.WithContext(ctx)
-	resp, err := transport.RoundTrip(req)
+	var progress *progressWatcher
+	if f := progressFromContext(ctx); f != nil {
+		progress = &progressWatcher{f: f}
+		ctx = progress.withTrace(ctx)
+		if t, ok := transport.(*progressTransport); ok {
+			transport = t.withProgress()
+		}
+	}
+
+	safe := retrySafeFromRequest(req)
+	for retry := 0; ; retry++ {
+		body, header, err = c.sendRequest(ctx, transport, req, safe)
+		if err == nil && progress != nil {
+			progress.reportHeader(header)
+		}
+		if err == nil || retry >= c.retryPolicy.MaxRetries || req.GetBody == nil ||
+			ctx.Err() != nil || !c.retryPolicy.retryable(err, safe) {
+			return body, header, err
+		}
+		c.log("retry the request after error: ", err)
+		if !c.retryPolicy.wait(ctx, retry) {
+			return nil, nil, err
+		}
+		newBody, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return nil, nil, err
+		}
+		req = req.WithContext(req.Context())
+		req.Body = newBody
+	}
+}
+
+// sendRequest makes a single attempt to execute the request
+func (c *conn) sendRequest(ctx context.Context, transport http.RoundTripper, req *http.Request, safe bool) (io.ReadCloser, http.Header, error) {
+	req, resp, err := c.roundTrip(ctx, transport, req, safe)
 	if err != nil {
-		return nil, fmt.Errorf("doRequest: transport failed to send a request to ClickHouse: %w", err)
+		return nil, nil, fmt.Errorf("doRequest: transport failed to send a request to ClickHouse: %w", err)
 	}
 
 	if err = callCtxTransportCallback(ctx, req, resp); err != nil {
-		return nil, fmt.Errorf("doRequest: transport callback: %w", err)
+		return nil, nil, fmt.Errorf("doRequest: transport callback: %w", err)
 	}
 
 	if resp.StatusCode != 200 {
 		msg, err := readResponse(resp)
 		if err != nil {
-			return nil, fmt.Errorf("doRequest: failed to read the response with the status code %d: %w", resp.StatusCode, err)
+			return nil, nil, fmt.Errorf("doRequest: failed to read the response with the status code %d: %w", resp.StatusCode, err)
 		}
 		// we got non-200 response, which means ClickHouse send an error in the
 		// response
-		return nil, newError(string(msg))
+		return nil, nil, newError(string(msg))
 	}
 	if errHeader, ok := resp.Header[http.CanonicalHeaderKey("X-ClickHouse-Exception-Code")]; ok {
-		return nil, newError(strings.Join(errHeader, ", "))
+		return nil, nil, newError(strings.Join(errHeader, ", "))
 	}
 
-	return resp.Body, nil
+	return resp.Body, resp.Header, nil
+}
+
+// roundTrip sends the request to the hosts of the connection in the order
+// defined by the host strategy. If the transport fails before a response
+// arrives, the host is put into cooldown and the next one is tried.
+// The statements which are not safe to repeat are sent to the next host
+// only if they never reached the previous one.
+func (c *conn) roundTrip(ctx context.Context, transport http.RoundTripper, req *http.Request, safe bool) (*http.Request, *http.Response, error) {
+	hosts := []string{req.URL.Host}
+	if c.hosts != nil {
+		hosts = c.hosts.order()
+	}
+
+	var lastErr error
+	for i, host := range hosts {
+		r := req.WithContext(ctx)
+		if i > 0 {
+			if req.GetBody == nil {
+				break
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				break
+			}
+			r.Body = body
+		}
+		u := *req.URL
+		u.Host = host
+		r.URL, r.Host = &u, host
+
+		resp, err := transport.RoundTrip(r)
+		if err == nil {
+			if c.hosts != nil {
+				c.hosts.markAlive(host)
+			}
+			return r, resp, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+		if c.hosts != nil {
+			c.hosts.markFailed(host)
+		}
+		if !safe && reachedServer(err) {
+			break
+		}
+		if i < len(hosts)-1 {
+			c.log("host failed, trying the next one: ", host, err)
+		}
+	}
+	return req, nil, lastErr
 }
 
 func (c *conn) buildRequest(ctx context.Context, query string, params []driver.Value) (*http.Request, error) {
@@ -325,21 +584,37 @@ func (c *conn) buildRequest(ctx context.Context, query string, params []driver.V
 		}
 	}
 
-	bodyReader, bodyWriter := io.Pipe()
-	go func() {
-		if c.useGzipCompression {
-			gz := gzip.NewWriter(bodyWriter)
-			_, _ = gz.Write([]byte(query))
-			_ = gz.Close()
-			_ = bodyWriter.Close()
-		} else {
-			_, _ = bodyWriter.Write([]byte(query))
-			_ = bodyWriter.Close()
-		}
-	}()
+	newBody := func() io.ReadCloser {
+		bodyReader, bodyWriter := io.Pipe()
+		go func() {
+			if c.useGzipCompression {
+				gz := gzip.NewWriter(bodyWriter)
+				_, _ = gz.Write([]byte(query))
+				_ = gz.Close()
+				_ = bodyWriter.Close()
+			} else {
+				_, _ = bodyWriter.Write([]byte(query))
+				_ = bodyWriter.Close()
+			}
+		}()
+		return bodyReader
+	}
 	c.log("query: ", query)
 
-	req, err := http.NewRequest(http.MethodPost, c.url.String(), bodyReader)
+	req, err := c.newRequest(ctx, query, newBody())
+	if err != nil {
+		return nil, err
+	}
+	// the body is needed again when the request is sent to another host
+	req.GetBody = func() (io.ReadCloser, error) {
+		return newBody(), nil
+	}
+	return req, nil
+}
+
+// newRequest creates a request with the given body, which contains the query
+func (c *conn) newRequest(ctx context.Context, query string, body io.ReadCloser) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url.String(), body)
 	if err != nil {
 		return nil, fmt.Errorf("buildRequest: failed to create a request: %w", err)
 	}
@@ -370,7 +645,40 @@ func (c *conn) buildRequest(ctx context.Context, query string, params []driver.V
 			reqQuery.Add(queryIDParamName, queryID)
 		}
 
+		if progressFromContext(ctx) != nil {
+			if reqQuery == nil {
+				reqQuery = req.URL.Query()
+			}
+			reqQuery.Set(sendProgressInHeadersParam, "1")
+		}
+
+		queryParams, queryParamsOk := ctx.Value(ctxQueryParamsKey).(map[string]string)
+		if queryParamsOk {
+			if reqQuery == nil {
+				reqQuery = req.URL.Query()
+			}
+			for name, value := range queryParams {
+				reqQuery.Set(queryParamPrefix+name, value)
+			}
+		}
+
 		requestQueryParams, requestQueryParamsOk := ctx.Value(RequestQueryParams).(map[string]string)
+		if async, ok := c.asyncInsertSettings(ctx, query); ok {
+			// the settings passed explicitly are not overridden
+			if _, ok = requestQueryParams[asyncInsertParamName]; !ok {
+				if reqQuery == nil {
+					reqQuery = req.URL.Query()
+				}
+				reqQuery.Set(asyncInsertParamName, "1")
+				if _, ok = requestQueryParams[waitForAsyncInsertParamName]; !ok {
+					if async.wait {
+						reqQuery.Set(waitForAsyncInsertParamName, "1")
+					} else {
+						reqQuery.Set(waitForAsyncInsertParamName, "0")
+					}
+				}
+			}
+		}
 		if requestQueryParamsOk && len(requestQueryParams) != 0 {
 			if reqQuery == nil {
 				reqQuery = req.URL.Query()
@@ -396,6 +704,9 @@ func (c *conn) buildRequest(ctx context.Context, query string, params []driver.V
 		otel.GetTextMapPropagator().Inject(ctx, carrier)
 	}
 
+	// doRequest decides whether the request can be retried, so keep it in the request
+	req = req.WithContext(context.WithValue(req.Context(), ctxRetrySafeKey, isRetrySafe(query, req.URL.Query())))
+
 	return req, nil
 }
 
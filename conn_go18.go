@@ -25,7 +25,7 @@ func (c *conn) Ping(ctx context.Context) error {
 		return err
 	}
 
-	respBody, err := c.doRequest(ctx, req)
+	respBody, _, err := c.doRequest(ctx, req)
 	defer func() {
 		c.cancel = nil
 	}()
@@ -59,30 +59,40 @@ func (c *conn) PrepareContext(_ context.Context, query string) (driver.Stmt, err
 
 // ExecContext implements the driver.ExecerContext
 func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	values, err := namedValueToValue(args)
+	values, params, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
 	}
-	return c.exec(ctx, query, values)
+	return c.exec(withQueryParams(ctx, params), query, values)
 }
 
 // QueryContext implements the driver.QueryerContext
 func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	values, err := namedValueToValue(args)
+	values, params, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
 	}
-	return c.query(ctx, query, values)
+	return c.query(withQueryParams(ctx, params), query, values)
 }
 
-func namedValueToValue(named []driver.NamedValue) ([]driver.Value, error) {
-	dargs := make([]driver.Value, len(named))
-	for n, param := range named {
-		if len(param.Name) > 0 {
-			// TODO: support the use of Named Parameters #561
-			return nil, ErrNameParams
+// namedValueToValue splits the arguments into the values of placeholders
+// and the encoded values of named query parameters
+func namedValueToValue(named []driver.NamedValue) ([]driver.Value, map[string]string, error) {
+	dargs := make([]driver.Value, 0, len(named))
+	var params map[string]string
+	for _, param := range named {
+		if len(param.Name) == 0 {
+			dargs = append(dargs, param.Value)
+			continue
 		}
-		dargs[n] = param.Value
+		v, err := paramEncode.Encode(param.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("clickhouse: failed to encode parameter %s: %w", param.Name, err)
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[param.Name] = string(v)
 	}
-	return dargs, nil
+	return dargs, params, nil
 }
@@ -5,8 +5,14 @@ package clickhouse
 
 import (
 	"context"
+	"database/sql"
 	"database/sql/driver"
+	"io"
+	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -15,6 +21,7 @@ var (
 	_ driver.ConnPrepareContext = new(conn)
 	_ driver.ConnBeginTx        = new(conn)
 	_ driver.Pinger             = new(conn)
+	_ Conn                      = new(conn)
 )
 
 func (s *connSuite) TestQueryContext() {
@@ -67,3 +74,65 @@ func (s *connSuite) TestColumnTypes() {
 		s.Equal(e, types[i].DatabaseTypeName())
 	}
 }
+
+func (s *connSuite) TestQueryStats() {
+	conn, err := s.conn.Conn(context.Background())
+	s.Require().NoError(err)
+	defer conn.Close()
+	err = conn.Raw(func(driverConn interface{}) error {
+		res, err := driverConn.(driver.ExecerContext).ExecContext(context.Background(),
+			"INSERT INTO data (u64) SELECT number + 1000 FROM system.numbers LIMIT 3", nil)
+		if !s.NoError(err) {
+			return nil
+		}
+		stats, ok := res.(QueryStatsReporter).QueryStats()
+		s.True(ok)
+		s.EqualValues(3, stats.WrittenRows)
+		return nil
+	})
+	s.NoError(err)
+}
+
+func (s *connSuite) TestQueryNamedParams() {
+	rows, err := s.conn.Query("SELECT i64, {s:String}, {a:Array(String)}, {m:Map(String, UInt8)} FROM data WHERE i64={i:Int64} AND d32<?",
+		sql.Named("i", -1),
+		sql.Named("s", "it's\ta string"),
+		sql.Named("a", Array([]string{"a", "it's"})),
+		sql.Named("m", map[string]uint8{"k": 1}),
+		Decimal32(100, 4),
+	)
+	s.Require().NoError(err)
+	v, err := scanValues(rows, []interface{}{int64(0), "", []string{}, map[string]uint8{}})
+	if s.NoError(err) {
+		s.Equal([][]interface{}{{int64(-1), "it's\ta string", []string{"a", "it's"}, map[string]uint8{"k": 1}}}, v)
+	}
+	s.NoError(rows.Close())
+
+	st, err := s.conn.Prepare("SELECT i64 FROM data WHERE i64={i:Int64}")
+	s.Require().NoError(err)
+	defer st.Close()
+	var i64 int64
+	s.NoError(st.QueryRow(sql.Named("i", -2)).Scan(&i64))
+	s.Equal(int64(-2), i64)
+}
+
+func TestNamedValueToValue(t *testing.T) {
+	values, params, err := namedValueToValue([]driver.NamedValue{
+		{Ordinal: 1, Value: int64(1)},
+		{Name: "id", Ordinal: 2, Value: uint64(5)},
+		{Name: "names", Ordinal: 3, Value: []string{"a", "b"}},
+		{Ordinal: 4, Value: "x"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []driver.Value{int64(1), "x"}, values)
+	assert.Equal(t, map[string]string{"id": "5", "names": "['a','b']"}, params)
+
+	cn := newConn(NewConfig())
+	req, err := cn.buildRequest(withQueryParams(context.Background(), params), "SELECT {id:UInt64}, {names:Array(String)}, ?", values[:1])
+	require.NoError(t, err)
+	assert.Equal(t, "5", req.URL.Query().Get("param_id"))
+	assert.Equal(t, "['a','b']", req.URL.Query().Get("param_names"))
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT {id:UInt64}, {names:Array(String)}, 1", string(body))
+}
@@ -28,8 +28,10 @@ func (s *connSuite) TestExecBuild19() {
 		s.NotNil(result)
 		_, err = result.LastInsertId()
 		s.Equal(ErrNoLastInsertID, err)
-		_, err = result.RowsAffected()
-		s.Equal(ErrNoRowsAffected, err)
+		affected, err := result.RowsAffected()
+		if s.NoError(err) {
+			s.EqualValues(1, affected)
+		}
 		if len(tc.query2) == 0 {
 			continue
 		}
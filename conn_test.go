@@ -7,6 +7,7 @@ import (
 	"database/sql/driver"
 	"io"
 	"net/http"
+	"net/netip"
 	"strings"
 	"testing"
 	"time"
@@ -41,9 +42,9 @@ func (s *connSuite) TestQuery() {
 		{"SELECT i64 AS num FROM data WHERE i64<?", []interface{}{-3}, [][]interface{}{}},
 		{"SELECT i64 AS num FROM data WHERE i64=?", []interface{}{nil}, [][]interface{}{}},
 		{"SELECT i64 AS num FROM data WHERE i64=?", []interface{}{-1}, [][]interface{}{{int64(-1)}}},
-		{"SELECT d32 AS num FROM data WHERE d32=?", []interface{}{Decimal32(10.1111, 4)}, [][]interface{}{{"10.1111"}}},
-		{"SELECT d64 AS num FROM data WHERE d64=?", []interface{}{Decimal64(100.1111, 4)}, [][]interface{}{{"100.1111"}}},
-		{"SELECT d128 AS num FROM data WHERE d128=?", []interface{}{Decimal128(1000.1111, 4)}, [][]interface{}{{"1000.1111"}}},
+		{"SELECT d32 AS num FROM data WHERE d32=?", []interface{}{Decimal32(10.1111, 4)}, [][]interface{}{{parseDecimalValue("10.1111")}}},
+		{"SELECT d64 AS num FROM data WHERE d64=?", []interface{}{Decimal64(100.1111, 4)}, [][]interface{}{{parseDecimalValue("100.1111")}}},
+		{"SELECT d128 AS num FROM data WHERE d128=?", []interface{}{Decimal128(1000.1111, 4)}, [][]interface{}{{parseDecimalValue("1000.1111")}}},
 		{
 			"SELECT * FROM data WHERE u64=?",
 			[]interface{}{1},
@@ -51,8 +52,9 @@ func (s *connSuite) TestQuery() {
 				{
 					int64(-1), uint64(1), float64(1), true, "1", "1", []int16{1}, []uint8{10},
 					parseDate("2011-03-06"), parseDateTime("2011-03-06 06:20:00"), "one",
-					"10.1111", "100.1111", "1000.1111", "1.1111", "127.0.0.1",
-					"2001:db8:3333:4444:5555:6666:7777:8888", "12345678", "one",
+					parseDecimalValue("10.1111"), parseDecimalValue("100.1111"), parseDecimalValue("1000.1111"),
+					parseDecimalValue("1.1111"), netip.MustParseAddr("127.0.0.1"),
+					netip.MustParseAddr("2001:db8:3333:4444:5555:6666:7777:8888"), "12345678", "one",
 					map[string][]int64{"key1": {1}},
 					map[string]int64{"key1": 1},
 					map[int32]int32{1: 1},
@@ -149,8 +151,10 @@ func (s *connSuite) TestExec() {
 		s.NotNil(result)
 		_, err = result.LastInsertId()
 		s.Equal(ErrNoLastInsertID, err)
-		_, err = result.RowsAffected()
-		s.Equal(ErrNoRowsAffected, err)
+		affected, err := result.RowsAffected()
+		if s.NoError(err) {
+			s.EqualValues(1, affected)
+		}
 		if len(tc.query2) == 0 {
 			continue
 		}
@@ -0,0 +1,179 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultMaxIdleConns is the number of idle connections kept by the transport
+// shared by all connections of the Connectors with the same transport options
+const defaultMaxIdleConns = 100
+
+var (
+	sharedTransportsLock sync.Mutex
+	sharedTransports     map[transportKey]*sharedTransport
+)
+
+// transportKey consists of the options of the Config the transport is built of
+type transportKey struct {
+	timeout     time.Duration
+	idleTimeout time.Duration
+	readTimeout time.Duration
+	tlsConfig   *tls.Config
+}
+
+// sharedTransport is used by all the Connectors with the same transport options
+// like http.DefaultTransport, it is closed when the last of them is closed
+type sharedTransport struct {
+	transport *progressTransport
+	refs      int
+}
+
+func newTransportKey(cfg *Config) transportKey {
+	return transportKey{
+		timeout:     cfg.Timeout,
+		idleTimeout: cfg.IdleTimeout,
+		readTimeout: cfg.ReadTimeout,
+		tlsConfig:   getTLSConfig(cfg.TLSConfig),
+	}
+}
+
+// acquireTransport returns the transport shared by the Connectors with the same transport options
+func acquireTransport(cfg *Config, key transportKey) *progressTransport {
+	sharedTransportsLock.Lock()
+	defer sharedTransportsLock.Unlock()
+	if sharedTransports == nil {
+		sharedTransports = make(map[transportKey]*sharedTransport)
+	}
+	t, ok := sharedTransports[key]
+	if !ok {
+		t = &sharedTransport{transport: newTransport(cfg, defaultMaxIdleConns)}
+		sharedTransports[key] = t
+	}
+	t.refs++
+	return t.transport
+}
+
+// releaseTransport closes the idle connections of the shared transport
+// and forgets it when it is not used by any Connector
+func releaseTransport(key transportKey) {
+	sharedTransportsLock.Lock()
+	defer sharedTransportsLock.Unlock()
+	t, ok := sharedTransports[key]
+	if !ok {
+		return
+	}
+	if t.refs--; t.refs == 0 {
+		delete(sharedTransports, key)
+		t.transport.CloseIdleConnections()
+	}
+}
+
+// Option configures a Connector
+type Option func(*Connector)
+
+// WithRoundTripper sets the transport used to send requests to ClickHouse.
+// Timeouts and TLS options of the Config are not applied to it.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(c *Connector) {
+		c.transport = rt
+	}
+}
+
+// WithHTTPClient sets the client used to send requests to ClickHouse.
+// Timeouts and TLS options of the Config are not applied to it.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Connector) {
+		c.transport = clientRoundTripper{client}
+	}
+}
+
+// clientRoundTripper sends requests with http.Client, so its redirect
+// policy, cookies and timeout are applied
+type clientRoundTripper struct {
+	client *http.Client
+}
+
+// RoundTrip implements http.RoundTripper
+func (c clientRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// Connector implements driver.Connector interface.
+// All connections of a Connector share the same transport and hosts,
+// the transport is shared with the other Connectors unless it is set by an Option.
+type Connector struct {
+	cfg       *Config
+	transport http.RoundTripper
+	hosts     *hostPool
+	driver    *Driver
+	// shared is set if the transport is shared, it is released by Close
+	shared    *transportKey
+	closeOnce sync.Once
+}
+
+// NewConnector returns a connector which can be used with sql.OpenDB
+func NewConnector(cfg *Config, opts ...Option) (*Connector, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("clickhouse: config must be set")
+	}
+	if cfg.HostStrategy != "" {
+		if _, err := parseHostStrategy(string(cfg.HostStrategy)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Format != "" {
+		if _, err := parseResultFormat(string(cfg.Format)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.InsertFormat != "" {
+		if _, err := parseInsertFormat(string(cfg.InsertFormat)); err != nil {
+			return nil, err
+		}
+	}
+	cfg = cfg.clone()
+	c := &Connector{
+		cfg:    cfg,
+		hosts:  newHostPool(cfg.hosts(), cfg.HostStrategy, cfg.HostCooldown),
+		driver: new(Driver),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.transport == nil {
+		key := newTransportKey(cfg)
+		c.transport = acquireTransport(cfg, key)
+		c.shared = &key
+	}
+	return c, nil
+}
+
+// Connect implements driver.Connector
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return newConnWithTransport(c.cfg, c.transport, c.hosts), nil
+}
+
+// Driver implements driver.Connector
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close releases the shared transport, its idle connections are closed
+// when it is not used by other connectors. It is called by sql.DB.Close.
+func (c *Connector) Close() error {
+	if c.shared != nil {
+		c.closeOnce.Do(func() {
+			releaseTransport(*c.shared)
+		})
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ driver.Connector     = new(Connector)
+	_ driver.DriverContext = new(Driver)
+	_ io.Closer            = new(Connector)
+)
+
+type countingRoundTripper struct {
+	requests int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.requests, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newPingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte("1\n"))
+	}))
+}
+
+func TestConnectorRoundTripper(t *testing.T) {
+	srv := newPingServer()
+	defer srv.Close()
+
+	cfg, err := ParseDSN(srv.URL + "/default")
+	require.NoError(t, err)
+	rt := new(countingRoundTripper)
+	connector, err := NewConnector(cfg, WithRoundTripper(rt))
+	require.NoError(t, err)
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conns[i], err = db.Conn(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, conns[i].PingContext(context.Background()))
+	}
+	for _, c := range conns {
+		require.NoError(t, c.Raw(func(driverConn interface{}) error {
+			assert.True(t, driverConn.(*conn).transport == rt)
+			return nil
+		}))
+		assert.NoError(t, c.Close())
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&rt.requests))
+}
+
+func TestConnectorHTTPClient(t *testing.T) {
+	srv := newPingServer()
+	defer srv.Close()
+
+	cfg, err := ParseDSN(srv.URL + "/default")
+	require.NoError(t, err)
+	rt := new(countingRoundTripper)
+	connector, err := NewConnector(cfg, WithHTTPClient(&http.Client{Transport: rt}))
+	require.NoError(t, err)
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	require.NoError(t, db.Ping())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&rt.requests))
+}
+
+func TestConnectorSharedTransport(t *testing.T) {
+	srv := newPingServer()
+	defer srv.Close()
+
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	c1, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer c1.Close()
+	c2, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer c2.Close()
+
+	var transports []http.RoundTripper
+	for _, c := range []*sql.Conn{c1, c2} {
+		require.NoError(t, c.Raw(func(driverConn interface{}) error {
+			transports = append(transports, driverConn.(*conn).transport)
+			return nil
+		}))
+	}
+	assert.True(t, transports[0] == transports[1])
+}
+
+func TestConnectorTransportSharedByDBs(t *testing.T) {
+	transport := func(db *sql.DB) (rt http.RoundTripper) {
+		c, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		defer c.Close()
+		require.NoError(t, c.Raw(func(driverConn interface{}) error {
+			rt = driverConn.(*conn).transport
+			return nil
+		}))
+		return rt
+	}
+	// the idle timeout is not used by the other tests, so the transport is not shared with them
+	const dsn = "http://127.0.0.1:8123/default?idle_timeout=17s"
+	db1, err := sql.Open("chhttp", dsn)
+	require.NoError(t, err)
+	db2, err := sql.Open("chhttp", dsn+"&max_execution_time=10")
+	require.NoError(t, err)
+	db3, err := sql.Open("chhttp", dsn+"&read_timeout=7s")
+	require.NoError(t, err)
+	defer db3.Close()
+
+	// the pools with the same transport options share the transport
+	assert.True(t, transport(db1) == transport(db2))
+	assert.False(t, transport(db1) == transport(db3))
+
+	cfg, err := ParseDSN(dsn)
+	require.NoError(t, err)
+	key := newTransportKey(cfg)
+	require.NoError(t, db1.Close())
+	sharedTransportsLock.Lock()
+	assert.Equal(t, 1, sharedTransports[key].refs)
+	sharedTransportsLock.Unlock()
+	require.NoError(t, db2.Close())
+	sharedTransportsLock.Lock()
+	assert.NotContains(t, sharedTransports, key)
+	sharedTransportsLock.Unlock()
+}
+
+func TestNewConnectorConfig(t *testing.T) {
+	_, err := NewConnector(nil)
+	assert.Error(t, err)
+
+	cfg := NewConfig()
+	cfg.HostStrategy = "fastest"
+	_, err = NewConnector(cfg)
+	assert.EqualError(t, err, "unknown host strategy 'fastest'")
+
+	cfg = NewConfig()
+	cfg.InsertFormat = "csv"
+	_, err = NewConnector(cfg)
+	assert.EqualError(t, err, "unknown insert format 'csv'")
+
+	// the connector must not be affected by further changes of the config
+	cfg = NewConfig()
+	connector, err := NewConnector(cfg)
+	require.NoError(t, err)
+	cfg.Params["max_execution_time"] = "10"
+	cn, err := connector.Connect(context.Background())
+	require.NoError(t, err)
+	assert.NotContains(t, cn.(*conn).url.RawQuery, "max_execution_time")
+	assert.NoError(t, connector.Close())
+}
@@ -9,8 +9,25 @@ type ctxKey uint8
 
 const (
 	ctxTransportCallbackKey ctxKey = iota + 1
+	ctxRetrySafeKey
+	ctxProgressKey
+	ctxQueryParamsKey
+	ctxAsyncInsertKey
 )
 
+// queryParamPrefix is the prefix of the URL parameters holding
+// the values of {name:Type} query parameters
+const queryParamPrefix = "param_"
+
+// withQueryParams returns a context which passes the encoded
+// named parameters to buildRequest
+func withQueryParams(ctx context.Context, params map[string]string) context.Context {
+	if len(params) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, ctxQueryParamsKey, params)
+}
+
 // TransportCallback is a transport response callback. Called before processing the http response.
 type TransportCallback func(*http.Request, *http.Response) error
 
@@ -3,12 +3,17 @@ package clickhouse
 import (
 	"bytes"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
+	"net/netip"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 var (
@@ -26,6 +31,14 @@ var (
 	reflectTypeUInt64      = reflect.TypeOf(uint64(0))
 	reflectTypeFloat32     = reflect.TypeOf(float32(0))
 	reflectTypeFloat64     = reflect.TypeOf(float64(0))
+	reflectTypeBigInt      = reflect.TypeOf((*big.Int)(nil))
+	reflectTypeDecimal     = reflect.TypeOf(Decimal{})
+	reflectTypeUUID        = reflect.TypeOf(uuid.UUID{})
+	reflectTypeAddr        = reflect.TypeOf(netip.Addr{})
+	reflectTypeNamedTuple  = reflect.TypeOf(map[string]interface{}{})
+	reflectTypeJSON        = reflect.TypeOf(map[string]interface{}{})
+	reflectTypeRawMessage  = reflect.TypeOf(json.RawMessage{})
+	reflectTypeVariant     = reflect.TypeOf((*interface{})(nil)).Elem() // Variant or nil
 )
 
 func readNumber(s io.RuneScanner) (string, error) {
@@ -120,18 +133,22 @@ func (p *nullableParser) Parse(s io.RuneScanner) (driver.Value, error) {
 	var dB *bytes.Buffer
 
 	dType := p.DataParser.Type()
+	if _, ok := p.DataParser.(*decimalParser); ok {
+		// the decimals parsed into strings are still numbers
+		dType = reflectTypeDecimal
+	}
 
 	switch dType {
 	case reflectTypeInt8, reflectTypeInt16, reflectTypeInt32, reflectTypeInt64,
 		reflectTypeUInt8, reflectTypeUInt16, reflectTypeUInt32, reflectTypeUInt64,
-		reflectTypeFloat32, reflectTypeFloat64:
+		reflectTypeFloat32, reflectTypeFloat64, reflectTypeBigInt, reflectTypeDecimal:
 		d, err := readNumber(s)
 		if err != nil {
 			return nil, fmt.Errorf("error: %v", err)
 		}
 
 		dB = bytes.NewBufferString(d)
-	case reflectTypeString:
+	case reflectTypeString, reflectTypeUUID, reflectTypeAddr:
 		runes := ""
 		iter := 0
 
@@ -244,6 +261,56 @@ func (p *stringParser) Type() reflect.Type {
 	return reflectTypeString
 }
 
+// uuidParser parses UUID values into uuid.UUID or strings
+type uuidParser struct {
+	stringParser
+	asString bool
+}
+
+func (p *uuidParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	str, err := p.stringParser.Parse(s)
+	if err != nil || p.asString {
+		return str, err
+	}
+	return uuid.Parse(str.(string))
+}
+
+func (p *uuidParser) Type() reflect.Type {
+	if p.asString {
+		return reflectTypeString
+	}
+	return reflectTypeUUID
+}
+
+// ipParser parses IPv4 and IPv6 values into netip.Addr or strings
+type ipParser struct {
+	stringParser
+	asString bool
+}
+
+func (p *ipParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	str, err := p.stringParser.Parse(s)
+	if err != nil || p.asString {
+		return str, err
+	}
+	return netip.ParseAddr(str.(string))
+}
+
+func (p *ipParser) Type() reflect.Type {
+	if p.asString {
+		return reflectTypeString
+	}
+	return reflectTypeAddr
+}
+
+// value returns the address or its text representation
+func (p *ipParser) value(addr netip.Addr) driver.Value {
+	if p.asString {
+		return addr.String()
+	}
+	return addr
+}
+
 type dateTimeParser struct {
 	unquote   bool
 	format    string
@@ -281,14 +348,27 @@ func (p *dateTimeParser) Type() reflect.Type {
 }
 
 type tupleParser struct {
-	args []DataParser
+	args  []DataParser
+	names []string // the names of the elements of a named tuple
 }
 
 func (p *tupleParser) Type() reflect.Type {
-	fields := make([]reflect.StructField, len(p.args))
+	if p.names != nil {
+		return reflectTypeNamedTuple
+	}
+	types := make([]reflect.Type, len(p.args))
 	for i, arg := range p.args {
+		types[i] = arg.Type()
+	}
+	return tupleType(types)
+}
+
+// tupleType returns the struct type used for a tuple with the given element types
+func tupleType(types []reflect.Type) reflect.Type {
+	fields := make([]reflect.StructField, len(types))
+	for i, typ := range types {
 		fields[i].Name = "Field" + strconv.Itoa(i)
-		fields[i].Type = arg.Type()
+		fields[i].Type = typ
 	}
 	return reflect.StructOf(fields)
 }
@@ -299,7 +379,7 @@ func (p *tupleParser) Parse(s io.RuneScanner) (driver.Value, error) {
 		return nil, fmt.Errorf("unexpected character '%c', expected '(' at the beginning of tuple", r)
 	}
 
-	struc := reflect.New(p.Type()).Elem()
+	values := make([]driver.Value, len(p.args))
 	for i, arg := range p.args {
 		if i > 0 {
 			r := read(s)
@@ -313,7 +393,7 @@ func (p *tupleParser) Parse(s io.RuneScanner) (driver.Value, error) {
 			return nil, fmt.Errorf("failed to parse tuple element: %v", err)
 		}
 
-		struc.Field(i).Set(reflect.ValueOf(v))
+		values[i] = v
 	}
 
 	r = read(s)
@@ -321,7 +401,26 @@ func (p *tupleParser) Parse(s io.RuneScanner) (driver.Value, error) {
 		return nil, fmt.Errorf("unexpected character '%c', expected ')' at the end of tuple", r)
 	}
 
-	return struc.Interface(), nil
+	return tupleValue(p.Type(), p.names, values), nil
+}
+
+// tupleValue returns the value of a tuple of the given type, the elements of
+// named tuples are keyed by their names and the others are the fields of a struct
+func tupleValue(typ reflect.Type, names []string, values []driver.Value) driver.Value {
+	if names != nil {
+		m := make(map[string]interface{}, len(values))
+		for i, v := range values {
+			m[names[i]] = v
+		}
+		return m
+	}
+	struc := reflect.New(typ).Elem()
+	for i, v := range values {
+		if v != nil {
+			struc.Field(i).Set(reflect.ValueOf(v))
+		}
+	}
+	return struc.Interface()
 }
 
 type arrayParser struct {
@@ -352,7 +451,10 @@ func (p *arrayParser) Parse(s io.RuneScanner) (driver.Value, error) {
 		}
 
 		if v == nil {
-			if reflect.TypeOf(p.arg) != reflect.TypeOf(&nullableParser{}) {
+			if p.arg.Type() == reflectTypeVariant {
+				// NULL values of Variant and Dynamic are kept
+				slice = reflect.Append(slice, reflect.Zero(reflectTypeVariant))
+			} else if reflect.TypeOf(p.arg) != reflect.TypeOf(&nullableParser{}) {
 				//need check if v is nil: panic otherwise
 				return nil, fmt.Errorf("unexpected nil element")
 			}
@@ -488,6 +590,12 @@ type floatParser struct {
 	bitSize int
 }
 
+// bigIntParser parses the integers wider than 64 bits into *big.Int
+type bigIntParser struct {
+	signed  bool
+	bitSize int
+}
+
 func (p *intParser) Parse(s io.RuneScanner) (driver.Value, error) {
 	repr, err := readNumber(s)
 	if err != nil {
@@ -555,6 +663,65 @@ func (p *intParser) Type() reflect.Type {
 	}
 }
 
+func (p *bigIntParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	repr, err := readNumber(s)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := new(big.Int).SetString(repr, 10)
+	if !ok {
+		return nil, fmt.Errorf("malformed %d bit integer %s", p.bitSize, repr)
+	}
+	return v, nil
+}
+
+func (p *bigIntParser) Type() reflect.Type {
+	return reflectTypeBigInt
+}
+
+// decimalParser parses decimals of the given scale into Decimal
+type decimalParser struct {
+	scale    int
+	asString bool
+}
+
+func (p *decimalParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	var repr string
+	var err error
+	// the decimals in arrays and tuples may be quoted
+	quoted := read(s) == '\''
+	_ = s.UnreadRune()
+	if quoted {
+		repr, err = readString(s, 0, true)
+	} else {
+		repr, err = readNumber(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	unscaled, err := parseDecimal(repr, p.scale)
+	if err != nil {
+		return nil, err
+	}
+	return p.value(unscaled), nil
+}
+
+// value returns the decimal or its text representation
+func (p *decimalParser) value(unscaled *big.Int) driver.Value {
+	d := Decimal{unscaled: unscaled, scale: int32(p.scale)}
+	if p.asString {
+		return d.String()
+	}
+	return d
+}
+
+func (p *decimalParser) Type() reflect.Type {
+	if p.asString {
+		return reflectTypeString
+	}
+	return reflectTypeDecimal
+}
+
 func (p *floatParser) Parse(s io.RuneScanner) (driver.Value, error) {
 	repr, err := readNumber(s)
 	if err != nil {
@@ -593,6 +760,95 @@ func (p *nothingParser) Type() reflect.Type {
 	return reflectTypeEmptyStruct
 }
 
+// jsonParser parses JSON objects into maps or json.RawMessage
+type jsonParser struct {
+	unquote bool
+	raw     bool
+}
+
+func (p *jsonParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	str, err := readString(s, 0, p.unquote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON: %v", err)
+	}
+	if p.raw {
+		return json.RawMessage(str), nil
+	}
+	return decodeJSON([]byte(str))
+}
+
+func (p *jsonParser) Type() reflect.Type {
+	if p.raw {
+		return reflectTypeRawMessage
+	}
+	return reflectTypeJSON
+}
+
+// variantParser parses the values of Variant columns. The types of the values
+// are not known in text formats, so the value has the first type it can be parsed as,
+// the strings are tried last.
+type variantParser struct {
+	unquote bool
+	types   []string
+	args    []DataParser
+}
+
+func (p *variantParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	str := readVariantText(s, p.unquote)
+	if isNullText(str, p.unquote) {
+		return nil, nil
+	}
+	for i, arg := range p.args {
+		r := strings.NewReader(str)
+		if v, err := arg.Parse(r); err == nil && r.Len() == 0 {
+			return Variant{Type: p.types[i], Value: v}, nil
+		}
+	}
+	return nil, fmt.Errorf("value %s has none of the types of Variant", str)
+}
+
+func (p *variantParser) Type() reflect.Type {
+	return reflectTypeVariant
+}
+
+// dynamicParser parses the values of Dynamic columns, their types are
+// not known in text formats, so the values are returned as text
+type dynamicParser struct {
+	unquote bool
+}
+
+func (p *dynamicParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	str := readVariantText(s, p.unquote)
+	if isNullText(str, p.unquote) {
+		return nil, nil
+	}
+	if p.unquote && strings.HasPrefix(str, "'") {
+		// the strings in arrays and tuples are quoted
+		v, err := readString(strings.NewReader(str), 0, true)
+		if err != nil {
+			return nil, err
+		}
+		return Variant{Value: v}, nil
+	}
+	var b strings.Builder
+	r := strings.NewReader(str)
+	for c := read(r); c != eof; c = read(r) {
+		if c == '\\' && !p.unquote {
+			escaped, err := readEscaped(r)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect escaping in string: %v", err)
+			}
+			c = escaped
+		}
+		b.WriteRune(c)
+	}
+	return Variant{Value: b.String()}, nil
+}
+
+func (p *dynamicParser) Type() reflect.Type {
+	return reflectTypeVariant
+}
+
 // DataParserOptions describes DataParser options.
 // Ex.: Fields Location and UseDBLocation specify timezone options.
 type DataParserOptions struct {
@@ -600,6 +856,14 @@ type DataParserOptions struct {
 	Location *time.Location
 	// UseDBLocation if false: always use Location, ignore DateTime argument.
 	UseDBLocation bool
+	// UUIDAndIPAsString if true: UUID, IPv4 and IPv6 values are parsed into strings
+	// instead of uuid.UUID and netip.Addr.
+	UUIDAndIPAsString bool
+	// JSONAsRaw if true: the values of JSON columns are parsed into json.RawMessage
+	// instead of map[string]interface{}.
+	JSONAsRaw bool
+	// DecimalAsString if true: decimals are parsed into strings instead of Decimal.
+	DecimalAsString bool
 }
 
 // NewDataParser creates a new DataParser based on the
@@ -683,12 +947,30 @@ func newDataParser(t *TypeDesc, unquote bool, opt *DataParserOptions) (DataParse
 		return &intParser{true, 32}, nil
 	case "Int64":
 		return &intParser{true, 64}, nil
+	case "UInt128":
+		return &bigIntParser{false, 128}, nil
+	case "UInt256":
+		return &bigIntParser{false, 256}, nil
+	case "Int128":
+		return &bigIntParser{true, 128}, nil
+	case "Int256":
+		return &bigIntParser{true, 256}, nil
 	case "Float32":
 		return &floatParser{32}, nil
 	case "Float64":
 		return &floatParser{64}, nil
-	case "Decimal", "String", "Enum8", "Enum16", "UUID", "IPv4", "IPv6":
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		_, scale, err := decimalArgs(t)
+		if err != nil {
+			return nil, err
+		}
+		return &decimalParser{scale: scale, asString: opt != nil && opt.DecimalAsString}, nil
+	case "String", "Enum8", "Enum16":
 		return &stringParser{unquote: unquote}, nil
+	case "UUID":
+		return &uuidParser{stringParser{unquote: unquote}, opt != nil && opt.UUIDAndIPAsString}, nil
+	case "IPv4", "IPv6":
+		return &ipParser{stringParser{unquote: unquote}, opt != nil && opt.UUIDAndIPAsString}, nil
 	case "FixedString":
 		if len(t.Args) != 1 {
 			return nil, fmt.Errorf("length not specified for FixedString")
@@ -719,7 +1001,14 @@ func newDataParser(t *TypeDesc, unquote bool, opt *DataParserOptions) (DataParse
 			}
 			subParsers[i] = subParser
 		}
-		return &tupleParser{subParsers}, nil
+		return &tupleParser{subParsers, elemNames(t)}, nil
+	case "Nested":
+		// the values of Nested columns are arrays of named tuples
+		subParser, err := newDataParser(&TypeDesc{Name: "Tuple", Args: t.Args}, true, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for Nested: %v", err)
+		}
+		return &arrayParser{subParser}, nil
 	case "LowCardinality":
 		if len(t.Args) != 1 {
 			return nil, fmt.Errorf("element type not specified for LowCardinality")
@@ -754,6 +1043,37 @@ func newDataParser(t *TypeDesc, unquote bool, opt *DataParserOptions) (DataParse
 			key:   keyParser,
 			value: valueParser,
 		}, nil
+	case "JSON", "Object":
+		return &jsonParser{unquote: unquote, raw: opt != nil && opt.JSONAsRaw}, nil
+	case "Variant":
+		if len(t.Args) < 1 {
+			return nil, fmt.Errorf("types not specified for Variant")
+		}
+		p := &variantParser{unquote: unquote}
+		// any text is a string, so the strings are tried last
+		for _, last := range []bool{false, true} {
+			for _, arg := range t.Args {
+				if stringVariant(arg) != last {
+					continue
+				}
+				argParser, err := newDataParser(arg, unquote, opt)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create parser for Variant type: %v", err)
+				}
+				p.types = append(p.types, arg.String())
+				p.args = append(p.args, argParser)
+			}
+		}
+		return p, nil
+	case "Dynamic":
+		return &dynamicParser{unquote: unquote}, nil
+	case "Point", "Ring", "Polygon", "MultiPolygon":
+		// the geo types are the aliases of tuples and arrays of Float64
+		subParser, err := newDataParser(geoTypeDesc(t.Name), unquote, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create parser for %s: %v", t.Name, err)
+		}
+		return &geoParser{subParser, geoTypes[t.Name]}, nil
 	default:
 		return nil, fmt.Errorf("type %s is not supported", t.Name)
 	}
@@ -1,11 +1,15 @@
 package clickhouse
 
 import (
+	"encoding/json"
 	"math"
+	"math/big"
+	"net/netip"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -80,6 +84,30 @@ func TestParseData(t *testing.T) {
 			inputdata: "123",
 			output:    uint64(123),
 		},
+		{
+			name:      "int128",
+			inputtype: "Int128",
+			inputdata: "-170141183460469231731687303715884105728",
+			output:    bigIntFromString("-170141183460469231731687303715884105728"),
+		},
+		{
+			name:      "nullable uint256",
+			inputtype: "Nullable(UInt256)",
+			inputdata: `\N`,
+			output:    nil,
+		},
+		{
+			name:      "nullable uint256 not null",
+			inputtype: "Nullable(UInt256)",
+			inputdata: "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+			output:    bigIntFromString("115792089237316195423570985008687907853269984665640564039457584007913129639935"),
+		},
+		{
+			name:          "malformed int256",
+			inputtype:     "Int256",
+			inputdata:     "1e5",
+			failParseData: true,
+		},
 		{
 			name:      "float",
 			inputtype: "Float32",
@@ -90,7 +118,14 @@ func TestParseData(t *testing.T) {
 			name:      "decimal",
 			inputtype: "Decimal(9,4)",
 			inputdata: "123",
-			output:    "123",
+			output:    NewDecimal(big.NewInt(1230000), 4),
+		},
+		{
+			name:      "decimal as string",
+			inputtype: "Decimal(9,4)",
+			inputopt:  &DataParserOptions{DecimalAsString: true},
+			inputdata: "-12.5000",
+			output:    "-12.5",
 		},
 		{
 			name:      "date",
@@ -123,8 +158,21 @@ func TestParseData(t *testing.T) {
 			name:      "uuid",
 			inputtype: "UUID",
 			inputdata: "c79a9747-7cef-4b11-8177-380f7ed462a4",
+			output:    uuid.MustParse("c79a9747-7cef-4b11-8177-380f7ed462a4"),
+		},
+		{
+			name:      "uuid as string",
+			inputtype: "UUID",
+			inputopt:  &DataParserOptions{UUIDAndIPAsString: true},
+			inputdata: "c79a9747-7cef-4b11-8177-380f7ed462a4",
 			output:    "c79a9747-7cef-4b11-8177-380f7ed462a4",
 		},
+		{
+			name:          "malformed uuid",
+			inputtype:     "UUID",
+			inputdata:     "c79a9747",
+			failParseData: true,
+		},
 		{
 			name:      "datetime, without options and argument",
 			inputtype: "DateTime",
@@ -274,6 +322,108 @@ func TestParseData(t *testing.T) {
 				Field4 int64
 			}{"hello world", 32.1, -1, 2, 3},
 		},
+		{
+			name:      "named tuple",
+			inputtype: "Tuple(id UInt64, `user name` String)",
+			inputdata: "(1,'a b')",
+			output:    map[string]interface{}{"id": uint64(1), "user name": "a b"},
+		},
+		{
+			name:      "nested",
+			inputtype: "Nested(a String, b Array(Int32))",
+			inputdata: "[('x',[1,2]),('y',[])]",
+			output: []map[string]interface{}{
+				{"a": "x", "b": []int32{1, 2}},
+				{"a": "y", "b": []int32{}},
+			},
+		},
+		{
+			name:      "json",
+			inputtype: "JSON",
+			inputdata: `{"a":{"b":"1","c":[1,2.5]},"d":-1}`,
+			output:    map[string]interface{}{"a": map[string]interface{}{"b": "1", "c": []interface{}{int64(1), 2.5}}, "d": int64(-1)},
+		},
+		{
+			name:      "json as raw",
+			inputtype: "JSON(a UInt8)",
+			inputopt:  &DataParserOptions{JSONAsRaw: true},
+			inputdata: `{"a":1,"b":"x\\\\y"}`,
+			output:    json.RawMessage(`{"a":1,"b":"x\\y"}`),
+		},
+		{
+			name:          "malformed json",
+			inputtype:     "JSON",
+			inputdata:     `{"a":`,
+			failParseData: true,
+		},
+		{
+			name:      "variant of number",
+			inputtype: "Variant(String, UInt64)",
+			inputdata: "123",
+			output:    Variant{Type: "UInt64", Value: uint64(123)},
+		},
+		{
+			name:      "variant of string",
+			inputtype: "Variant(String, UInt64)",
+			inputdata: `a\tb`,
+			output:    Variant{Type: "String", Value: "a\tb"},
+		},
+		{
+			name:      "variant of null",
+			inputtype: "Variant(String, UInt64)",
+			inputdata: `\N`,
+			output:    nil,
+		},
+		{
+			name:      "array of variants",
+			inputtype: "Array(Variant(Array(UInt8), String))",
+			inputdata: `[[1,2],'[a',NULL]`,
+			output: []interface{}{
+				Variant{Type: "Array(UInt8)", Value: []uint8{1, 2}},
+				Variant{Type: "String", Value: "[a"},
+				nil,
+			},
+		},
+		{
+			name:          "value of none of variant types",
+			inputtype:     "Variant(Date, UInt64)",
+			inputdata:     "abc",
+			failParseData: true,
+		},
+		{
+			name:      "dynamic",
+			inputtype: "Dynamic",
+			inputdata: `a\tb`,
+			output:    Variant{Value: "a\tb"},
+		},
+		{
+			name:      "tuple of dynamic",
+			inputtype: "Tuple(Dynamic, Dynamic, Dynamic)",
+			inputdata: `('a\'b',[1,'x'],NULL)`,
+			output: struct {
+				Field0 interface{}
+				Field1 interface{}
+				Field2 interface{}
+			}{Variant{Value: "a'b"}, Variant{Value: "[1,'x']"}, nil},
+		},
+		{
+			name:      "point",
+			inputtype: "Point",
+			inputdata: "(1.5,-2)",
+			output:    Point{1.5, -2},
+		},
+		{
+			name:      "multipolygon",
+			inputtype: "MultiPolygon",
+			inputdata: "[[[(0,0),(1,0),(1,1)],[]],[]]",
+			output:    MultiPolygon{{{{0, 0}, {1, 0}, {1, 1}}, {}}, {}},
+		},
+		{
+			name:          "malformed point",
+			inputtype:     "Point",
+			inputdata:     "(1)",
+			failParseData: true,
+		},
 		{
 			name:      "array of strings",
 			inputtype: "Array(String)",
@@ -422,14 +572,27 @@ func TestParseData(t *testing.T) {
 			name:      "ipv4",
 			inputtype: "IPv4",
 			inputdata: "127.0.0.1",
-			output:    "127.0.0.1",
+			output:    netip.MustParseAddr("127.0.0.1"),
 		},
 		{
 			name:      "ipv6",
 			inputtype: "IPv6",
 			inputdata: "2a02:aa08:e000:3100::2",
+			output:    netip.MustParseAddr("2a02:aa08:e000:3100::2"),
+		},
+		{
+			name:      "ipv6 as string",
+			inputtype: "IPv6",
+			inputopt:  &DataParserOptions{UUIDAndIPAsString: true},
+			inputdata: "2a02:aa08:e000:3100::2",
 			output:    "2a02:aa08:e000:3100::2",
 		},
+		{
+			name:          "malformed ipv4",
+			inputtype:     "IPv4",
+			inputdata:     "127.0.0",
+			failParseData: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -500,7 +663,7 @@ func TestParseDataNewNullableArray(t *testing.T) {
 			name:      "array(nullable(uuid))",
 			inputtype: "Array(Nullable(UUID))",
 			inputdata: `['c79a9747-7cef-4b11-8177-380f7ed462a4','\N','00000000-0000-0000-0000-000000000000']`,
-			output:    []string{"c79a9747-7cef-4b11-8177-380f7ed462a4", "00000000-0000-0000-0000-000000000000"},
+			output:    []uuid.UUID{uuid.MustParse("c79a9747-7cef-4b11-8177-380f7ed462a4"), uuid.MustParse("00000000-0000-0000-0000-000000000000")},
 		},
 		{
 			name:      "array of nullable null UInt64",
@@ -600,12 +763,26 @@ func TestParseDataNewNullableArray(t *testing.T) {
 			name:      "nullable(decimal)",
 			inputtype: "Nullable(Decimal(9,4))",
 			inputdata: "123",
-			output:    "123",
+			output:    NewDecimal(big.NewInt(1230000), 4),
 		},
 		{
 			name:      "array(nullable(decimal))",
 			inputtype: "Array(Nullable(Decimal(9,4)))",
 			inputdata: "['123','555.6']",
+			output:    []Decimal{NewDecimal(big.NewInt(1230000), 4), NewDecimal(big.NewInt(5556000), 4)},
+		},
+		{
+			name:      "nullable(decimal) as string",
+			inputtype: "Nullable(Decimal(9,4))",
+			inputopt:  &DataParserOptions{DecimalAsString: true},
+			inputdata: "123",
+			output:    "123",
+		},
+		{
+			name:      "array(nullable(decimal)) as string",
+			inputtype: "Array(Nullable(Decimal(9,4)))",
+			inputopt:  &DataParserOptions{DecimalAsString: true},
+			inputdata: "['123','555.6']",
 			output:    []string{"123", "555.6"},
 		},
 		{
@@ -624,13 +801,26 @@ func TestParseDataNewNullableArray(t *testing.T) {
 			name:      "nullable(uuid)",
 			inputtype: "Nullable(UUID)",
 			inputdata: "c79a9747-7cef-4b11-8177-380f7ed462a4",
-			output:    "c79a9747-7cef-4b11-8177-380f7ed462a4",
+			output:    uuid.MustParse("c79a9747-7cef-4b11-8177-380f7ed462a4"),
+		},
+		{
+			name:      "array(nullable(ipv4))",
+			inputtype: "Array(Nullable(IPv4))",
+			inputdata: `['127.0.0.1','\N','8.8.8.8']`,
+			output:    []netip.Addr{netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("8.8.8.8")},
+		},
+		{
+			name:      "array(nullable(uuid)) as strings",
+			inputtype: "Array(Nullable(UUID))",
+			inputopt:  &DataParserOptions{UUIDAndIPAsString: true},
+			inputdata: "['c79a9747-7cef-4b11-8177-380f7ed462a4']",
+			output:    []string{"c79a9747-7cef-4b11-8177-380f7ed462a4"},
 		},
 		{
 			name:      "array(nullable(uuid))",
 			inputtype: "Array(Nullable(UUID))",
 			inputdata: "['c79a9747-7cef-4b11-8177-380f7ed462a4','00000000-0000-0000-0000-000000000000']",
-			output:    []string{"c79a9747-7cef-4b11-8177-380f7ed462a4", "00000000-0000-0000-0000-000000000000"},
+			output:    []uuid.UUID{uuid.MustParse("c79a9747-7cef-4b11-8177-380f7ed462a4"), uuid.MustParse("00000000-0000-0000-0000-000000000000")},
 		},
 		{
 			name:      "nullable low cardinality string",
@@ -872,3 +1062,12 @@ func TestParseDataNewNullableArray(t *testing.T) {
 		})
 	}
 }
+
+// bigIntFromString parses a decimal integer for the test cases
+func bigIntFromString(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		panic("malformed integer " + s)
+	}
+	return n
+}
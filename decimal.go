@@ -0,0 +1,141 @@
+package clickhouse
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number, its value is unscaled * 10^-scale.
+// The values of Decimal columns are scanned as Decimal, it can be passed as
+// a parameter of any Decimal type.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+// NewDecimal creates a Decimal with the value unscaled * 10^-scale
+func NewDecimal(unscaled *big.Int, scale int32) Decimal {
+	return Decimal{unscaled: new(big.Int).Set(unscaled), scale: scale}
+}
+
+// ParseDecimal parses the text representation of a decimal, e.g. -10.25,
+// the scale is the number of digits after the point
+func ParseDecimal(s string) (Decimal, error) {
+	_, fracPart, _ := strings.Cut(s, ".")
+	n, err := parseDecimal(s, len(fracPart))
+	if err != nil {
+		return Decimal{}, err
+	}
+	return Decimal{unscaled: n, scale: int32(len(fracPart))}, nil
+}
+
+// Unscaled returns the unscaled value of the decimal
+func (d Decimal) Unscaled() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Scale returns the number of digits after the point
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// String formats the decimal like ClickHouse does, without trailing zeros
+func (d Decimal) String() string {
+	return formatDecimal(d.Unscaled().String(), int(d.scale))
+}
+
+// Scan implements sql.Scanner
+func (d *Decimal) Scan(src interface{}) error {
+	var err error
+	switch v := src.(type) {
+	case Decimal:
+		*d = v
+	case string:
+		*d, err = ParseDecimal(v)
+	case []byte:
+		*d, err = ParseDecimal(string(v))
+	case int64:
+		*d = Decimal{unscaled: big.NewInt(v)}
+	case float64:
+		*d, err = ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("clickhouse: can't scan %T into Decimal", src)
+	}
+	return err
+}
+
+// Value implements driver.Valuer
+func (d Decimal) Value() (driver.Value, error) {
+	// the smallest decimal type which keeps all digits
+	digits := len(d.Unscaled().Text(10))
+	if d.Unscaled().Sign() < 0 {
+		digits--
+	}
+	if int(d.scale) > digits {
+		digits = int(d.scale)
+	}
+	bits := 256
+	switch {
+	case digits <= 9:
+		bits = 32
+	case digits <= 18:
+		bits = 64
+	case digits <= 38:
+		bits = 128
+	}
+	return []byte(fmt.Sprintf("toDecimal%d('%s', %d)", bits, d, d.scale)), nil
+}
+
+// decimalSize returns the size in bytes of a decimal of the given precision
+func decimalSize(precision int) int {
+	switch {
+	case precision <= 9:
+		return 4
+	case precision <= 18:
+		return 8
+	case precision <= 38:
+		return 16
+	}
+	return 32
+}
+
+// decimalArgs returns the precision and the scale of Decimal(P, S),
+// Decimal32(S), Decimal64(S), Decimal128(S) or Decimal256(S)
+func decimalArgs(t *TypeDesc) (precision, scale int, err error) {
+	switch t.Name {
+	case "Decimal":
+		// like in ClickHouse, Decimal is Decimal(10, 0) and Decimal(P) is Decimal(P, 0)
+		if len(t.Args) == 0 {
+			return 10, 0, nil
+		}
+		if len(t.Args) > 2 {
+			return 0, 0, fmt.Errorf("too many arguments specified for Decimal")
+		}
+		if precision, err = strconv.Atoi(t.Args[0].Name); err != nil {
+			return 0, 0, fmt.Errorf("malformed precision specified for Decimal: %v", err)
+		}
+		if len(t.Args) == 1 {
+			t = &TypeDesc{Name: t.Name, Args: []*TypeDesc{t.Args[0], {Name: "0"}}}
+		}
+	case "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		if len(t.Args) != 1 {
+			return 0, 0, fmt.Errorf("scale not specified for %s", t.Name)
+		}
+		precision = map[string]int{"Decimal32": 9, "Decimal64": 18, "Decimal128": 38, "Decimal256": 76}[t.Name]
+	default:
+		return 0, 0, fmt.Errorf("%s is not a decimal type", t.Name)
+	}
+	if scale, err = strconv.Atoi(t.Args[len(t.Args)-1].Name); err != nil {
+		return 0, 0, fmt.Errorf("malformed scale specified for %s: %v", t.Name, err)
+	}
+	if precision < 1 || precision > 76 || scale < 0 || scale > precision {
+		return 0, 0, fmt.Errorf("malformed precision %d or scale %d specified for %s", precision, scale, t.Name)
+	}
+	return precision, scale, nil
+}
@@ -0,0 +1,113 @@
+package clickhouse
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	testCases := []struct {
+		repr     string
+		unscaled int64
+		scale    int32
+		str      string
+	}{
+		{"0", 0, 0, "0"},
+		{"-10.25", -1025, 2, "-10.25"},
+		{"1.500", 1500, 3, "1.5"},
+		{"0.0001", 1, 4, "0.0001"},
+	}
+	for _, tc := range testCases {
+		d, err := ParseDecimal(tc.repr)
+		if assert.NoError(t, err, tc.repr) {
+			assert.Equal(t, big.NewInt(tc.unscaled), d.Unscaled(), tc.repr)
+			assert.Equal(t, tc.scale, d.Scale(), tc.repr)
+			assert.Equal(t, tc.str, d.String(), tc.repr)
+		}
+	}
+
+	for _, repr := range []string{"", "1e5", "1.2.3", "abc"} {
+		_, err := ParseDecimal(repr)
+		assert.Error(t, err, repr)
+	}
+}
+
+func TestDecimalScan(t *testing.T) {
+	testCases := []struct {
+		src      interface{}
+		expected string
+	}{
+		{NewDecimal(big.NewInt(5), 1), "0.5"},
+		{"-1.25", "-1.25"},
+		{[]byte("3.14"), "3.14"},
+		{int64(7), "7"},
+		{float64(0.1), "0.1"},
+	}
+	for _, tc := range testCases {
+		var d Decimal
+		if assert.NoError(t, d.Scan(tc.src), "%v", tc.src) {
+			assert.Equal(t, tc.expected, d.String())
+		}
+	}
+
+	var d Decimal
+	assert.Error(t, d.Scan(true))
+	assert.Error(t, d.Scan("x"))
+}
+
+func TestDecimalValue(t *testing.T) {
+	testCases := []struct {
+		value    Decimal
+		expected string
+	}{
+		{Decimal{}, "toDecimal32('0', 0)"},
+		{NewDecimal(big.NewInt(-1025), 2), "toDecimal32('-10.25', 2)"},
+		{NewDecimal(big.NewInt(1), 10), "toDecimal64('0.0000000001', 10)"},
+		{NewDecimal(big.NewInt(1234567890123456789), 0), "toDecimal128('1234567890123456789', 0)"},
+		{NewDecimal(bigIntFromString("-100000000000000000000000000000000000000"), 0), "toDecimal256('-100000000000000000000000000000000000000', 0)"},
+	}
+	for _, tc := range testCases {
+		v, err := tc.value.Value()
+		if assert.NoError(t, err) {
+			assert.Equal(t, []byte(tc.expected), v)
+		}
+	}
+}
+
+func TestDecimalArgs(t *testing.T) {
+	testCases := []struct {
+		typ       string
+		precision int
+		scale     int
+	}{
+		{"Decimal(9, 4)", 9, 4},
+		{"Decimal", 10, 0},
+		{"Decimal(20)", 20, 0},
+		{"Decimal(76, 0)", 76, 0},
+		{"Decimal32(2)", 9, 2},
+		{"Decimal64(4)", 18, 4},
+		{"Decimal128(10)", 38, 10},
+		{"Decimal256(20)", 76, 20},
+	}
+	for _, tc := range testCases {
+		desc, err := ParseTypeDesc(tc.typ)
+		if !assert.NoError(t, err, tc.typ) {
+			continue
+		}
+		precision, scale, err := decimalArgs(desc)
+		if assert.NoError(t, err, tc.typ) {
+			assert.Equal(t, tc.precision, precision, tc.typ)
+			assert.Equal(t, tc.scale, scale, tc.typ)
+		}
+	}
+
+	for _, typ := range []string{"Decimal(77)", "Decimal(9, 2, 1)", "Decimal(x, 2)", "Decimal(9, 10)", "Decimal(77, 2)", "Decimal32", "Decimal32(10)", "Int32"} {
+		desc, err := ParseTypeDesc(typ)
+		if assert.NoError(t, err, typ) {
+			_, _, err = decimalArgs(desc)
+			assert.Error(t, err, typ)
+		}
+	}
+}
@@ -1,15 +1,23 @@
 package clickhouse
 
 import (
+	"bytes"
 	"database/sql/driver"
 	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
 	"reflect"
+	"sort"
 	"strconv"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 var (
-	textEncode encoder = new(textEncoder)
+	textEncode  encoder = new(textEncoder)
+	paramEncode encoder = new(paramEncoder)
 )
 
 type encoder interface {
@@ -35,6 +43,31 @@ func (e *textEncoder) Encode(value driver.Value) ([]byte, error) {
 		return v, nil
 	case time.Time:
 		return []byte(e.encode(v)), nil
+	case *big.Int:
+		if v == nil {
+			return []byte("NULL"), nil
+		}
+		return []byte(v.String()), nil
+	case Decimal:
+		dv, err := v.Value()
+		if err != nil {
+			return nil, err
+		}
+		return dv.([]byte), nil
+	case uuid.UUID:
+		return []byte(e.encode(v.String())), nil
+	case netip.Addr:
+		return []byte(e.encode(v.String())), nil
+	case netip.Prefix:
+		return []byte(e.encode(v.String())), nil
+	case Variant:
+		return e.Encode(v.Value)
+	case jsonObject:
+		b, err := marshalJSON(v.v)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(e.encode(string(b))), nil
 	}
 
 	vv := reflect.ValueOf(value)
@@ -198,3 +231,195 @@ func (e *textEncoder) encodeMap(m reflect.Value) ([]byte, error) {
 	}
 	return append(res, ')'), nil
 }
+
+// paramEncoder encodes values of query parameters ({name:Type} in the query)
+// in the text format parsed by the server
+type paramEncoder struct {
+}
+
+// Encode encodes driver value into the value of a query parameter.
+// Unlike textEncoder, the strings are not quoted at the top level
+// and NULL is encoded as \N.
+func (e *paramEncoder) Encode(value driver.Value) ([]byte, error) {
+	return e.encode(value, false)
+}
+
+func (e *paramEncoder) encode(value interface{}, nested bool) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		if nested {
+			return []byte("NULL"), nil
+		}
+		return []byte(`\N`), nil
+	case string:
+		if nested {
+			return []byte(quote(escape(v))), nil
+		}
+		return []byte(paramEscaper.Replace(v)), nil
+	case []byte:
+		return e.encode(string(v), nested)
+	case time.Time:
+		if v.Nanosecond() == 0 {
+			return e.encode(v.Format(timeFormat), nested)
+		}
+		return e.encode(v.Format(dateTime64Format), nested)
+	case date:
+		return e.encode(time.Time(v).Format(dateFormat), nested)
+	case array:
+		return e.encodeArray(reflect.ValueOf(v.v))
+	case tuple:
+		return e.encodeTuple(reflect.ValueOf(v.v))
+	case mapp:
+		return e.encodeMap(reflect.ValueOf(v.v))
+	case bigUint64:
+		return []byte(strconv.FormatUint(uint64(v), 10)), nil
+	case *big.Int:
+		if v == nil {
+			return e.encode(nil, nested)
+		}
+		return []byte(v.String()), nil
+	case bigInt:
+		vv, err := v.Value()
+		if err != nil || vv == nil {
+			return e.encode(nil, nested)
+		}
+		return vv.([]byte), nil
+	case decimal:
+		return []byte(v.digits()), nil
+	case Decimal:
+		return []byte(v.String()), nil
+	case ip:
+		return e.encode(net.IP(v).String(), nested)
+	case netip.Addr:
+		return e.encode(v.String(), nested)
+	case netip.Prefix:
+		return e.encode(v.String(), nested)
+	case Variant:
+		return e.encode(v.Value, nested)
+	case Point:
+		return e.encodeTuple(reflect.ValueOf(v))
+	case Ring, Polygon, MultiPolygon:
+		return e.encodeArray(reflect.ValueOf(v))
+	case jsonObject:
+		b, err := marshalJSON(v.v)
+		if err != nil {
+			return nil, err
+		}
+		return e.encode(string(b), nested)
+	case driver.Valuer:
+		vv, err := v.Value()
+		if err != nil {
+			return nil, err
+		}
+		return e.encode(vv, nested)
+	}
+
+	vv := reflect.ValueOf(value)
+	switch vv.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if vv.IsNil() {
+			return e.encode(nil, nested)
+		}
+		return e.encode(vv.Elem().Interface(), nested)
+	case reflect.String:
+		return e.encode(vv.String(), nested)
+	case reflect.Slice, reflect.Array:
+		if vv.Type().Elem().Kind() == reflect.Uint8 && vv.Kind() == reflect.Slice {
+			return e.encode(string(vv.Bytes()), nested)
+		}
+		return e.encodeArray(vv)
+	case reflect.Map:
+		return e.encodeMap(vv)
+	case reflect.Struct:
+		return e.encodeTuple(vv)
+	}
+	return []byte(new(textEncoder).encode(value)), nil
+}
+
+// encodeArray encodes a go slice or array as [v1,v2]
+func (e *paramEncoder) encodeArray(value reflect.Value) ([]byte, error) {
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected array or slice, got %s", value.Kind())
+	}
+	res := []byte{'['}
+	for i := 0; i < value.Len(); i++ {
+		if i > 0 {
+			res = append(res, ',')
+		}
+		tmp, err := e.encode(value.Index(i).Interface(), true)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, tmp...)
+	}
+	return append(res, ']'), nil
+}
+
+// encodeTuple encodes a go struct as (v1,v2)
+func (e *paramEncoder) encodeTuple(value reflect.Value) ([]byte, error) {
+	fields, err := e.encodeTuplePart(value, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := []byte{'('}
+	res = append(res, bytes.Join(fields, []byte{','})...)
+	return append(res, ')'), nil
+}
+
+func (e *paramEncoder) encodeTuplePart(value reflect.Value, fields [][]byte) ([][]byte, error) {
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %s", value.Kind())
+	}
+	t := value.Type()
+	for i := 0; i < value.NumField(); i++ {
+		ft := t.Field(i)
+		fv := value.Field(i)
+		if ft.Anonymous {
+			var err error
+			if fields, err = e.encodeTuplePart(fv, fields); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !fv.CanInterface() {
+			continue
+		}
+		b, err := e.encode(fv.Interface(), true)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, b)
+	}
+	return fields, nil
+}
+
+// encodeMap encodes a go map as {k1:v1,k2:v2}, the keys are sorted
+// to make the value of the parameter stable
+func (e *paramEncoder) encodeMap(m reflect.Value) ([]byte, error) {
+	if m.Kind() != reflect.Map {
+		return nil, fmt.Errorf("expected map, got %s", m.Kind())
+	}
+	pairs := make([][]byte, 0, m.Len())
+	iter := m.MapRange()
+	for iter.Next() {
+		key, err := e.encode(iter.Key().Interface(), true)
+		if err != nil {
+			return nil, err
+		}
+		value, err := e.encode(iter.Value().Interface(), true)
+		if err != nil {
+			return nil, err
+		}
+		pair := append(key, ':')
+		pairs = append(pairs, append(pair, value...))
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return bytes.Compare(pairs[i], pairs[j]) < 0
+	})
+	res := []byte{'{'}
+	res = append(res, bytes.Join(pairs, []byte{','})...)
+	return append(res, '}'), nil
+}
@@ -1,10 +1,14 @@
 package clickhouse
 
 import (
+	"math/big"
+	"net"
+	"net/netip"
 	"regexp"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -56,10 +60,24 @@ func TestTextEncoder(t *testing.T) {
 		{Array([]interface{}{Array([]int8{1})}), "[[1]]"},
 		{[][]int16{{1}}, "[[1]]"},
 		{[]int16(nil), "[]"},
+		{big.NewInt(-1), "-1"},
+		{[]*big.Int{big.NewInt(1)}, "[1]"},
+		{[]uuid.UUID{uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")}, "['123e4567-e89b-12d3-a456-426614174000']"},
+		{Tuple(struct {
+			A netip.Addr
+			B netip.Prefix
+		}{netip.MustParseAddr("::1"), netip.MustParsePrefix("10.0.0.0/8")}), "('::1','10.0.0.0/8')"},
+		{NewDecimal(big.NewInt(-1050), 2), "toDecimal32('-10.5', 2)"},
 		{(*int16)(nil), "NULL"},
 		{Tuple(TestTuple{A: 1, B: "2", TestEmbedTuple: TestEmbedTuple{C: true, private: 5}}), "(1,'2',1)"},
 		{Tuple(TestNestedTuple{A: &TestTuple{A: 1, B: "2", TestEmbedTuple: TestEmbedTuple{C: true}}, D: 4}), "((1,'2',1),4)"},
 		{[]TestTuple{{A: 1, B: "2", TestEmbedTuple: TestEmbedTuple{C: true, private: 5}}}, "[(1,'2',1)]"},
+		{JSON(map[string]string{"a": "it's"}), `'{"a":"it\'s"}'`},
+		{JSON(`{"a":1}`), `'{"a":1}'`},
+		{Variant{Type: "UInt64", Value: uint64(1)}, "1"},
+		{Point{1.5, -2}, "(1.5,-2)"},
+		{Polygon{{{0, 0}, {1, 0}}, {}}, "[[(0,0),(1,0)],[]]"},
+		{[]Variant{{Value: "a"}, {}}, "['a',NULL]"},
 	}
 
 	enc := new(textEncoder)
@@ -89,3 +107,60 @@ func TestTextEncoder_Map(t *testing.T) {
 		}
 	}
 }
+
+func TestParamEncoder(t *testing.T) {
+	dt := time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC)
+	dt64 := time.Date(2011, 3, 6, 6, 20, 0, 123000000, time.UTC)
+	testCases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, `\N`},
+		{(*int16)(nil), `\N`},
+		{true, "1"},
+		{int64(-1), "-1"},
+		{uint64(1), "1"},
+		{float64(1.5), "1.5"},
+		{"hello", "hello"},
+		{"it's\t\\\n", `it's\t\\\n`},
+		{[]byte("hello"), "hello"},
+		{dt, "2011-03-06 06:20:00"},
+		{dt64, "2011-03-06 06:20:00.123"},
+		{Date(dt), "2011-03-06"},
+		{UInt64(1 << 63), "9223372036854775808"},
+		{big.NewInt(-5), "-5"},
+		{UInt128(big.NewInt(5)), "5"},
+		{[]*big.Int{big.NewInt(1), nil}, "[1,NULL]"},
+		{Decimal32(10.1111, 4), "10.1111"},
+		{NewDecimal(big.NewInt(-1050), 2), "-10.5"},
+		{IP(net.ParseIP("127.0.0.1")), "127.0.0.1"},
+		{netip.MustParseAddr("127.0.0.1"), "127.0.0.1"},
+		{netip.MustParsePrefix("2001:db8::/32"), "2001:db8::/32"},
+		{uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"), "123e4567-e89b-12d3-a456-426614174000"},
+		{[]netip.Addr{netip.MustParseAddr("::1")}, "['::1']"},
+		{[]int32{1, 2}, "[1,2]"},
+		{[]int32{}, "[]"},
+		{Array([]string{"a", "it's"}), `['a','it\'s']`},
+		{[][]int16{{1}, {2, 3}}, "[[1],[2,3]]"},
+		{[]*int8{nil}, "[NULL]"},
+		{[]time.Time{dt}, "['2011-03-06 06:20:00']"},
+		{map[string]int{"b": 2, "a": 1}, "{'a':1,'b':2}"},
+		{Map(map[int32][]string{1: {"x"}}), "{1:['x']}"},
+		{Tuple(TestTuple{A: 1, B: "2", TestEmbedTuple: TestEmbedTuple{C: true, private: 5}}), "(1,'2',1)"},
+		{TestNestedTuple{A: &TestTuple{A: 1, B: "2"}, D: 4}, "((1,'2',0),4)"},
+		{JSON(map[string]string{"a": "b\tc"}), `{"a":"b\\tc"}`},
+		{Variant{Type: "String", Value: "a\tb"}, `a\tb`},
+		{[]Variant{{Value: 1}, {}}, "[1,NULL]"},
+		{Point{1.5, -2}, "(1.5,-2)"},
+		{MultiPolygon{{{{0, 0}, {1, 0}}}}, "[[[(0,0),(1,0)]]]"},
+		{[]Point{{1, 2}}, "[(1,2)]"},
+	}
+
+	enc := new(paramEncoder)
+	for _, tc := range testCases {
+		v, err := enc.Encode(tc.value)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.expected, string(v))
+		}
+	}
+}
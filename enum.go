@@ -0,0 +1,80 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// enumCode scans the name of an enum value into its code
+type enumCode struct {
+	values map[string]int16
+	dest   interface{}
+	err    error
+}
+
+// EnumCode returns a sql.Scanner which scans the values of an Enum8 or Enum16
+// column into their codes instead of their names. The type is the type of
+// the column, e.g. from sql.ColumnType.DatabaseTypeName, and dest is a pointer
+// to an integer, or a pointer to a pointer to an integer for Nullable columns:
+//
+//	var code int8
+//	err := rows.Scan(clickhouse.EnumCode(columnTypes[0].DatabaseTypeName(), &code))
+func EnumCode(typ string, dest interface{}) sql.Scanner {
+	desc, err := ParseTypeDesc(typ)
+	if err != nil {
+		return &enumCode{err: err}
+	}
+	if desc = enumType(desc); desc == nil {
+		return &enumCode{err: fmt.Errorf("clickhouse: %s is not an Enum", typ)}
+	}
+	return &enumCode{values: desc.Enum, dest: dest}
+}
+
+// Scan implements sql.Scanner
+func (e *enumCode) Scan(src interface{}) error {
+	if e.err != nil {
+		return e.err
+	}
+	v := reflect.ValueOf(e.dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("clickhouse: expected a pointer to an integer, got %T", e.dest)
+	}
+	v = v.Elem()
+	if src == nil {
+		if v.Kind() != reflect.Ptr {
+			return fmt.Errorf("clickhouse: can't scan NULL into %T", e.dest)
+		}
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	name, ok := stringValue(src)
+	if !ok {
+		return fmt.Errorf("clickhouse: unexpected type %T of an enum value", src)
+	}
+	code, ok := e.values[name]
+	if !ok {
+		return fmt.Errorf("clickhouse: unknown enum name '%s'", name)
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	switch {
+	case isIntegerKind(v.Kind()) && v.Kind() <= reflect.Int64:
+		if v.OverflowInt(int64(code)) {
+			return fmt.Errorf("clickhouse: enum value %d overflows %s", code, v.Type())
+		}
+		v.SetInt(int64(code))
+	case isIntegerKind(v.Kind()):
+		if code < 0 || v.OverflowUint(uint64(code)) {
+			return fmt.Errorf("clickhouse: enum value %d overflows %s", code, v.Type())
+		}
+		v.SetUint(uint64(code))
+	default:
+		return fmt.Errorf("clickhouse: expected a pointer to an integer, got %T", e.dest)
+	}
+	return nil
+}
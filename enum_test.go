@@ -0,0 +1,191 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEnumServer(t *testing.T) *sql.DB {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("level\tstate\n" +
+			`Enum8(\'debug\' = -1, \'info\' = 1)` + "\t" + `Nullable(Enum16(\'off\' = 0, \'on\' = 1000))` + "\n" +
+			"debug\ton\n" +
+			"info\t\\N\n"))
+	}))
+	t.Cleanup(srv.Close)
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestEnumCode(t *testing.T) {
+	db := newEnumServer(t)
+	rows, err := db.Query("SELECT level, state FROM t")
+	require.NoError(t, err)
+	defer rows.Close()
+	columnTypes, err := rows.ColumnTypes()
+	require.NoError(t, err)
+
+	var (
+		level     int8
+		levelName string
+		state     *uint16
+	)
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(EnumCode(columnTypes[0].DatabaseTypeName(), &level), EnumCode(columnTypes[1].DatabaseTypeName(), &state)))
+	assert.Equal(t, int8(-1), level)
+	if assert.NotNil(t, state) {
+		assert.Equal(t, uint16(1000), *state)
+	}
+
+	require.True(t, rows.Next())
+	require.NoError(t, rows.Scan(&levelName, EnumCode(columnTypes[1].DatabaseTypeName(), &state)))
+	assert.Equal(t, "info", levelName)
+	assert.Nil(t, state)
+	require.NoError(t, rows.Close())
+
+	typ := "Enum16('a' = -1, 'b' = 300)"
+	var (
+		i8  int8
+		u16 uint16
+		s   string
+	)
+	testCases := []struct {
+		scanner sql.Scanner
+		src     interface{}
+		err     string
+	}{
+		{EnumCode(typ, &i8), "c", "clickhouse: unknown enum name 'c'"},
+		{EnumCode(typ, &i8), "b", "clickhouse: enum value 300 overflows int8"},
+		{EnumCode(typ, &u16), "a", "clickhouse: enum value -1 overflows uint16"},
+		{EnumCode(typ, &u16), nil, "clickhouse: can't scan NULL into *uint16"},
+		{EnumCode(typ, &s), "a", "clickhouse: expected a pointer to an integer, got *string"},
+		{EnumCode(typ, u16), "a", "clickhouse: expected a pointer to an integer, got uint16"},
+		{EnumCode("String", &i8), "a", "clickhouse: String is not an Enum"},
+	}
+	for _, tc := range testCases {
+		assert.EqualError(t, tc.scanner.Scan(tc.src), tc.err)
+	}
+	assert.Error(t, EnumCode("Enum8('a')", &i8).Scan("a"))
+}
+
+func TestSelectEnum(t *testing.T) {
+	db := newEnumServer(t)
+	type event struct {
+		Level int16
+		State *string
+	}
+	events, err := Select[event](context.Background(), db, "SELECT level, state FROM t")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int16(-1), events[0].Level)
+	if assert.NotNil(t, events[0].State) {
+		assert.Equal(t, "on", *events[0].State)
+	}
+	assert.Equal(t, int16(1), events[1].Level)
+	assert.Nil(t, events[1].State)
+}
+
+func TestBatchEnum(t *testing.T) {
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			_, _ = w.Write([]byte("name\ttype\tdefault_type\nString\tString\tString\n" +
+				"id\tInt64\t\nlevel\t" + `Enum8(\'debug\' = -1, \'info\' = 1)` + "\t\n" +
+				"state\t" + `Nullable(Enum16(\'off\' = 0, \'on\' = 1000))` + "\t\n"))
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+
+	require.NoError(t, sqlConn.Raw(func(driverConn interface{}) error {
+		batch, err := driverConn.(Conn).PrepareBatch(context.Background(), "INSERT INTO data")
+		require.NoError(t, err)
+		assert.NoError(t, batch.Append(1, "debug", nil))
+		assert.NoError(t, batch.Append(2, 1, "on"))
+		assert.EqualError(t, batch.Append(3, "warning", nil),
+			"clickhouse: invalid value of the column level: unknown enum name 'warning'")
+		assert.EqualError(t, batch.Append(4, "info", 1),
+			"clickhouse: invalid value of the column state: unknown enum value 1")
+		return batch.Send()
+	}))
+	assert.Equal(t, []string{"DESCRIBE TABLE data", "INSERT INTO data VALUES (1, 'debug', NULL), (2, 1, 'on')"}, srv.received())
+}
+
+func TestStmtBatchEnum(t *testing.T) {
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			_, _ = w.Write([]byte("name\ttype\tdefault_type\nString\tString\tString\n" +
+				"id\tInt64\t\nlevel\t" + `Enum8(\'debug\' = -1, \'info\' = 1)` + "\t\n"))
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the rows of a prepared statement are checked like the rows of a Batch
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	st, err := tx.Prepare("INSERT INTO data VALUES (?, ?)")
+	require.NoError(t, err)
+	_, err = st.Exec(1, "debug")
+	assert.NoError(t, err)
+	_, err = st.Exec(2, "warning")
+	assert.EqualError(t, err, "clickhouse: invalid value of the column level: unknown enum name 'warning'")
+	_, err = st.Exec(3, 1)
+	assert.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"DESCRIBE TABLE data", "INSERT INTO data VALUES(1, 'debug'), (3, 1)"}, srv.received())
+}
+
+func TestStmtBatchEnumListedColumns(t *testing.T) {
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			_, _ = w.Write([]byte("name\ttype\tdefault_type\nString\tString\tString\n" +
+				"id\tInt64\t\nlevel\t" + `Enum8(\'debug\' = -1, \'info\' = 1)` + "\t\n"))
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the values are checked by the names of the listed columns
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	st, err := tx.Prepare("INSERT INTO data (level, id) VALUES (?, ?)")
+	require.NoError(t, err)
+	_, err = st.Exec("info", 1)
+	assert.NoError(t, err)
+	_, err = st.Exec("warning", 2)
+	assert.EqualError(t, err, "clickhouse: invalid value of the column level: unknown enum name 'warning'")
+	require.NoError(t, tx.Commit())
+
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+	require.NoError(t, sqlConn.Raw(func(driverConn interface{}) error {
+		batch, err := driverConn.(Conn).PrepareBatch(context.Background(), "INSERT INTO data (level, id)")
+		require.NoError(t, err)
+		assert.NoError(t, batch.Append("debug", 3))
+		assert.EqualError(t, batch.Append(2, 4), "clickhouse: invalid value of the column level: unknown enum value 2")
+		return batch.Send()
+	}))
+	assert.Equal(t, []string{
+		"DESCRIBE TABLE data", "INSERT INTO data (level, id) VALUES('info', 1)",
+		"DESCRIBE TABLE data", "INSERT INTO data (level, id) VALUES ('debug', 3)",
+	}, srv.received())
+}
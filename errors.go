@@ -5,20 +5,34 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 // Various errors the driver might return. Can change between driver versions.
 var (
-	ErrPlaceholderCount  = errors.New("clickhouse: wrong placeholder count")
+	ErrPlaceholderCount = errors.New("clickhouse: wrong placeholder count")
+	// ErrNameParams is not returned any more, named arguments are sent as query parameters.
+	//
+	// Deprecated: use ErrBatchNameParams, which is returned for named arguments of batch inserts.
 	ErrNameParams        = errors.New("clickhouse: driver does not support the use of Named Parameters")
+	ErrBatchNameParams   = errors.New("clickhouse: named parameters are not supported in batch inserts")
 	ErrMalformed         = errors.New("clickhouse: response is malformed")
 	ErrTransportNil      = errors.New("clickhouse: transport must be set")
 	ErrIncorrectResponse = errors.New("clickhouse: response must contain 'Ok.'")
 	ErrNoLastInsertID    = errors.New("no LastInsertId available")
 	ErrNoRowsAffected    = errors.New("no RowsAffected available")
+	ErrBatchDone         = errors.New("clickhouse: batch has already been sent or aborted")
+	ErrInserterClosed    = errors.New("clickhouse: inserter is closed")
+	ErrInserterFull      = errors.New("clickhouse: inserter queue is full")
 )
 
-var errorRe = regexp.MustCompile(`(?s)Code: (\d+)[,\.].+DB::Exception: (.+)[,\.] .*`)
+var (
+	errorRe          = regexp.MustCompile(`(?s)Code: (\d+)[,\.].+DB::Exception: (.+)[,\.] .*`)
+	exceptionStartRe = regexp.MustCompile(`Code: \d+[,\.] `)
+)
+
+// exceptionMarker is a part of any exception message written by ClickHouse
+const exceptionMarker = "DB::Exception:"
 
 // Error contains parsed information about server error
 type Error struct {
@@ -39,3 +53,26 @@ func newError(resp string) error {
 	code, _ := strconv.ParseInt(tokens[1], 10, 64)
 	return &Error{Code: int(code), Message: tokens[2]}
 }
+
+// exceptionTrailer returns the error written by ClickHouse at the end of
+// the response, when the query failed after a part of the result had been sent
+func exceptionTrailer(data string) error {
+	start := exceptionStart(data)
+	if start < 0 {
+		return nil
+	}
+	return newError(data[start:])
+}
+
+// exceptionStart returns the position of the last exception in the data or -1
+func exceptionStart(data string) int {
+	i := strings.LastIndex(data, exceptionMarker)
+	if i < 0 {
+		return -1
+	}
+	starts := exceptionStartRe.FindAllStringIndex(data[:i], -1)
+	if len(starts) == 0 {
+		return -1
+	}
+	return starts[len(starts)-1][0]
+}
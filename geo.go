@@ -0,0 +1,492 @@
+package clickhouse
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Point is a value of Point columns, X is the longitude and Y is the latitude for geographic coordinates
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Ring is a value of Ring columns, a polygon without holes
+type Ring []Point
+
+// Polygon is a value of Polygon columns, the first ring is the outer boundary
+// and the others are the holes
+type Polygon []Ring
+
+// MultiPolygon is a value of MultiPolygon columns
+type MultiPolygon []Polygon
+
+var (
+	reflectTypePoint        = reflect.TypeOf(Point{})
+	reflectTypeRing         = reflect.TypeOf(Ring{})
+	reflectTypePolygon      = reflect.TypeOf(Polygon{})
+	reflectTypeMultiPolygon = reflect.TypeOf(MultiPolygon{})
+
+	// geoTypes are the Go types of the values of the geo columns
+	geoTypes = map[string]reflect.Type{
+		"Point":        reflectTypePoint,
+		"Ring":         reflectTypeRing,
+		"Polygon":      reflectTypePolygon,
+		"MultiPolygon": reflectTypeMultiPolygon,
+	}
+)
+
+// geoTypeDesc returns the type the geo type is an alias of
+func geoTypeDesc(name string) *TypeDesc {
+	switch name {
+	case "Point":
+		return &TypeDesc{Name: "Tuple", Args: []*TypeDesc{{Name: "Float64"}, {Name: "Float64"}}}
+	case "Ring":
+		return &TypeDesc{Name: "Array", Args: []*TypeDesc{{Name: "Point"}}}
+	case "Polygon":
+		return &TypeDesc{Name: "Array", Args: []*TypeDesc{{Name: "Ring"}}}
+	}
+	return &TypeDesc{Name: "Array", Args: []*TypeDesc{{Name: "Polygon"}}}
+}
+
+// geoValue converts the value parsed as the type the geo type is an alias of
+// into the Go type of the geo type, the elements of arrays are converted already
+func geoValue(typ reflect.Type, v driver.Value) driver.Value {
+	rv := reflect.ValueOf(v)
+	if typ == reflectTypePoint {
+		return Point{X: rv.Field(0).Float(), Y: rv.Field(1).Float()}
+	}
+	return rv.Convert(typ).Interface()
+}
+
+// geoParser parses the values of geo columns
+type geoParser struct {
+	DataParser
+	typ reflect.Type
+}
+
+func (p *geoParser) Parse(s io.RuneScanner) (driver.Value, error) {
+	v, err := p.DataParser.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return geoValue(p.typ, v), nil
+}
+
+func (p *geoParser) Type() reflect.Type {
+	return p.typ
+}
+
+// Scan implements sql.Scanner, the value may also be a WKT string
+func (p *Point) Scan(src interface{}) error {
+	return scanGeo(p, src)
+}
+
+// Scan implements sql.Scanner, the value may also be a WKT string
+func (r *Ring) Scan(src interface{}) error {
+	return scanGeo(r, src)
+}
+
+// Scan implements sql.Scanner, the value may also be a WKT string
+func (p *Polygon) Scan(src interface{}) error {
+	return scanGeo(p, src)
+}
+
+// Scan implements sql.Scanner, the value may also be a WKT string
+func (m *MultiPolygon) Scan(src interface{}) error {
+	return scanGeo(m, src)
+}
+
+func scanGeo[T Point | Ring | Polygon | MultiPolygon](dest *T, src interface{}) error {
+	switch x := src.(type) {
+	case T:
+		*dest = x
+		return nil
+	case string:
+		return unmarshalWKT(dest, x)
+	case []byte:
+		return unmarshalWKT(dest, string(x))
+	}
+	return fmt.Errorf("clickhouse: can't scan %T into %T", src, *dest)
+}
+
+func unmarshalWKT[T Point | Ring | Polygon | MultiPolygon](dest *T, s string) error {
+	v, err := ParseWKT(s)
+	if err != nil {
+		return fmt.Errorf("clickhouse: %w", err)
+	}
+	if p, ok := v.(Polygon); ok && len(p) == 1 {
+		// rings are written as polygons without holes
+		if _, ok := interface{}(dest).(*Ring); ok {
+			v = p[0]
+		}
+	}
+	x, ok := v.(T)
+	if !ok {
+		return fmt.Errorf("clickhouse: can't scan %T into %T", v, *dest)
+	}
+	*dest = x
+	return nil
+}
+
+// Value implements driver.Valuer
+func (p Point) Value() (driver.Value, error) {
+	return textEncode.Encode(p)
+}
+
+// Value implements driver.Valuer
+func (r Ring) Value() (driver.Value, error) {
+	return textEncode.Encode(r)
+}
+
+// Value implements driver.Valuer
+func (p Polygon) Value() (driver.Value, error) {
+	return textEncode.Encode(p)
+}
+
+// Value implements driver.Valuer
+func (m MultiPolygon) Value() (driver.Value, error) {
+	return textEncode.Encode(m)
+}
+
+// String formats the point as WKT like the wkt function of ClickHouse: POINT(1 2)
+func (p Point) String() string {
+	return "POINT" + string(appendWKTPoint([]byte{'('}, p)) + ")"
+}
+
+// String formats the ring as WKT polygon like the wkt function of ClickHouse: POLYGON((0 0,1 0,1 1))
+func (r Ring) String() string {
+	return "POLYGON(" + string(appendWKTRing(nil, r)) + ")"
+}
+
+// String formats the polygon as WKT like the wkt function of ClickHouse: POLYGON((0 0,1 0,1 1),(...))
+func (p Polygon) String() string {
+	return "POLYGON" + string(appendWKTPolygon(nil, p))
+}
+
+// String formats the multipolygon as WKT like the wkt function of ClickHouse: MULTIPOLYGON(((0 0,1 0,1 1)),(...))
+func (m MultiPolygon) String() string {
+	b := []byte("MULTIPOLYGON(")
+	for i, p := range m {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendWKTPolygon(b, p)
+	}
+	return string(append(b, ')'))
+}
+
+func appendWKTPoint(b []byte, p Point) []byte {
+	b = strconv.AppendFloat(b, p.X, 'f', -1, 64)
+	b = append(b, ' ')
+	return strconv.AppendFloat(b, p.Y, 'f', -1, 64)
+}
+
+func appendWKTRing(b []byte, r Ring) []byte {
+	b = append(b, '(')
+	for i, p := range r {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendWKTPoint(b, p)
+	}
+	return append(b, ')')
+}
+
+func appendWKTPolygon(b []byte, p Polygon) []byte {
+	b = append(b, '(')
+	for i, r := range p {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendWKTRing(b, r)
+	}
+	return append(b, ')')
+}
+
+// ParseWKT parses a POINT, POLYGON or MULTIPOLYGON in WKT format into
+// Point, Polygon or MultiPolygon. Polygons without holes may be scanned into Ring.
+func ParseWKT(s string) (interface{}, error) {
+	r := &wktReader{s: s}
+	var (
+		v   interface{}
+		err error
+	)
+	tag := strings.ToUpper(r.readTag())
+	switch tag {
+	case "POINT":
+		var p Point
+		if err = r.expect('('); err == nil {
+			if p, err = r.readPoint(); err == nil {
+				err = r.expect(')')
+			}
+		}
+		v = p
+	case "POLYGON":
+		v, err = r.readPolygon()
+	case "MULTIPOLYGON":
+		var m MultiPolygon
+		err = r.readList(func() error {
+			p, err := r.readPolygon()
+			m = append(m, p)
+			return err
+		})
+		v = m
+	default:
+		return nil, fmt.Errorf("unsupported WKT geometry %q", tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("malformed WKT %s: %v", tag, err)
+	}
+	if r.skipSpaces(); r.pos < len(r.s) {
+		return nil, fmt.Errorf("malformed WKT %s: unexpected %q after the end", tag, r.s[r.pos:])
+	}
+	return v, nil
+}
+
+// wktReader reads the coordinates of WKT geometries
+type wktReader struct {
+	s   string
+	pos int
+}
+
+func (r *wktReader) skipSpaces() {
+	for r.pos < len(r.s) && strings.IndexByte(" \t\r\n", r.s[r.pos]) >= 0 {
+		r.pos++
+	}
+}
+
+func (r *wktReader) readTag() string {
+	r.skipSpaces()
+	start := r.pos
+	for r.pos < len(r.s) && (r.s[r.pos] >= 'a' && r.s[r.pos] <= 'z' || r.s[r.pos] >= 'A' && r.s[r.pos] <= 'Z') {
+		r.pos++
+	}
+	return r.s[start:r.pos]
+}
+
+func (r *wktReader) expect(c byte) error {
+	r.skipSpaces()
+	if r.pos >= len(r.s) {
+		return fmt.Errorf("expected '%c', got the end", c)
+	}
+	if r.s[r.pos] != c {
+		return fmt.Errorf("expected '%c', got '%c'", c, r.s[r.pos])
+	}
+	r.pos++
+	return nil
+}
+
+// readList reads the comma separated elements in parentheses
+func (r *wktReader) readList(readElem func() error) error {
+	if err := r.expect('('); err != nil {
+		return err
+	}
+	for {
+		if err := readElem(); err != nil {
+			return err
+		}
+		if r.skipSpaces(); r.pos < len(r.s) && r.s[r.pos] == ',' {
+			r.pos++
+			continue
+		}
+		return r.expect(')')
+	}
+}
+
+func (r *wktReader) readNumber() (float64, error) {
+	r.skipSpaces()
+	start := r.pos
+	for r.pos < len(r.s) && strings.IndexByte("0123456789+-.eE", r.s[r.pos]) >= 0 {
+		r.pos++
+	}
+	return strconv.ParseFloat(r.s[start:r.pos], 64)
+}
+
+func (r *wktReader) readPoint() (p Point, err error) {
+	if p.X, err = r.readNumber(); err != nil {
+		return p, err
+	}
+	p.Y, err = r.readNumber()
+	return p, err
+}
+
+func (r *wktReader) readRing() (Ring, error) {
+	var ring Ring
+	return ring, r.readList(func() error {
+		p, err := r.readPoint()
+		ring = append(ring, p)
+		return err
+	})
+}
+
+func (r *wktReader) readPolygon() (Polygon, error) {
+	var polygon Polygon
+	return polygon, r.readList(func() error {
+		ring, err := r.readRing()
+		polygon = append(polygon, ring)
+		return err
+	})
+}
+
+// geoJSON is a GeoJSON geometry object
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// MarshalJSON implements json.Marshaler, the point is encoded as GeoJSON Point
+func (p Point) MarshalJSON() ([]byte, error) {
+	return marshalGeoJSON("Point", geoCoordinates(p))
+}
+
+// MarshalJSON implements json.Marshaler, the ring is encoded as GeoJSON Polygon
+func (r Ring) MarshalJSON() ([]byte, error) {
+	return marshalGeoJSON("Polygon", [][][]float64{geoCoordinates(r).([][]float64)})
+}
+
+// MarshalJSON implements json.Marshaler, the polygon is encoded as GeoJSON Polygon
+func (p Polygon) MarshalJSON() ([]byte, error) {
+	return marshalGeoJSON("Polygon", geoCoordinates(p))
+}
+
+// MarshalJSON implements json.Marshaler, the multipolygon is encoded as GeoJSON MultiPolygon
+func (m MultiPolygon) MarshalJSON() ([]byte, error) {
+	return marshalGeoJSON("MultiPolygon", geoCoordinates(m))
+}
+
+func marshalGeoJSON(typ string, coordinates interface{}) ([]byte, error) {
+	b, err := json.Marshal(coordinates)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(geoJSON{Type: typ, Coordinates: b})
+}
+
+// geoCoordinates returns the GeoJSON coordinates of a geo value
+func geoCoordinates(v interface{}) interface{} {
+	switch x := v.(type) {
+	case Point:
+		return []float64{x.X, x.Y}
+	case Ring:
+		coordinates := make([][]float64, len(x))
+		for i, p := range x {
+			coordinates[i] = geoCoordinates(p).([]float64)
+		}
+		return coordinates
+	case Polygon:
+		coordinates := make([][][]float64, len(x))
+		for i, r := range x {
+			coordinates[i] = geoCoordinates(r).([][]float64)
+		}
+		return coordinates
+	case MultiPolygon:
+		coordinates := make([][][][]float64, len(x))
+		for i, p := range x {
+			coordinates[i] = geoCoordinates(p).([][][]float64)
+		}
+		return coordinates
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for GeoJSON Point
+func (p *Point) UnmarshalJSON(data []byte) error {
+	var coordinates []float64
+	if err := unmarshalGeoJSON(data, "Point", &coordinates); err != nil {
+		return err
+	}
+	point, err := pointCoordinates(coordinates)
+	if err != nil {
+		return err
+	}
+	*p = point
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for GeoJSON Polygon without holes
+func (r *Ring) UnmarshalJSON(data []byte) error {
+	var p Polygon
+	if err := p.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	if len(p) != 1 {
+		return fmt.Errorf("clickhouse: GeoJSON Polygon of %d rings can't be unmarshaled into Ring", len(p))
+	}
+	*r = p[0]
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for GeoJSON Polygon
+func (p *Polygon) UnmarshalJSON(data []byte) error {
+	var coordinates [][][]float64
+	if err := unmarshalGeoJSON(data, "Polygon", &coordinates); err != nil {
+		return err
+	}
+	polygon, err := polygonCoordinates(coordinates)
+	if err != nil {
+		return err
+	}
+	*p = polygon
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for GeoJSON MultiPolygon
+func (m *MultiPolygon) UnmarshalJSON(data []byte) error {
+	var coordinates [][][][]float64
+	if err := unmarshalGeoJSON(data, "MultiPolygon", &coordinates); err != nil {
+		return err
+	}
+	multiPolygon := make(MultiPolygon, len(coordinates))
+	for i, c := range coordinates {
+		polygon, err := polygonCoordinates(c)
+		if err != nil {
+			return err
+		}
+		multiPolygon[i] = polygon
+	}
+	*m = multiPolygon
+	return nil
+}
+
+func unmarshalGeoJSON(data []byte, typ string, coordinates interface{}) error {
+	var g geoJSON
+	if err := json.Unmarshal(data, &g); err != nil {
+		return err
+	}
+	if g.Type != typ {
+		return fmt.Errorf("clickhouse: expected GeoJSON %s, got %q", typ, g.Type)
+	}
+	if err := json.Unmarshal(g.Coordinates, coordinates); err != nil {
+		return fmt.Errorf("clickhouse: malformed coordinates of GeoJSON %s: %v", typ, err)
+	}
+	return nil
+}
+
+// pointCoordinates converts GeoJSON position into Point, the altitude is ignored
+func pointCoordinates(coordinates []float64) (Point, error) {
+	if len(coordinates) < 2 {
+		return Point{}, fmt.Errorf("clickhouse: GeoJSON position of %d coordinates", len(coordinates))
+	}
+	return Point{X: coordinates[0], Y: coordinates[1]}, nil
+}
+
+func polygonCoordinates(coordinates [][][]float64) (Polygon, error) {
+	polygon := make(Polygon, len(coordinates))
+	for i, ring := range coordinates {
+		polygon[i] = make(Ring, len(ring))
+		for j, position := range ring {
+			p, err := pointCoordinates(position)
+			if err != nil {
+				return nil, err
+			}
+			polygon[i][j] = p
+		}
+	}
+	return polygon, nil
+}
@@ -0,0 +1,128 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWKT(t *testing.T) {
+	testCases := []struct {
+		value interface{}
+		wkt   string
+	}{
+		{Point{1.5, -2}, "POINT(1.5 -2)"},
+		{Polygon{{{0, 0}, {10, 0}, {10, 10}}, {{1, 1}, {2, 2}, {2, 1}}}, "POLYGON((0 0,10 0,10 10),(1 1,2 2,2 1))"},
+		{MultiPolygon{{{{0, 0}, {1, 0}, {1, 1}}}, {{{2, 2}, {3, 2}, {3, 3}}}}, "MULTIPOLYGON(((0 0,1 0,1 1)),((2 2,3 2,3 3)))"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.wkt, tc.value.(interface{ String() string }).String())
+		v, err := ParseWKT(tc.wkt)
+		if assert.NoError(t, err, tc.wkt) {
+			assert.Equal(t, tc.value, v)
+		}
+	}
+	assert.Equal(t, "POLYGON((0 0,1 0,1 1))", Ring{{0, 0}, {1, 0}, {1, 1}}.String())
+
+	v, err := ParseWKT(" point ( 1e3  2 ) ")
+	require.NoError(t, err)
+	assert.Equal(t, Point{1000, 2}, v)
+
+	for _, wkt := range []string{"", "LINESTRING(0 0,1 1)", "POINT(1)", "POINT(1 2", "POLYGON((0 0,1 1)) x", "POLYGON(())"} {
+		_, err := ParseWKT(wkt)
+		assert.Error(t, err, wkt)
+	}
+}
+
+func TestGeoJSON(t *testing.T) {
+	testCases := []struct {
+		value   interface{}
+		geoJSON string
+	}{
+		{&Point{1.5, -2}, `{"type":"Point","coordinates":[1.5,-2]}`},
+		{&Ring{{0, 0}, {1, 0}}, `{"type":"Polygon","coordinates":[[[0,0],[1,0]]]}`},
+		{&Polygon{{{0, 0}, {1, 0}}, {}}, `{"type":"Polygon","coordinates":[[[0,0],[1,0]],[]]}`},
+		{&MultiPolygon{{{{0, 0}}}}, `{"type":"MultiPolygon","coordinates":[[[[0,0]]]]}`},
+	}
+	for _, tc := range testCases {
+		b, err := json.Marshal(tc.value)
+		if assert.NoError(t, err) {
+			assert.Equal(t, tc.geoJSON, string(b))
+		}
+		dest := newGeoValue(tc.value)
+		if assert.NoError(t, json.Unmarshal([]byte(tc.geoJSON), dest), tc.geoJSON) {
+			assert.Equal(t, tc.value, dest)
+		}
+	}
+
+	var p Point
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"Point","coordinates":[1,2,3]}`), &p))
+	assert.Equal(t, Point{1, 2}, p)
+	assert.EqualError(t, json.Unmarshal([]byte(`{"type":"Polygon","coordinates":[]}`), &p),
+		`clickhouse: expected GeoJSON Point, got "Polygon"`)
+	assert.EqualError(t, json.Unmarshal([]byte(`{"type":"Point","coordinates":[1]}`), &p),
+		"clickhouse: GeoJSON position of 1 coordinates")
+	var r Ring
+	assert.EqualError(t, json.Unmarshal([]byte(`{"type":"Polygon","coordinates":[[],[]]}`), &r),
+		"clickhouse: GeoJSON Polygon of 2 rings can't be unmarshaled into Ring")
+}
+
+// newGeoValue returns a pointer to a new zero value of the geo type v points to
+func newGeoValue(v interface{}) interface{} {
+	switch v.(type) {
+	case *Point:
+		return new(Point)
+	case *Ring:
+		return new(Ring)
+	case *Polygon:
+		return new(Polygon)
+	}
+	return new(MultiPolygon)
+}
+
+func TestGeoScan(t *testing.T) {
+	var r Ring
+	require.NoError(t, r.Scan(Ring{{1, 2}}))
+	assert.Equal(t, Ring{{1, 2}}, r)
+	require.NoError(t, r.Scan("POLYGON((0 0,1 1))"))
+	assert.Equal(t, Ring{{0, 0}, {1, 1}}, r)
+	var m MultiPolygon
+	require.NoError(t, m.Scan([]byte("MULTIPOLYGON(((0 0)))")))
+	assert.Equal(t, MultiPolygon{{{{0, 0}}}}, m)
+
+	var p Point
+	assert.EqualError(t, p.Scan(Ring{}), "clickhouse: can't scan clickhouse.Ring into clickhouse.Point")
+	assert.EqualError(t, p.Scan("POLYGON((0 0))"), "clickhouse: can't scan clickhouse.Polygon into clickhouse.Point")
+	assert.EqualError(t, p.Scan(nil), "clickhouse: can't scan <nil> into clickhouse.Point")
+	assert.EqualError(t, r.Scan("POLYGON((0 0),(1 1))"), "clickhouse: can't scan clickhouse.Polygon into clickhouse.Ring")
+}
+
+func TestSelectGeo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("id\tlocation\tzone\n" +
+			"UInt64\tPoint\tMultiPolygon\n" +
+			"1\t(37.6,55.7)\t[[[(37,55),(38,55),(38,56)]]]\n"))
+	}))
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var (
+		id       uint64
+		location Point
+		zone     MultiPolygon
+	)
+	require.NoError(t, db.QueryRow("SELECT id, location, zone FROM t").Scan(&id, &location, &zone))
+	assert.Equal(t, Point{37.6, 55.7}, location)
+	assert.Equal(t, MultiPolygon{{{{37, 55}, {38, 55}, {38, 56}}}}, zone)
+
+	v, err := zone.Value()
+	require.NoError(t, err)
+	assert.Equal(t, "[[[(37,55),(38,55),(38,56)]]]", string(v.([]byte)))
+}
@@ -9,6 +9,8 @@ import (
 
 var (
 	escaper          = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	paramEscaper     = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`)
+	elemNameEscaper  = strings.NewReplacer(`\`, `\\`, "`", "\\`")
 	dateFormat       = "2006-01-02"
 	timeFormat       = "2006-01-02 15:04:05"
 	dateTime64Format = "2006-01-02 15:04:05.999999999"
@@ -18,6 +20,11 @@ func escape(s string) string {
 	return escaper.Replace(s)
 }
 
+// quoteIdentifier quotes the name of a table or a column with backquotes
+func quoteIdentifier(name string) string {
+	return "`" + elemNameEscaper.Replace(name) + "`"
+}
+
 func quote(s string) string {
 	return "'" + s + "'"
 }
@@ -0,0 +1,86 @@
+package clickhouse
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var defaultHostCooldown = 10 * time.Second
+
+// hostPool keeps the hosts of a DSN and tracks the ones which recently failed,
+// it is shared by the connections of a Connector and released with it
+type hostPool struct {
+	hosts    []string
+	strategy HostStrategy
+	cooldown time.Duration
+	next     uint32
+
+	mu          sync.Mutex
+	failedUntil map[string]time.Time
+}
+
+func newHostPool(hosts []string, strategy HostStrategy, cooldown time.Duration) *hostPool {
+	if cooldown == 0 {
+		cooldown = defaultHostCooldown
+	}
+	return &hostPool{
+		hosts:       hosts,
+		strategy:    strategy,
+		cooldown:    cooldown,
+		failedUntil: make(map[string]time.Time),
+	}
+}
+
+// order returns the hosts in the order they should be tried.
+// Hosts in cooldown are moved to the end of the list, so they are
+// used only when all the other hosts have failed too.
+func (p *hostPool) order() []string {
+	if len(p.hosts) == 1 {
+		return p.hosts
+	}
+
+	start := 0
+	switch p.strategy {
+	case HostStrategyRoundRobin:
+		start = int((atomic.AddUint32(&p.next, 1) - 1) % uint32(len(p.hosts)))
+	case HostStrategyRandom:
+		start = rand.Intn(len(p.hosts))
+	}
+
+	now := time.Now()
+	healthy := make([]string, 0, len(p.hosts))
+	var failed []string
+	p.mu.Lock()
+	for i := range p.hosts {
+		host := p.hosts[(start+i)%len(p.hosts)]
+		if until, ok := p.failedUntil[host]; ok && now.Before(until) {
+			failed = append(failed, host)
+			continue
+		}
+		healthy = append(healthy, host)
+	}
+	p.mu.Unlock()
+	return append(healthy, failed...)
+}
+
+// markFailed puts the host into cooldown
+func (p *hostPool) markFailed(host string) {
+	if len(p.hosts) == 1 {
+		return
+	}
+	p.mu.Lock()
+	p.failedUntil[host] = time.Now().Add(p.cooldown)
+	p.mu.Unlock()
+}
+
+// markAlive takes the host out of cooldown
+func (p *hostPool) markAlive(host string) {
+	if len(p.hosts) == 1 {
+		return
+	}
+	p.mu.Lock()
+	delete(p.failedUntil, host)
+	p.mu.Unlock()
+}
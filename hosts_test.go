@@ -0,0 +1,101 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostPoolOrder(t *testing.T) {
+	hosts := []string{"ch1:8123", "ch2:8123", "ch3:8123"}
+
+	p := newHostPool(hosts, HostStrategyInOrder, time.Minute)
+	assert.Equal(t, hosts, p.order())
+	assert.Equal(t, hosts, p.order())
+
+	p = newHostPool(hosts, HostStrategyRoundRobin, time.Minute)
+	assert.Equal(t, []string{"ch1:8123", "ch2:8123", "ch3:8123"}, p.order())
+	assert.Equal(t, []string{"ch2:8123", "ch3:8123", "ch1:8123"}, p.order())
+	assert.Equal(t, []string{"ch3:8123", "ch1:8123", "ch2:8123"}, p.order())
+	assert.Equal(t, []string{"ch1:8123", "ch2:8123", "ch3:8123"}, p.order())
+
+	p = newHostPool(hosts, HostStrategyRandom, time.Minute)
+	for i := 0; i < 10; i++ {
+		assert.ElementsMatch(t, hosts, p.order())
+	}
+}
+
+func TestHostPoolCooldown(t *testing.T) {
+	hosts := []string{"ch1:8123", "ch2:8123", "ch3:8123"}
+	p := newHostPool(hosts, HostStrategyInOrder, time.Minute)
+
+	p.markFailed("ch1:8123")
+	assert.Equal(t, []string{"ch2:8123", "ch3:8123", "ch1:8123"}, p.order())
+	p.markFailed("ch2:8123")
+	assert.Equal(t, []string{"ch3:8123", "ch1:8123", "ch2:8123"}, p.order())
+	p.markAlive("ch1:8123")
+	assert.Equal(t, []string{"ch1:8123", "ch3:8123", "ch2:8123"}, p.order())
+
+	p = newHostPool(hosts, HostStrategyInOrder, time.Nanosecond)
+	p.markFailed("ch1:8123")
+	time.Sleep(time.Millisecond)
+	assert.Equal(t, hosts, p.order())
+}
+
+func TestConnectorHostPool(t *testing.T) {
+	cfg, err := ParseDSN("http://shared1,shared2/db?host_strategy=round_robin")
+	require.NoError(t, err)
+	connector, err := NewConnector(cfg)
+	require.NoError(t, err)
+	connector2, err := NewConnector(cfg)
+	require.NoError(t, err)
+
+	// the connections of a connector share the failed hosts,
+	// the pool is not kept anywhere else, so it is released with the connector
+	c1, err := connector.Connect(context.Background())
+	require.NoError(t, err)
+	c2, err := connector.Connect(context.Background())
+	require.NoError(t, err)
+	c3, err := connector2.Connect(context.Background())
+	require.NoError(t, err)
+	assert.True(t, c1.(*conn).hosts == c2.(*conn).hosts)
+	assert.False(t, c1.(*conn).hosts == c3.(*conn).hosts)
+}
+
+func TestConnFailover(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		queries = append(queries, string(b))
+		_, _ = w.Write([]byte("1\n"))
+	}))
+	defer srv.Close()
+
+	// a listener which is closed right away gives an address refusing connections
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	deadHost := l.Addr().String()
+	require.NoError(t, l.Close())
+	aliveHost := strings.TrimPrefix(srv.URL, "http://")
+
+	cfg, err := ParseDSN("http://" + deadHost + "," + aliveHost + "/default?host_cooldown=1m")
+	require.NoError(t, err)
+	cn := newConn(cfg)
+	defer cn.Close()
+
+	require.NoError(t, cn.Ping(context.Background()))
+	assert.Equal(t, []string{"select 1"}, queries)
+	assert.Equal(t, []string{aliveHost, deadHost}, cn.hosts.order())
+
+	// the dead host is in cooldown, so the request goes straight to the alive one
+	require.NoError(t, cn.Ping(context.Background()))
+	assert.Equal(t, []string{"select 1", "select 1"}, queries)
+}
@@ -0,0 +1,79 @@
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// queryParamName is the URL parameter holding the query,
+// when the body of the request contains the data
+const queryParamName = "query"
+
+// InsertFrom executes the query, which is an INSERT with a FORMAT clause,
+// and streams the data read from r as the body of the request
+func (c *conn) InsertFrom(ctx context.Context, query string, r io.Reader) (driver.Result, error) {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return nil, driver.ErrBadConn
+	}
+	if statementKeyword(query) != "INSERT" {
+		return nil, fmt.Errorf("clickhouse: InsertFrom query must be INSERT, got: %s", query)
+	}
+	c.log("insert from: ", query)
+
+	bodyReader, bodyWriter := io.Pipe()
+	req, err := c.newRequest(ctx, query, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	params := req.URL.Query()
+	params.Set(queryParamName, query)
+	req.URL.RawQuery = params.Encode()
+
+	copied := make(chan error, 1)
+	go func() {
+		copied <- c.copyBody(bodyWriter, r)
+	}()
+	result, err := c.insertFrom(ctx, query, req)
+	// stop copying if the request has finished before the end of the data
+	_ = bodyReader.Close()
+	copyErr := <-copied
+	if copyErr != nil && copyErr != io.ErrClosedPipe {
+		// the request has failed because of the data reader
+		return nil, copyErr
+	}
+	return result, err
+}
+
+// copyBody writes the data to the body of the request,
+// the body is closed with the error of the data reader
+func (c *conn) copyBody(w *io.PipeWriter, r io.Reader) (err error) {
+	if c.useGzipCompression {
+		gz := gzip.NewWriter(w)
+		if _, err = io.Copy(gz, r); err == nil {
+			err = gz.Close()
+		}
+	} else {
+		_, err = io.Copy(w, r)
+	}
+	_ = w.CloseWithError(err)
+	return err
+}
+
+// insertFrom sends the request, which is never repeated because its body is streamed
+func (c *conn) insertFrom(ctx context.Context, query string, req *http.Request) (driver.Result, error) {
+	body, header, err := c.doRequest(ctx, req)
+	if body != nil {
+		defer body.Close()
+		// Drain body to enable connection reuse
+		_, _ = io.Copy(io.Discard, body)
+	}
+	if err != nil {
+		return emptyResult, err
+	}
+	return newExecResult(query, req.URL.Query(), header), nil
+}
@@ -0,0 +1,78 @@
+package clickhouse
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func insertFrom(t *testing.T, dsn, query string, r io.Reader) (err error) {
+	db, err := sql.Open("chhttp", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+	return sqlConn.Raw(func(driverConn interface{}) error {
+		_, err := driverConn.(Conn).InsertFrom(context.Background(), query, r)
+		return err
+	})
+}
+
+func TestInsertFrom(t *testing.T) {
+	const (
+		query = "INSERT INTO t FORMAT CSVWithNames"
+		data  = "a,b\n1,x\n2,y\n"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			reader = gz
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			// the request has been aborted
+			return
+		}
+		assert.Equal(t, query, r.URL.Query().Get(queryParamName))
+		if string(body) != data {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Code: 27. DB::Exception: Cannot parse input: expected ',' before: 'bad'. (CANNOT_PARSE_INPUT_ASSERTION_FAILED) (version 23.8.1.1)"))
+		}
+	}))
+	defer srv.Close()
+
+	assert.NoError(t, insertFrom(t, srv.URL+"/default", query, strings.NewReader(data)))
+	assert.NoError(t, insertFrom(t, srv.URL+"/default?enable_http_compression=1", query, strings.NewReader(data)))
+
+	err := insertFrom(t, srv.URL+"/default", query, strings.NewReader("bad"))
+	assert.Equal(t, &Error{Code: 27, Message: "Cannot parse input: expected ',' before: 'bad'"}, err)
+
+	readErr := errors.New("read failed")
+	err = insertFrom(t, srv.URL+"/default", query, io.MultiReader(strings.NewReader("a,b\n"), &failingReader{readErr}))
+	assert.Equal(t, readErr, err)
+
+	err = insertFrom(t, srv.URL+"/default", "SELECT 1", strings.NewReader(data))
+	assert.EqualError(t, err, "clickhouse: InsertFrom query must be INSERT, got: SELECT 1")
+}
+
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,182 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structInsert is a group of rows which have the same columns
+type structInsert struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+// InsertStructs inserts the rows into the table with the Batch API. The columns
+// are the fields of T matched like ScanStruct does, T may be a pointer to a struct.
+//
+// The options of the ch tag change the inserted columns:
+//
+//	Name    string    `ch:"name"`                // the column name
+//	Skipped string    `ch:"-"`                   // the field is not a column
+//	Updated time.Time `ch:"updated,omitempty"`   // the zero value is not inserted, the column gets its default
+//	Created time.Time `ch:"created,default"`     // the column is never inserted, it is computed by ClickHouse
+//	Point   Point     `ch:"point,tuple"`         // the struct is a tuple column
+//
+// The fields of a nested struct are the columns prefixed with the name of the field
+// and _, unless the struct is a value like time.Time or netip.Addr. The table and
+// the columns are quoted with backquotes. Rows with different omitted columns are
+// sent in separate INSERTs.
+func InsertStructs[T any](ctx context.Context, db *sql.DB, table string, rows []T) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("clickhouse: expected a struct, got %s", t)
+	}
+	var fields []structField
+	for _, f := range structFields(t) {
+		if !f.isDefault {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("clickhouse: no fields to insert in %s", t)
+	}
+
+	var inserts []*structInsert
+	byOmitted := make(map[string]*structInsert)
+	for i := range rows {
+		v := reflect.ValueOf(&rows[i]).Elem()
+		if isPtr {
+			if v.IsNil() {
+				return fmt.Errorf("clickhouse: row %d is nil", i)
+			}
+			v = v.Elem()
+		}
+		omitted := make([]byte, len(fields))
+		values := make([]interface{}, 0, len(fields))
+		for j, f := range fields {
+			fv, err := v.FieldByIndexErr(f.index)
+			if err != nil {
+				// a nil embedded or nested struct is a group of NULL columns
+				fv = reflect.Value{}
+			}
+			if f.omitEmpty && isEmptyValue(fv) {
+				omitted[j] = 1
+				continue
+			}
+			values = append(values, insertValue(fv))
+		}
+		insert, ok := byOmitted[string(omitted)]
+		if !ok {
+			insert = new(structInsert)
+			for j, f := range fields {
+				if omitted[j] == 0 {
+					insert.columns = append(insert.columns, f.name)
+				}
+			}
+			byOmitted[string(omitted)] = insert
+			inserts = append(inserts, insert)
+		}
+		insert.rows = append(insert.rows, values)
+	}
+
+	sqlConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sqlConn.Close()
+	return sqlConn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(Conn)
+		if !ok {
+			return fmt.Errorf("clickhouse: unexpected connection %T", driverConn)
+		}
+		for _, insert := range inserts {
+			if len(insert.columns) == 0 {
+				return fmt.Errorf("clickhouse: all fields of %d rows are empty", len(insert.rows))
+			}
+			if err := insert.send(ctx, c, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// send inserts the rows with a Batch
+func (insert *structInsert) send(ctx context.Context, c Conn, table string) error {
+	columns := make([]string, len(insert.columns))
+	for i, column := range insert.columns {
+		columns[i] = quoteIdentifier(column)
+	}
+	batch, err := c.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s (%s)", quoteTable(table), strings.Join(columns, ", ")))
+	if err != nil {
+		return err
+	}
+	for _, values := range insert.rows {
+		if err = batch.Append(values...); err != nil {
+			batch.Abort()
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+// quoteTable quotes the table name and the database name before the dot
+// unless the name is quoted already
+func quoteTable(table string) string {
+	if strings.ContainsAny(table, "`\"") {
+		return table
+	}
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// insertValue returns the value of the field, which is wrapped for
+// the encoders if it is an array, a map or a tuple
+func insertValue(v reflect.Value) interface{} {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(reflectTypeValuer) || v.Type() == reflectTypeBigInt {
+			// the encoders take *big.Int
+			return v.Interface()
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	switch {
+	case v.Type().Implements(reflectTypeValuer):
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8, v.Kind() == reflect.Array:
+		return Array(v.Interface())
+	case v.Kind() == reflect.Map:
+		return Map(v.Interface())
+	case v.Kind() == reflect.Struct && flattened(v.Type()):
+		// the structs which are not values themselves like netip.Addr
+		return Tuple(v.Interface())
+	}
+	return v.Interface()
+}
+
+// isEmptyValue reports whether the value is omitted like encoding/json does it
+func isEmptyValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,114 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type insertPoint struct {
+	X, Y int8
+}
+
+type insertGeo struct {
+	Lat, Lon float64
+}
+
+type insertEvent struct {
+	ID      uint64
+	Name    string            `ch:"event_name"`
+	Tags    []string          `ch:",omitempty"`
+	Attrs   map[string]string `ch:"attrs"`
+	Point   insertPoint       `ch:"point,tuple"`
+	Geo     *insertGeo
+	Time    time.Time `ch:"ts"`
+	Created time.Time `ch:"created,default"`
+	Skipped string    `ch:"-"`
+}
+
+func TestInsertStructs(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ts := time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC)
+	events := []*insertEvent{
+		{ID: 1, Name: "a", Tags: []string{"x"}, Attrs: map[string]string{"k": "v"}, Point: insertPoint{1, 2}, Geo: &insertGeo{1.5, 2}, Time: ts},
+		{ID: 2, Name: "b", Point: insertPoint{3, 4}, Time: ts, Skipped: "skipped"},
+		{ID: 3, Name: "c", Tags: []string{}, Time: ts},
+	}
+	require.NoError(t, InsertStructs(context.Background(), db, "events", events))
+	assert.Equal(t, []string{
+		"INSERT INTO `events` (`id`, `event_name`, `tags`, `attrs`, `point`, `geo_lat`, `geo_lon`, `ts`) VALUES " +
+			"(1, 'a', ['x'], map('k','v'), (1,2), 1.5, 2, '2011-03-06 06:20:00')",
+		"INSERT INTO `events` (`id`, `event_name`, `attrs`, `point`, `geo_lat`, `geo_lon`, `ts`) VALUES " +
+			"(2, 'b', map(), (3,4), NULL, NULL, '2011-03-06 06:20:00'), " +
+			"(3, 'c', map(), (0,0), NULL, NULL, '2011-03-06 06:20:00')",
+	}, srv.inserted())
+
+	assert.EqualError(t, InsertStructs(context.Background(), db, "events", []int{1}), "clickhouse: expected a struct, got int")
+	assert.EqualError(t, InsertStructs(context.Background(), db, "events", []*insertEvent{nil}), "clickhouse: row 0 is nil")
+}
+
+func TestInsertStructsValueTypes(t *testing.T) {
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the structs without exported fields are single columns
+	type hit struct {
+		Addr  netip.Addr `ch:"client addr"`
+		Count *big.Int   `ch:"count"`
+		Note  string     `ch:"note\x60s"`
+	}
+	hits := []hit{{Addr: netip.MustParseAddr("127.0.0.1"), Count: big.NewInt(42), Note: "a"}}
+	require.NoError(t, InsertStructs(context.Background(), db, "stats.hits", hits))
+	assert.Equal(t, []string{"INSERT INTO `stats`.`hits` (`client addr`, `count`, `note\\`s`) VALUES ('127.0.0.1', 42, 'a')"}, srv.inserted())
+}
+
+func TestInsertStructsRowBinary(t *testing.T) {
+	describe := rowBinary(uint8(3), "name", "type", "default_type", "String", "String", "String",
+		"id", "UInt64", "",
+		"event_name", "String", "",
+		"tags", "Array(String)", "",
+		"attrs", "Map(String, String)", "",
+		"point", "Tuple(Int8, Int8)", "",
+		"geo_lat", "Nullable(Float64)", "",
+		"geo_lon", "Nullable(Float64)", "",
+		"ts", "DateTime", "",
+		"created", "DateTime", "DEFAULT",
+	)
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			w.Header().Set(formatHeader, "RowBinaryWithNamesAndTypes")
+			_, _ = w.Write(describe)
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default?insert_format=rowbinary")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ts := time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC)
+	events := []insertEvent{
+		{ID: 1, Name: "a", Tags: []string{"x"}, Attrs: map[string]string{"k": "v"}, Point: insertPoint{1, 2}, Geo: &insertGeo{1.5, 2}, Time: ts},
+	}
+	require.NoError(t, InsertStructs(context.Background(), db, "events", events))
+	received := srv.received()
+	require.Len(t, received, 2)
+	assert.Equal(t, "INSERT INTO `events` (`id`, `event_name`, `tags`, `attrs`, `point`, `geo_lat`, `geo_lon`, `ts`) FORMAT RowBinary\n"+
+		string(rowBinary(uint64(1), "a", uint8(1), "x", uint8(1), "k", "v", int8(1), int8(2),
+			uint8(0), float64(1.5), uint8(0), float64(2), uint32(ts.Unix()))), received[1])
+}
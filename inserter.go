@@ -0,0 +1,267 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultInserterQueueSize is the number of rows waiting to be flushed
+// when InserterConfig.QueueSize is not set
+const defaultInserterQueueSize = 10000
+
+// InserterConfig configures an Inserter. Zero limits are disabled,
+// the rows are flushed when any of the enabled limits is reached.
+type InserterConfig struct {
+	MaxRows  int           // flush after this number of rows
+	MaxBytes int64         // flush after this size of the encoded rows
+	Interval time.Duration // flush the rows at least this often
+	// QueueSize is the number of rows waiting to be flushed,
+	// Add blocks or drops the row when the queue is full (10000 by default)
+	QueueSize int
+	// DropWhenFull makes Add return ErrInserterFull instead of blocking when the queue is full
+	DropWhenFull bool
+	// Retry describes how a failed flush is repeated. The rows of every flush are sent
+	// with a unique insert_deduplication_token, so they are not inserted twice.
+	Retry RetryPolicy
+	// OnFlush is called after every flush, it is called from the goroutine of the Inserter,
+	// so Add called from it waits for Close if the queue is full
+	OnFlush func(FlushStats)
+}
+
+// FlushStats describes a flush of an Inserter
+type FlushStats struct {
+	Rows     int
+	Bytes    int
+	Retries  int
+	Duration time.Duration
+	Err      error
+}
+
+// Inserter accumulates rows and inserts them in the background. It is safe
+// for concurrent use. The rows are sent with the INSERT query through the
+// connections of sql.DB, so Close must be called before closing the sql.DB.
+type Inserter struct {
+	db      *sql.DB
+	query   string
+	columns []string
+	cfg     InserterConfig
+
+	mu      sync.Mutex // guards closed and sending
+	closed  bool
+	sending sync.WaitGroup // Add and Flush sending to queue
+	closing chan struct{}  // interrupts sending to the full queue
+	queue   chan inserterItem
+	done    chan struct{}
+
+	// the state of the goroutine
+	buf        []byte
+	rows       int
+	failed     int   // the number of failed background flushes
+	lastFailed error // the error of the last failed background flush
+	closeErr   error
+}
+
+// inserterItem is either a row or a request to flush the rows
+type inserterItem struct {
+	row     []byte
+	flushed chan error
+}
+
+// NewInserter creates an Inserter for the query INSERT INTO table [(columns)]
+func NewInserter(db *sql.DB, query string, cfg InserterConfig) (*Inserter, error) {
+	_, columns, ok := parseInsert(query)
+	if !ok {
+		return nil, fmt.Errorf("clickhouse: inserter query must be INSERT INTO table (columns), got: %s", query)
+	}
+	queueSize := cfg.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultInserterQueueSize
+	}
+	ins := &Inserter{
+		db:      db,
+		query:   strings.TrimSpace(query) + " VALUES ",
+		columns: columns,
+		cfg:     cfg,
+		closing: make(chan struct{}),
+		queue:   make(chan inserterItem, queueSize),
+		done:    make(chan struct{}),
+	}
+	go ins.run()
+	return ins, nil
+}
+
+// Add adds a row with the given values to the queue. If the columns are
+// listed in the query, the number of values must be the same.
+func (ins *Inserter) Add(values ...interface{}) error {
+	if len(ins.columns) > 0 && len(values) != len(ins.columns) {
+		return fmt.Errorf("clickhouse: inserter has %d columns, but %d values are added", len(ins.columns), len(values))
+	}
+	row, err := appendTextRow(nil, values, ins.columns)
+	if err != nil {
+		return err
+	}
+
+	if !ins.startSending() {
+		return ErrInserterClosed
+	}
+	defer ins.sending.Done()
+	if ins.cfg.DropWhenFull {
+		select {
+		case ins.queue <- inserterItem{row: row}:
+			return nil
+		default:
+			return ErrInserterFull
+		}
+	}
+	select {
+	case ins.queue <- inserterItem{row: row}:
+		return nil
+	case <-ins.closing:
+		return ErrInserterClosed
+	}
+}
+
+// Flush inserts the rows added before the call and returns the result
+func (ins *Inserter) Flush() error {
+	if !ins.startSending() {
+		return ErrInserterClosed
+	}
+	flushed := make(chan error, 1)
+	select {
+	case ins.queue <- inserterItem{flushed: flushed}:
+		ins.sending.Done()
+	case <-ins.closing:
+		ins.sending.Done()
+		return ErrInserterClosed
+	}
+	return <-flushed
+}
+
+// startSending registers a sender to the queue, false is returned if the Inserter is closed.
+// The lock is not held while sending, so Close interrupts the senders waiting for the full queue.
+func (ins *Inserter) startSending() bool {
+	ins.mu.Lock()
+	defer ins.mu.Unlock()
+	if ins.closed {
+		return false
+	}
+	ins.sending.Add(1)
+	return true
+}
+
+// Close inserts the rest of the rows and stops the Inserter. It returns
+// the result of the last flush and the errors of the background flushes.
+func (ins *Inserter) Close() error {
+	ins.mu.Lock()
+	if ins.closed {
+		ins.mu.Unlock()
+		return nil
+	}
+	ins.closed = true
+	close(ins.closing)
+	ins.mu.Unlock()
+	// the queue is closed when nobody sends to it
+	ins.sending.Wait()
+	close(ins.queue)
+	<-ins.done
+	if ins.failed == 0 {
+		return ins.closeErr
+	}
+	return errors.Join(fmt.Errorf("clickhouse: %d background flushes of the inserter have failed, the last one: %w",
+		ins.failed, ins.lastFailed), ins.closeErr)
+}
+
+func (ins *Inserter) run() {
+	defer close(ins.done)
+	var tick <-chan time.Time
+	if ins.cfg.Interval > 0 {
+		ticker := time.NewTicker(ins.cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case item, ok := <-ins.queue:
+			switch {
+			case !ok:
+				ins.closeErr = ins.flush()
+				return
+			case item.flushed != nil:
+				item.flushed <- ins.flush()
+			default:
+				if ins.rows > 0 {
+					ins.buf = append(ins.buf, ", "...)
+				}
+				ins.buf = append(ins.buf, item.row...)
+				ins.rows++
+				if ins.cfg.MaxRows > 0 && ins.rows >= ins.cfg.MaxRows ||
+					ins.cfg.MaxBytes > 0 && int64(len(ins.buf)) >= ins.cfg.MaxBytes {
+					ins.backgroundFlush()
+				}
+			}
+		case <-tick:
+			ins.backgroundFlush()
+		}
+	}
+}
+
+// backgroundFlush flushes the rows when a limit is reached,
+// the error is kept to be returned by Close
+func (ins *Inserter) backgroundFlush() {
+	if err := ins.flush(); err != nil {
+		ins.failed++
+		ins.lastFailed = err
+	}
+}
+
+// flush inserts the accumulated rows, the failed insert is repeated
+// with the same deduplication token according to the retry policy
+func (ins *Inserter) flush() error {
+	if ins.rows == 0 {
+		return nil
+	}
+	stats := FlushStats{Rows: ins.rows, Bytes: len(ins.buf)}
+	query := ins.query + string(ins.buf)
+	ins.buf, ins.rows = ins.buf[:0], 0
+
+	start := time.Now()
+	ctx := context.WithValue(context.Background(), RequestQueryParams, map[string]string{
+		insertDeduplicationTokenParamName: uuid.New().String(),
+	})
+	for ; ; stats.Retries++ {
+		stats.Err = ins.exec(ctx, query)
+		if stats.Err == nil || stats.Retries >= ins.cfg.Retry.MaxRetries ||
+			!ins.cfg.Retry.retryable(stats.Err, true) || !ins.cfg.Retry.wait(ctx, stats.Retries) {
+			break
+		}
+	}
+	stats.Duration = time.Since(start)
+	if ins.cfg.OnFlush != nil {
+		ins.cfg.OnFlush(stats)
+	}
+	return stats.Err
+}
+
+// exec executes the query with conn.exec of a connection of the pool
+func (ins *Inserter) exec(ctx context.Context, query string) error {
+	sqlConn, err := ins.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sqlConn.Close()
+	return sqlConn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*conn)
+		if !ok {
+			return fmt.Errorf("clickhouse: unexpected connection %T", driverConn)
+		}
+		_, err := c.exec(ctx, query, nil)
+		return err
+	})
+}
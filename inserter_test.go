@@ -0,0 +1,219 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInserter(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var (
+		mu      sync.Mutex
+		flushes []FlushStats
+	)
+	ins, err := NewInserter(db, "INSERT INTO data (i64, s)", InserterConfig{
+		MaxRows: 2,
+		OnFlush: func(stats FlushStats) {
+			mu.Lock()
+			defer mu.Unlock()
+			flushes = append(flushes, stats)
+		},
+	})
+	require.NoError(t, err)
+	assert.EqualError(t, ins.Add(1), "clickhouse: inserter has 2 columns, but 1 values are added")
+	require.NoError(t, ins.Add(1, "a"))
+	require.NoError(t, ins.Add(2, "b"))
+	require.NoError(t, ins.Add(3, "c"))
+	require.NoError(t, ins.Flush())
+	require.NoError(t, ins.Flush())
+	require.NoError(t, ins.Add(4, "d"))
+	require.NoError(t, ins.Close())
+	assert.Equal(t, ErrInserterClosed, ins.Add(5, "e"))
+	assert.Equal(t, ErrInserterClosed, ins.Flush())
+	assert.NoError(t, ins.Close())
+
+	assert.Equal(t, []string{
+		"INSERT INTO data (i64, s) VALUES (1, 'a'), (2, 'b')",
+		"INSERT INTO data (i64, s) VALUES (3, 'c')",
+		"INSERT INTO data (i64, s) VALUES (4, 'd')",
+	}, srv.received())
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, flushes, 3) {
+		assert.Equal(t, 2, flushes[0].Rows)
+		assert.Equal(t, len("(1, 'a'), (2, 'b')"), flushes[0].Bytes)
+		assert.NoError(t, flushes[0].Err)
+		assert.Equal(t, 1, flushes[2].Rows)
+	}
+}
+
+func TestInserterInterval(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ins, err := NewInserter(db, "INSERT INTO data", InserterConfig{Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer ins.Close()
+	require.NoError(t, ins.Add(1))
+	assert.Eventually(t, func() bool {
+		return len(srv.received()) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"INSERT INTO data VALUES (1)"}, srv.received())
+}
+
+func TestInserterRetry(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		tokens []string
+	)
+	srv := newBatchServer(t, nil)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tokens = append(tokens, r.URL.Query().Get(insertDeduplicationTokenParamName))
+		if len(tokens) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("Code: 202. DB::Exception: Too many simultaneous queries. (TOO_MANY_SIMULTANEOUS_QUERIES) (version 23.8.1.1)"))
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var stats FlushStats
+	ins, err := NewInserter(db, "INSERT INTO data", InserterConfig{
+		Retry:   RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond},
+		OnFlush: func(s FlushStats) { stats = s },
+	})
+	require.NoError(t, err)
+	require.NoError(t, ins.Add(1))
+	require.NoError(t, ins.Close())
+
+	assert.Equal(t, 1, stats.Retries)
+	assert.NoError(t, stats.Err)
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, tokens, 2) {
+		assert.NotEmpty(t, tokens[0])
+		assert.Equal(t, tokens[0], tokens[1])
+	}
+}
+
+func TestInserterDropWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		<-release
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ins, err := NewInserter(db, "INSERT INTO data", InserterConfig{MaxRows: 1, QueueSize: 1, DropWhenFull: true})
+	require.NoError(t, err)
+	// the first row is being flushed, the second one waits in the queue
+	require.NoError(t, ins.Add(1))
+	assert.Eventually(t, func() bool {
+		return ins.Add(2) == nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, ErrInserterFull, ins.Add(3))
+	close(release)
+	require.NoError(t, ins.Close())
+	assert.Equal(t, []string{"INSERT INTO data VALUES (1)", "INSERT INTO data VALUES (2)"}, srv.received())
+}
+
+func TestInserterCloseWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		<-release
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var ins *Inserter
+	flushAdded := make(chan error, 1)
+	ins, err = NewInserter(db, "INSERT INTO data", InserterConfig{
+		MaxRows:   1,
+		QueueSize: 1,
+		OnFlush: func(FlushStats) {
+			select {
+			case flushAdded <- ins.Add(0):
+			default:
+			}
+		},
+	})
+	require.NoError(t, err)
+	// the first row is being flushed, the second one waits in the queue
+	require.NoError(t, ins.Add(1))
+	require.NoError(t, ins.Add(2))
+	added := make(chan error)
+	go func() {
+		added <- ins.Add(3)
+	}()
+	closed := make(chan error)
+	go func() {
+		closed <- ins.Close()
+	}()
+	// Close interrupts Add waiting for the full queue
+	select {
+	case err = <-added:
+		assert.Equal(t, ErrInserterClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Add is not interrupted by Close")
+	}
+	close(release)
+	select {
+	case err = <-closed:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked")
+	}
+	assert.Equal(t, ErrInserterClosed, <-flushAdded)
+	assert.Equal(t, []string{"INSERT INTO data VALUES (1)", "INSERT INTO data VALUES (2)"}, srv.received())
+}
+
+func TestInserterBackgroundErrors(t *testing.T) {
+	srv := newBatchServer(t, nil)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("Code: 241. DB::Exception: Memory limit (total) exceeded. (MEMORY_LIMIT_EXCEEDED) (version 23.8.1.1)"))
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ins, err := NewInserter(db, "INSERT INTO data", InserterConfig{MaxBytes: 1})
+	require.NoError(t, err)
+	require.NoError(t, ins.Add(1))
+	require.NoError(t, ins.Add(2))
+	err = ins.Close()
+	var srvErr *Error
+	if assert.ErrorAs(t, err, &srvErr) {
+		assert.Equal(t, 241, srvErr.Code)
+	}
+	assert.Contains(t, err.Error(), "2 background flushes of the inserter have failed")
+}
+
+func TestNewInserterQuery(t *testing.T) {
+	_, err := NewInserter(nil, "SELECT 1", InserterConfig{})
+	assert.True(t, strings.HasPrefix(err.Error(), "clickhouse: inserter query must be INSERT INTO table"))
+}
@@ -4,43 +4,26 @@ import (
 	"database/sql/driver"
 )
 
+// placeholders returns the positions of ? placeholders in the query,
+// ignoring the ones in string literals, identifiers and comments
 func placeholders(query string) []int {
-	n := 0
-	quote := false
-	first := -1
-	for i := 0; i < len(query); i++ {
-		switch query[i] {
-		case '\\':
-			i++
-		case '\'':
-			quote = !quote
-		case '?':
-			if !quote {
-				n++
-				if first == -1 {
-					first = i
-				}
-			}
+	var index []int
+	for _, t := range tokenizeQuery(query) {
+		if t.kind == '?' {
+			index = append(index, t.pos)
 		}
 	}
-	if n == 0 {
-		return nil
-	}
-	quote = false
-	index := make([]int, n)
-	n = 0
-	for i, ch := range query[first:] {
-		switch ch {
-		case '\'':
-			quote = !quote
-		case '?':
-			if !quote {
-				index[n] = first + i
-				n++
-			}
+	return index
+}
+
+// hasQueryParams reports whether the query has {name:Type} parameters
+func hasQueryParams(query string) bool {
+	for _, t := range tokenizeQuery(query) {
+		if t.kind == '{' {
+			return true
 		}
 	}
-	return index
+	return false
 }
 
 func interpolateParams(query string, params []driver.Value) (string, error) {
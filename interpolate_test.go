@@ -2,6 +2,7 @@ package clickhouse
 
 import (
 	"database/sql/driver"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,6 +20,14 @@ func TestInterpolate(t *testing.T) {
 		{"SELECT a='?' AND b=?", []driver.Value{"1"}, "SELECT a='?' AND b='1'"},
 		{"SELECT a=? AND b='?'", []driver.Value{"1"}, "SELECT a='1' AND b='?'"},
 		{"SELECT '\\'', ?", []driver.Value{"1"}, "SELECT '\\'', '1'"},
+		{"SELECT 'it''s ?', ?", []driver.Value{1}, "SELECT 'it''s ?', 1"},
+		{"SELECT ? -- why?\n", []driver.Value{1}, "SELECT 1 -- why?\n"},
+		{"SELECT ? # why?\n, ?", []driver.Value{1, 2}, "SELECT 1 # why?\n, 2"},
+		{"SELECT /* a? /* b? */ c? */ ?", []driver.Value{1}, "SELECT /* a? /* b? */ c? */ 1"},
+		{"SELECT \"a?\", `b?`, `c``?`, ?", []driver.Value{1}, "SELECT \"a?\", `b?`, `c``?`, 1"},
+		{"SELECT $$a?'$$, $t$b?$$?$t$, ?", []driver.Value{1}, "SELECT $$a?'$$, $t$b?$$?$t$, 1"},
+		{"SELECT {p:String}, {e:Enum8('?' = 1)}, ?", []driver.Value{1}, "SELECT {p:String}, {e:Enum8('?' = 1)}, 1"},
+		{"SELECT '\\\\', ?, '\\\\'", []driver.Value{"x"}, "SELECT '\\\\', 'x', '\\\\'"},
 	}
 
 	for _, tc := range testCases {
@@ -30,3 +39,56 @@ func TestInterpolate(t *testing.T) {
 	_, err := interpolateParams("SELECT ?, ?", []driver.Value{1})
 	assert.Equal(t, ErrPlaceholderCount, err)
 }
+
+func TestPlaceholders(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []int
+	}{
+		{"SELECT 1", nil},
+		{"SELECT ?", []int{7}},
+		{"SELECT ?, '?', ?", []int{7, 15}},
+		{"SELECT '\\\\' AS a, ?", []int{18}},
+		{"SELECT 1 -- ?", nil},
+		{"SELECT 1 /* ? ", nil},
+		{"SELECT #?", []int{8}},
+		{"SELECT a$?$", []int{9}},
+		{"SELECT {?}", []int{8}},
+		{"SELECT {a:?", []int{10}},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, placeholders(tc.query), tc.query)
+	}
+}
+
+func FuzzPlaceholders(f *testing.F) {
+	for _, q := range []string{
+		"SELECT ?",
+		"SELECT 'a''?', \"?\", `?`, ?",
+		"SELECT $$?$$, $x$?$x$ -- ?\n /* /* ? */ */ ?",
+		"SELECT {a:Array(String)}, {b:Enum('}' = 1)}, ?",
+		"SELECT '\\'', ?",
+		"SELECT 'unterminated ?",
+	} {
+		f.Add(q)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		prev := -1
+		for _, idx := range placeholders(query) {
+			if idx <= prev || query[idx] != '?' {
+				t.Fatalf("invalid placeholder position %d in %q", idx, query)
+			}
+			prev = idx
+		}
+		// nothing inside a string literal, an identifier or a comment is a placeholder
+		for _, wrapped := range []string{
+			quote(escape(query)),
+			"`" + strings.ReplaceAll(strings.ReplaceAll(query, "\\", "\\\\"), "`", "``") + "`",
+			"-- " + strings.ReplaceAll(query, "\n", " "),
+		} {
+			if index := placeholders(wrapped); len(index) != 0 {
+				t.Fatalf("unexpected placeholders %v in %q", index, wrapped)
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package clickhouse
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSON converts a value into a JSON object for JSON columns, the value is
+// marshaled with encoding/json, so it can be a map, a struct or a json.RawMessage
+func JSON(v interface{}) driver.Valuer {
+	return jsonObject{v: v}
+}
+
+type jsonObject struct {
+	v interface{}
+}
+
+// Value implements driver.Valuer
+func (j jsonObject) Value() (driver.Value, error) {
+	b, err := marshalJSON(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return textEncode.Encode(string(b))
+}
+
+// marshalJSON marshals the value of a JSON column, the strings and
+// byte slices are JSON text already
+func marshalJSON(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case jsonObject:
+		return marshalJSON(x.v)
+	case string:
+		return []byte(x), nil
+	case []byte:
+		return x, nil
+	case json.RawMessage:
+		return x, nil
+	}
+	return json.Marshal(v)
+}
+
+// decodeJSON decodes a JSON object, the numbers are decoded into int64,
+// uint64 or float64 depending on their values
+func decodeJSON(data []byte) (map[string]interface{}, error) {
+	object, err := decodeJSONNumbers(data)
+	if err != nil {
+		return nil, err
+	}
+	return jsonNumbers(object).(map[string]interface{}), nil
+}
+
+// decodeJSONNumbers decodes a JSON object, the numbers are kept as json.Number
+func decodeJSONNumbers(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("malformed JSON object: %v", err)
+	}
+	if object == nil {
+		return nil, fmt.Errorf("expected a JSON object, got null")
+	}
+	return object, nil
+}
+
+// jsonNumbers replaces json.Number with the numbers of Go types
+func jsonNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			return u
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = jsonNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = jsonNumbers(e)
+		}
+	}
+	return v
+}
+
+// jsonPaths returns the values of the paths of a JSON object like a.b.c,
+// the nested objects are flattened and the paths with null values are skipped
+func jsonPaths(v interface{}) (map[string]interface{}, error) {
+	data, err := marshalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	object, err := decodeJSONNumbers(data)
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[string]interface{})
+	flattenJSON(paths, "", object)
+	return paths, nil
+}
+
+func flattenJSON(paths map[string]interface{}, prefix string, object map[string]interface{}) {
+	for k, v := range object {
+		switch x := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			flattenJSON(paths, prefix+k+".", x)
+		default:
+			paths[prefix+k] = v
+		}
+	}
+}
+
+// setJSONPath sets the value of the path like a.b.c in the nested objects
+func setJSONPath(object map[string]interface{}, path string, v interface{}) {
+	keys := strings.Split(path, ".")
+	for _, k := range keys[:len(keys)-1] {
+		nested, ok := object[k].(map[string]interface{})
+		if !ok {
+			if _, exists := object[k]; exists {
+				// the path is a value and an object at the same time
+				object[strings.Join(keys, ".")] = v
+				return
+			}
+			nested = make(map[string]interface{})
+			object[k] = nested
+		}
+		object, keys = nested, keys[1:]
+	}
+	object[keys[0]] = v
+}
+
+// jsonValue converts a value of a JSON path to the value it has in a decoded
+// JSON object, the arrays are converted to []interface{}
+func jsonValue(v interface{}) interface{} {
+	if x, ok := v.(Variant); ok {
+		v = x.Value
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return v
+	}
+	values := make([]interface{}, rv.Len())
+	for i := range values {
+		values[i] = jsonValue(rv.Index(i).Interface())
+	}
+	return values
+}
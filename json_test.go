@@ -0,0 +1,93 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("j\tv\td\n" +
+			"JSON(a.b UInt32)\tVariant(String, UInt64)\tDynamic\n" +
+			`{"a":{"b":1},"c":"x\\ty"}` + "\t1\t[1,2]\n"))
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+	var (
+		j    map[string]interface{}
+		v, d Variant
+	)
+	require.NoError(t, db.QueryRow("SELECT j, v, d FROM t").Scan(&j, &v, &d))
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "c": "x\ty"}, j)
+	assert.Equal(t, Variant{Type: "UInt64", Value: uint64(1)}, v)
+	assert.Equal(t, Variant{Value: "[1,2]"}, d)
+
+	rawDB, err := sql.Open("chhttp", srv.URL+"/default?json_as_raw=1")
+	require.NoError(t, err)
+	defer rawDB.Close()
+	var raw json.RawMessage
+	require.NoError(t, rawDB.QueryRow("SELECT j FROM t").Scan(&raw, &v, &d))
+	assert.JSONEq(t, `{"a":{"b":1},"c":"x\ty"}`, string(raw))
+}
+
+func TestBatchJSON(t *testing.T) {
+	describe := rowBinary(uint8(3), "name", "type", "default_type", "String", "String", "String",
+		"j", "JSON(a.b UInt32)", "",
+		"v", "Variant(String, UInt64)", "",
+		"d", "Dynamic", "",
+	)
+	srv := newBatchServer(t, func(w http.ResponseWriter, body string) {
+		if strings.HasPrefix(body, "DESCRIBE TABLE") {
+			w.Header().Set(formatHeader, "RowBinaryWithNamesAndTypes")
+			_, _ = w.Write(describe)
+		}
+	})
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default?insert_format=rowbinary")
+	require.NoError(t, err)
+	defer db.Close()
+
+	type payload struct {
+		A struct {
+			B int `json:"b"`
+		} `json:"a"`
+		C []string `json:"c"`
+	}
+	p := payload{C: []string{"x"}}
+	p.A.B = 1
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	st, err := tx.Prepare("INSERT INTO data (j, v, d) VALUES (?, ?, ?)")
+	require.NoError(t, err)
+	_, err = st.Exec(p, uint64(2), "a")
+	require.NoError(t, err)
+	_, err = st.Exec(map[string]interface{}{}, nil, Variant{Type: "UInt8", Value: 3})
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, []string{
+		"DESCRIBE TABLE data",
+		"INSERT INTO data (j, v, d) FORMAT RowBinary\n" + string(rowBinary(
+			uint8(2), "a.b", uint32(1), "c", []byte{0x1e, 0x15}, uint8(1), "x", uint8(1), uint64(2), uint8(0x15), "a",
+			uint8(0), uint8(255), uint8(0x01), uint8(3),
+		)),
+	}, srv.received())
+}
+
+func TestJSONValue(t *testing.T) {
+	v, err := JSON(map[string]interface{}{"a": []int{1}, "b": "it's"}).Value()
+	require.NoError(t, err)
+	assert.Equal(t, `'{"a":[1],"b":"it\'s"}'`, string(v.([]byte)))
+
+	_, err = JSON(func() {}).Value()
+	assert.Error(t, err)
+}
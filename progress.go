@@ -0,0 +1,243 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	progressHeader               = "X-ClickHouse-Progress"
+	sendProgressInHeadersParam   = "send_progress_in_http_headers"
+	maxProgressHeaderLineLength  = 4 << 10
+	progressHeaderLinePrefixSize = len(progressHeader) + 1
+)
+
+// Progress is the progress of a query reported by ClickHouse
+// in X-ClickHouse-Progress headers
+type Progress struct {
+	ReadRows        uint64
+	ReadBytes       uint64
+	TotalRowsToRead uint64
+	WrittenRows     uint64
+	WrittenBytes    uint64
+	Elapsed         time.Duration
+}
+
+// WithProgress returns a context which enables sending the progress of
+// the query in HTTP headers and calls f for every received progress.
+// f is called while the query is still running, from the goroutine
+// reading the response.
+func WithProgress(ctx context.Context, f func(Progress)) context.Context {
+	return context.WithValue(ctx, ctxProgressKey, f)
+}
+
+func progressFromContext(ctx context.Context) func(Progress) {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(ctxProgressKey).(func(Progress))
+	return f
+}
+
+func parseProgress(s string) (Progress, bool) {
+	counters, err := parseCounters(s)
+	if err != nil {
+		return Progress{}, false
+	}
+	return Progress{
+		ReadRows:        counters["read_rows"],
+		ReadBytes:       counters["read_bytes"],
+		TotalRowsToRead: counters["total_rows_to_read"],
+		WrittenRows:     counters["written_rows"],
+		WrittenBytes:    counters["written_bytes"],
+		Elapsed:         time.Duration(counters["elapsed_ns"]),
+	}, true
+}
+
+// progressWatcher delivers the progress of a single request
+type progressWatcher struct {
+	f     func(Progress)
+	fired int32
+}
+
+// withTrace binds the watcher to the connection which will send the request
+func (w *progressWatcher) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if c, ok := info.Conn.(*progressConn); ok {
+				c.watch(w)
+			}
+		},
+	})
+}
+
+func (w *progressWatcher) report(p Progress) {
+	atomic.StoreInt32(&w.fired, 1)
+	w.f(p)
+}
+
+// reportHeader reports the progress from the response headers.
+// It is needed if the transport is not able to report it while
+// the headers are being received.
+func (w *progressWatcher) reportHeader(header http.Header) {
+	if atomic.LoadInt32(&w.fired) != 0 {
+		return
+	}
+	for _, v := range header.Values(progressHeader) {
+		if p, ok := parseProgress(v); ok {
+			w.report(p)
+		}
+	}
+}
+
+// progressTransport is the transport created by the driver. The requests with
+// a progress callback are sent by a copy of it, whose connections scan the response
+// headers, so the connections of other requests are not wrapped. The transports
+// set by WithRoundTripper and WithHTTPClient are never changed, the progress of
+// their requests is reported when the response headers have been received.
+type progressTransport struct {
+	*http.Transport
+
+	mu       sync.Mutex
+	progress *http.Transport
+}
+
+// withProgress returns the copy of the transport which reports the progress
+func (t *progressTransport) withProgress() *http.Transport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.progress == nil {
+		t.progress = t.Transport.Clone()
+		t.progress.DialContext = dialProgress(t.Transport.DialContext)
+		t.progress.DialTLSContext = dialTLSProgress(t.Transport.DialContext, t.Transport.TLSClientConfig)
+	}
+	return t.progress
+}
+
+// CloseIdleConnections closes the idle connections of both transports
+func (t *progressTransport) CloseIdleConnections() {
+	t.Transport.CloseIdleConnections()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.progress != nil {
+		t.progress.CloseIdleConnections()
+	}
+}
+
+// dialFunc is the type of http.Transport.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// progressConn scans the response headers while they are being received,
+// so the progress is reported before the query is finished
+type progressConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	watcher *progressWatcher
+	line    []byte
+}
+
+func (c *progressConn) watch(w *progressWatcher) {
+	c.mu.Lock()
+	c.watcher = w
+	c.line = c.line[:0]
+	c.mu.Unlock()
+}
+
+// Read implements net.Conn
+func (c *progressConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.scan(b[:n])
+	}
+	return n, err
+}
+
+func (c *progressConn) scan(data []byte) {
+	var progress []Progress
+
+	c.mu.Lock()
+	w := c.watcher
+	for c.watcher != nil && len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			c.appendLine(data)
+			break
+		}
+		c.appendLine(data[:i])
+		data = data[i+1:]
+		line := bytes.TrimSuffix(c.line, []byte("\r"))
+		c.line = c.line[:0]
+		if len(line) == 0 {
+			// the end of the headers, stop watching till the next request
+			c.watcher = nil
+			break
+		}
+		if len(line) > progressHeaderLinePrefixSize && line[len(progressHeader)] == ':' &&
+			strings.EqualFold(string(line[:len(progressHeader)]), progressHeader) {
+			if p, ok := parseProgress(string(bytes.TrimSpace(line[progressHeaderLinePrefixSize:]))); ok {
+				progress = append(progress, p)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for _, p := range progress {
+		w.report(p)
+	}
+}
+
+func (c *progressConn) appendLine(data []byte) {
+	if n := maxProgressHeaderLineLength - len(c.line); len(data) > n {
+		data = data[:n]
+	}
+	c.line = append(c.line, data...)
+}
+
+// dialProgress returns the dial function of the transport which
+// wraps the connections into progressConn
+func dialProgress(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &progressConn{Conn: conn}, nil
+	}
+}
+
+// dialTLSProgress is the same as dialProgress, but for TLS connections.
+// The headers are scanned after they have been decrypted.
+func dialTLSProgress(dial dialFunc, config *tls.Config) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg := config.Clone()
+		if cfg == nil {
+			cfg = &tls.Config{}
+		}
+		if cfg.ServerName == "" {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				host = addr
+			}
+			cfg.ServerName = host
+		}
+		tlsConn := tls.Client(conn, cfg)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		return &progressConn{Conn: tlsConn}, nil
+	}
+}
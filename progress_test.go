@@ -0,0 +1,184 @@
+package clickhouse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProgress(t *testing.T) {
+	p, ok := parseProgress(`{"read_rows":"10","read_bytes":"80","written_rows":"1","written_bytes":"8","total_rows_to_read":"100","elapsed_ns":"2000"}`)
+	assert.True(t, ok)
+	assert.Equal(t, Progress{
+		ReadRows:        10,
+		ReadBytes:       80,
+		TotalRowsToRead: 100,
+		WrittenRows:     1,
+		WrittenBytes:    8,
+		Elapsed:         2 * time.Microsecond,
+	}, p)
+
+	_, ok = parseProgress("{")
+	assert.False(t, ok)
+}
+
+func TestProgressConnScan(t *testing.T) {
+	var progress []Progress
+	c := &progressConn{}
+	c.watch(&progressWatcher{f: func(p Progress) {
+		progress = append(progress, p)
+	}})
+
+	data := "HTTP/1.1 200 OK\r\nx-clickhouse-progress: {\"read_rows\":\"1\"}\r\nX-ClickHouse-Progress: {\"read_rows\":\"2\"}\r\n\r\n" +
+		"X-ClickHouse-Progress: {\"read_rows\":\"3\"}\n"
+	// feed the data in small pieces to check lines split between reads
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		c.scan([]byte(data[i:end]))
+	}
+	assert.Equal(t, []Progress{{ReadRows: 1}, {ReadRows: 2}}, progress)
+	assert.Nil(t, c.watcher)
+}
+
+func TestWithProgressWhileRunning(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	progress := make(chan Progress, 10)
+	queryParams := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(io.Discard, req.Body)
+		queryParams <- req.URL.Query().Get(sendProgressInHeadersParam)
+
+		_, _ = fmt.Fprint(conn, "HTTP/1.1 200 OK\r\nX-ClickHouse-Progress: {\"read_rows\":\"5\",\"total_rows_to_read\":\"10\"}\r\n")
+		// the rest of the response is sent only when the first progress has been received
+		select {
+		case <-time.After(5 * time.Second):
+			return
+		case p := <-progress:
+			progress <- p
+		}
+		_, _ = fmt.Fprint(conn, "X-ClickHouse-Progress: {\"read_rows\":\"10\",\"total_rows_to_read\":\"10\"}\r\nContent-Length: 2\r\n\r\n1\n")
+	}()
+
+	cfg, err := ParseDSN("http://" + l.Addr().String() + "/default")
+	require.NoError(t, err)
+	cn := newConn(cfg)
+	defer cn.Close()
+
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		progress <- p
+	})
+	require.NoError(t, cn.Ping(ctx))
+	assert.Equal(t, "1", <-queryParams)
+	require.Len(t, progress, 2)
+	assert.Equal(t, Progress{ReadRows: 5, TotalRowsToRead: 10}, <-progress)
+	assert.Equal(t, Progress{ReadRows: 10, TotalRowsToRead: 10}, <-progress)
+}
+
+func TestProgressTransportScope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte("1\n"))
+	}))
+	defer srv.Close()
+
+	cfg, err := ParseDSN(srv.URL + "/default")
+	require.NoError(t, err)
+	cn := newConn(cfg)
+	defer cn.Close()
+
+	wrapped := func(ctx context.Context) bool {
+		var isProgressConn bool
+		ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				_, isProgressConn = info.Conn.(*progressConn)
+			},
+		})
+		require.NoError(t, cn.Ping(ctx))
+		return isProgressConn
+	}
+	// only the connections of the requests with a progress callback are wrapped
+	assert.False(t, wrapped(context.Background()))
+	assert.True(t, wrapped(WithProgress(context.Background(), func(Progress) {})))
+	assert.False(t, wrapped(context.Background()))
+}
+
+func TestWithProgressCustomTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Add(progressHeader, `{"read_rows":"1"}`)
+		w.Header().Add(progressHeader, `{"read_rows":"2"}`)
+		_, _ = w.Write([]byte("1\n"))
+	}))
+	defer srv.Close()
+
+	cfg, err := ParseDSN(srv.URL + "/default")
+	require.NoError(t, err)
+	connector, err := NewConnector(cfg, WithRoundTripper(http.DefaultTransport))
+	require.NoError(t, err)
+	cn, err := connector.Connect(context.Background())
+	require.NoError(t, err)
+	defer cn.Close()
+
+	var progress []Progress
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		progress = append(progress, p)
+	})
+	require.NoError(t, cn.(*conn).Ping(ctx))
+	assert.Equal(t, []Progress{{ReadRows: 1}, {ReadRows: 2}}, progress)
+}
+
+func TestWithProgressTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Add(progressHeader, `{"read_rows":"1"}`)
+		_, _ = w.Write([]byte("1\n"))
+	}))
+	defer srv.Close()
+
+	require.NoError(t, RegisterTLSConfig("progress-test", srv.Client().Transport.(*http.Transport).TLSClientConfig))
+	defer DeregisterTLSConfig("progress-test")
+
+	cfg, err := ParseDSN(srv.URL + "/default?tls_config=progress-test")
+	require.NoError(t, err)
+	cn := newConn(cfg)
+	defer cn.Close()
+
+	var progress []Progress
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		progress = append(progress, p)
+	})
+	require.NoError(t, cn.Ping(ctx))
+	assert.Equal(t, []Progress{{ReadRows: 1}}, progress)
+
+	// without the tls config the certificate of the test server is not trusted
+	cfg.TLSConfig = ""
+	cn2 := newConn(cfg)
+	defer cn2.Close()
+	assert.Error(t, cn2.Ping(context.Background()))
+}
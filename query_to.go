@@ -0,0 +1,113 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+const (
+	defaultFormatParamName = "default_format"
+	formatHeader           = "X-ClickHouse-Format"
+
+	// maxExceptionTailSize limits the size of the output kept to find an exception
+	maxExceptionTailSize = 64 << 10
+)
+
+// QueryTo executes the query and copies the result in the given format to w.
+// An explicit FORMAT clause of the query takes precedence over the format,
+// the format of the connection is used if neither of them is set.
+// An exception written on its own lines at the end of the output is returned as *Error,
+// it is copied to w as well; use wait_end_of_query to get the errors without partial output.
+func (c *conn) QueryTo(ctx context.Context, w io.Writer, query, format string, args ...interface{}) error {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return driver.ErrBadConn
+	}
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+		if a, ok := arg.(sql.NamedArg); ok {
+			named[i].Name, named[i].Value = a.Name, a.Value
+		}
+		if err := c.CheckNamedValue(&named[i]); err != nil {
+			return err
+		}
+	}
+	values, params, err := namedValueToValue(named)
+	if err != nil {
+		return err
+	}
+	ctx = withQueryParams(ctx, params)
+
+	req, err := c.buildRequest(ctx, query, values)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		reqQuery := req.URL.Query()
+		reqQuery.Set(defaultFormatParamName, format)
+		req.URL.RawQuery = reqQuery.Encode()
+	}
+	body, _, err := c.doRequest(ctx, req)
+	if err != nil {
+		if _, ok := err.(*Error); !ok && err != driver.ErrBadConn {
+			if killErr := c.killQuery(req); killErr != nil {
+				c.log("error from killQuery", killErr)
+			}
+		}
+		return err
+	}
+	defer func() {
+		c.cancel = nil
+		_ = body.Close()
+	}()
+
+	tw := &tailWriter{w: w}
+	if _, err = io.Copy(tw, body); err != nil {
+		return fmt.Errorf("clickhouse: failed to copy the result: %w", err)
+	}
+	if c.waitEndOfQuery {
+		// the server reports the errors by the status of the response
+		return nil
+	}
+	// the query may fail after a part of the result has been sent,
+	// then the exception is written at the end of the output
+	return outputException(tw.tail, tw.truncated)
+}
+
+// outputException returns the exception written at the end of the output in
+// an arbitrary format. The text of an exception could be a value of the result,
+// so only an exception on its own lines at the end of the output is taken:
+// the values in CSV, JSON and other formats are quoted or follow other values.
+func outputException(tail []byte, truncated bool) error {
+	data := string(tail)
+	start := exceptionStart(data)
+	switch {
+	case start < 0:
+		return nil
+	case start == 0 && truncated, start > 0 && data[start-1] != '\n':
+		return nil
+	}
+	return exceptionTrailer(data[start:])
+}
+
+// tailWriter keeps the last bytes written to w
+type tailWriter struct {
+	w         io.Writer
+	tail      []byte
+	truncated bool // whether the tail doesn't start at the beginning of the output
+}
+
+// Write implements io.Writer
+func (t *tailWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.tail = append(t.tail, p[:n]...)
+	if len(t.tail) > 2*maxExceptionTailSize {
+		t.tail = t.tail[:copy(t.tail, t.tail[len(t.tail)-maxExceptionTailSize:])]
+		t.truncated = true
+	}
+	return n, err
+}
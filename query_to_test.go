@@ -0,0 +1,111 @@
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryTo(t *testing.T) {
+	var params url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params = r.URL.Query()
+		switch r.URL.Query().Get(defaultFormatParamName) {
+		case "CSV":
+			_, _ = w.Write([]byte("1,\"a\"\n2,\"b\"\n"))
+		case "JSONEachRow":
+			_, _ = w.Write([]byte("{\"n\":1}\n"))
+			_, _ = w.Write([]byte("Code: 395. DB::Exception: Value passed to 'throwIf' function is non-zero: while executing 'FUNCTION throwIf(equals(number, 2)) :: 2'. (FUNCTION_THROW_IF_VALUE_IS_NON_ZERO) (version 23.8.1.1)\n"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Code: 73. DB::Exception: Unknown format Unknown. (UNKNOWN_FORMAT) (version 23.8.1.1)"))
+		}
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+	sqlConn, err := db.Conn(context.Background())
+	require.NoError(t, err)
+	defer sqlConn.Close()
+	queryTo := func(format string, args ...interface{}) (string, error) {
+		var buf bytes.Buffer
+		err := sqlConn.Raw(func(driverConn interface{}) error {
+			return driverConn.(Conn).QueryTo(context.Background(), &buf, "SELECT n, s FROM t WHERE n > ? AND s != {s:String}", format, args...)
+		})
+		return buf.String(), err
+	}
+
+	out, err := queryTo("CSV", 0, sql.Named("s", "c"))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1,\"a\"\n2,\"b\"\n", out)
+		assert.Equal(t, "c", params.Get(queryParamPrefix+"s"))
+	}
+
+	out, err = queryTo("JSONEachRow", 0, sql.Named("s", "c"))
+	assert.Equal(t, &Error{Code: 395, Message: "Value passed to 'throwIf' function is non-zero: while executing 'FUNCTION throwIf(equals(number, 2)) :: 2'"}, err)
+	assert.Contains(t, out, "{\"n\":1}\n")
+
+	_, err = queryTo("Unknown", 0, sql.Named("s", "c"))
+	assert.Equal(t, &Error{Code: 73, Message: "Unknown format Unknown"}, err)
+}
+
+func TestQueryToExceptionAsValue(t *testing.T) {
+	const exception = "Code: 60. DB::Exception: Unknown table expression identifier 'x'. (UNKNOWN_TABLE) (version 24.1.1.1)"
+	var output string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(output))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		dsn    string
+		output string
+		err    error
+	}{
+		// the exceptions from system.query_log are the values of the result
+		{"/default", "\"" + exception + "\"\n", nil},
+		{"/default", "{\"exception\":\"" + exception + "\"}\n", nil},
+		{"/default", "1\t" + exception + "\n", nil},
+		{"/default", "1\n" + exception + "\n", &Error{Code: 60, Message: "Unknown table expression identifier 'x'"}},
+		// the server reports the errors by the status with wait_end_of_query
+		{"/default?wait_end_of_query=1", "1\n" + exception + "\n", nil},
+	}
+	for _, tc := range testCases {
+		output = tc.output
+		db, err := sql.Open("chhttp", srv.URL+tc.dsn)
+		require.NoError(t, err)
+		sqlConn, err := db.Conn(context.Background())
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		err = sqlConn.Raw(func(driverConn interface{}) error {
+			return driverConn.(Conn).QueryTo(context.Background(), &buf, "SELECT exception FROM system.query_log", "CSV")
+		})
+		assert.Equal(t, tc.err, err, tc.output)
+		assert.Equal(t, tc.output, buf.String())
+		_ = sqlConn.Close()
+		_ = db.Close()
+	}
+}
+
+func TestQueryExplicitFormat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(formatHeader, "JSON")
+		_, _ = w.Write([]byte("{\"meta\": []}\n"))
+	}))
+	defer srv.Close()
+
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Query("SELECT 1 FORMAT JSON")
+	assert.EqualError(t, err, "clickhouse: the result in JSON format can't be read as rows, use QueryTo to copy it")
+}
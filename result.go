@@ -1,6 +1,9 @@
 package clickhouse
 
-import "database/sql/driver"
+import (
+	"database/sql/driver"
+	"net/http"
+)
 
 var emptyResult driver.Result = noResult{}
 
@@ -13,3 +16,37 @@ func (noResult) LastInsertId() (int64, error) {
 func (noResult) RowsAffected() (int64, error) {
 	return 0, ErrNoRowsAffected
 }
+
+func (noResult) QueryStats() (QueryStats, bool) {
+	return QueryStats{}, false
+}
+
+// result reports the number of written rows from X-ClickHouse-Summary
+type result struct {
+	noResult
+	stats  QueryStats
+	insert bool // the rows affected are known for INSERT statements only
+}
+
+func newResult(query string, header http.Header) driver.Result {
+	stats, ok := parseQueryStats(header)
+	if !ok {
+		return emptyResult
+	}
+	return &result{stats: stats, insert: statementKeyword(query) == "INSERT"}
+}
+
+// RowsAffected implements driver.Result
+func (r *result) RowsAffected() (int64, error) {
+	if !r.insert {
+		// written_rows of other statements like ALTER or CREATE ... AS SELECT
+		// are not the rows affected
+		return r.noResult.RowsAffected()
+	}
+	return int64(r.stats.WrittenRows), nil
+}
+
+// QueryStats implements QueryStatsReporter
+func (r *result) QueryStats() (QueryStats, bool) {
+	return r.stats, true
+}
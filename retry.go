@@ -0,0 +1,167 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const insertDeduplicationTokenParamName = "insert_deduplication_token"
+
+var (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Second
+	// defaultRetryableCodes are the server error codes after which
+	// the query may be successfully repeated
+	defaultRetryableCodes = []int{
+		159, // TIMEOUT_EXCEEDED
+		202, // TOO_MANY_SIMULTANEOUS_QUERIES
+		203, // NO_FREE_CONNECTION
+		209, // SOCKET_TIMEOUT
+		210, // NETWORK_ERROR
+	}
+)
+
+// RetryPolicy describes how requests failed with a transient error are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, retries are disabled if it is 0
+	MaxRetries int
+	// Backoff is the delay before the first retry, it is doubled after every retry (100ms by default)
+	Backoff time.Duration
+	// MaxBackoff limits the delay between retries (5s by default)
+	MaxBackoff time.Duration
+	// RetryableCodes are the server error codes to retry, defaultRetryableCodes are used if empty
+	RetryableCodes []int
+}
+
+// backoff returns the delay before the given retry with a random jitter
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+	for i := 0; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	// use a random delay from the upper half of the interval
+	// to avoid retrying all the requests at the same moment
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// retryable reports whether the request may be repeated after the error.
+// The statements which are not safe to repeat are retried only if they
+// never reached the server.
+func (p *RetryPolicy) retryable(err error, safe bool) bool {
+	if !safe {
+		return !reachedServer(err)
+	}
+	var srvErr *Error
+	if errors.As(err, &srvErr) {
+		codes := p.RetryableCodes
+		if len(codes) == 0 {
+			codes = defaultRetryableCodes
+		}
+		for _, code := range codes {
+			if srvErr.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		!reachedServer(err)
+}
+
+// wait sleeps before the given retry. It returns false if the context is done
+// or its deadline comes before the end of the delay.
+func (p *RetryPolicy) wait(ctx context.Context, retry int) bool {
+	delay := p.backoff(retry)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// reachedServer reports whether the request could have reached the server
+// before the error, so it is not known if the query has been executed.
+func reachedServer(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return false
+	}
+	var dnsErr *net.DNSError
+	return !errors.As(err, &dnsErr)
+}
+
+// isRetrySafe reports whether the query can be sent once again without
+// side effects. INSERTs are safe only with the deduplication token.
+func isRetrySafe(query string, params map[string][]string) bool {
+	switch statementKeyword(query) {
+	case "SELECT", "WITH", "SHOW", "DESCRIBE", "DESC", "EXISTS", "EXPLAIN":
+		return true
+	case "INSERT":
+		return len(params[insertDeduplicationTokenParamName]) > 0 &&
+			params[insertDeduplicationTokenParamName][0] != ""
+	}
+	return false
+}
+
+// statementKeyword returns the first keyword of the query in upper case,
+// skipping leading whitespace, comments and parentheses.
+func statementKeyword(query string) string {
+	for {
+		query = strings.TrimLeft(query, " \t\r\n(")
+		switch {
+		case strings.HasPrefix(query, "--"), strings.HasPrefix(query, "#"):
+			i := strings.IndexByte(query, '\n')
+			if i < 0 {
+				return ""
+			}
+			query = query[i+1:]
+			continue
+		case strings.HasPrefix(query, "/*"):
+			i := strings.Index(query, "*/")
+			if i < 0 {
+				return ""
+			}
+			query = query[i+2:]
+			continue
+		}
+		break
+	}
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_')
+	})
+	if end < 0 {
+		end = len(query)
+	}
+	return strings.ToUpper(query[:end])
+}
+
+// retrySafeFromRequest returns whether the request built by buildRequest is safe to repeat
+func retrySafeFromRequest(req *http.Request) bool {
+	safe, _ := req.Context().Value(ctxRetrySafeKey).(bool)
+	return safe
+}
@@ -0,0 +1,143 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementKeyword(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT"},
+		{"  \n\tselect 1", "SELECT"},
+		{"(SELECT 1) UNION ALL (SELECT 2)", "SELECT"},
+		{"-- comment\nINSERT INTO t VALUES (1)", "INSERT"},
+		{"/* comment */ insert INTO t VALUES (1)", "INSERT"},
+		{"# comment\nWITH 1 AS x SELECT x", "WITH"},
+		{"-- comment", ""},
+		{"/* unfinished", ""},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, statementKeyword(tc.query), tc.query)
+	}
+}
+
+func TestIsRetrySafe(t *testing.T) {
+	assert.True(t, isRetrySafe("SELECT 1", nil))
+	assert.True(t, isRetrySafe("DESCRIBE TABLE t", nil))
+	assert.False(t, isRetrySafe("INSERT INTO t VALUES (1)", nil))
+	assert.False(t, isRetrySafe("INSERT INTO t VALUES (1)", url.Values{insertDeduplicationTokenParamName: {""}}))
+	assert.True(t, isRetrySafe("INSERT INTO t VALUES (1)", url.Values{insertDeduplicationTokenParamName: {"token"}}))
+	assert.False(t, isRetrySafe("ALTER TABLE t DELETE WHERE 1", nil))
+	assert.False(t, isRetrySafe("KILL QUERY WHERE query_id='1'", nil))
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	p := &RetryPolicy{MaxRetries: 1}
+	dialErr := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	resetErr := &net.OpError{Op: "read", Err: syscall.ECONNRESET}
+
+	assert.True(t, p.retryable(fmt.Errorf("wrapped: %w", dialErr), true))
+	assert.True(t, p.retryable(fmt.Errorf("wrapped: %w", dialErr), false))
+	assert.True(t, p.retryable(resetErr, true))
+	assert.False(t, p.retryable(resetErr, false))
+	assert.True(t, p.retryable(&Error{Code: 202}, true))
+	assert.True(t, p.retryable(&Error{Code: 159}, true))
+	assert.False(t, p.retryable(&Error{Code: 202}, false))
+	assert.False(t, p.retryable(&Error{Code: 60}, true))
+	assert.False(t, p.retryable(errors.New("some error"), true))
+
+	p.RetryableCodes = []int{60}
+	assert.True(t, p.retryable(&Error{Code: 60}, true))
+	assert.False(t, p.retryable(&Error{Code: 202}, true))
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := &RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	testCases := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+	for _, tc := range testCases {
+		for i := 0; i < 10; i++ {
+			d := p.backoff(tc.retry)
+			assert.True(t, d >= tc.min && d <= tc.max, "retry %d: %s", tc.retry, d)
+		}
+	}
+}
+
+func TestRetryPolicyWaitDeadline(t *testing.T) {
+	p := &RetryPolicy{Backoff: time.Second}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.False(t, p.wait(ctx, 0))
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
+
+func TestConnRetry(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("Code: 202. DB::Exception: Too many simultaneous queries. Maximum: 1. (TOO_MANY_SIMULTANEOUS_QUERIES) (version 24.1.1.1)"))
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	cfg, err := ParseDSN(srv.URL + "/default?max_retries=2&retry_backoff=1ms")
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.RetryPolicy.MaxRetries)
+	assert.Equal(t, time.Millisecond, cfg.RetryPolicy.Backoff)
+	cn := newConn(cfg)
+	defer cn.Close()
+
+	req, err := cn.buildRequest(context.Background(), "SELECT 1", nil)
+	require.NoError(t, err)
+	body, _, err := cn.doRequest(context.Background(), req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(body)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 1", string(b))
+	assert.NoError(t, body.Close())
+	assert.Equal(t, 3, attempts)
+
+	// INSERT has reached the server, so it must not be repeated
+	attempts = 0
+	_, err = cn.exec(context.Background(), "INSERT INTO t VALUES (1)", nil)
+	assert.Equal(t, &Error{Code: 202, Message: "Too many simultaneous queries. Maximum: 1"}, err)
+	assert.Equal(t, 1, attempts)
+
+	// unless it has the deduplication token
+	attempts = 0
+	ctx := context.WithValue(context.Background(), RequestQueryParams, map[string]string{
+		insertDeduplicationTokenParamName: "token",
+	})
+	_, err = cn.exec(ctx, "INSERT INTO t VALUES (1)", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"io"
+	"net/http"
 	"reflect"
 	"strings"
 	"time"
@@ -11,6 +12,8 @@ import (
 
 type dataReader interface {
 	Read() (record []string, err error)
+	atEOF() bool
+	readRest() (string, error)
 }
 
 func newTextRows(c *conn, body io.ReadCloser, location *time.Location, useDBLocation bool) (*textRows, error) {
@@ -18,10 +21,21 @@ func newTextRows(c *conn, body io.ReadCloser, location *time.Location, useDBLoca
 
 	columns, err := tsvReader.Read()
 	if err != nil {
+		if excErr := readException(tsvReader, columns); excErr != nil {
+			return nil, excErr
+		}
 		return nil, fmt.Errorf("newTextRows: failed to parse the list of columns: %w", err)
 	}
+	if excErr := readException(tsvReader, columns); excErr != nil {
+		return nil, excErr
+	}
 
 	types, err := tsvReader.Read()
+	if err != nil || len(types) != len(columns) {
+		if excErr := readException(tsvReader, types); excErr != nil {
+			return nil, excErr
+		}
+	}
 	if err != nil {
 		return nil, fmt.Errorf("newTextRows: failed to parse the list of column types: %w", err)
 	}
@@ -40,8 +54,11 @@ func newTextRows(c *conn, body io.ReadCloser, location *time.Location, useDBLoca
 		}
 
 		parsers[i], err = NewDataParser(desc, &DataParserOptions{
-			Location:      location,
-			UseDBLocation: useDBLocation,
+			Location:          location,
+			UseDBLocation:     useDBLocation,
+			UUIDAndIPAsString: c.uuidAndIPAsString,
+			JSONAsRaw:         c.jsonAsRaw,
+			DecimalAsString:   c.decimalAsString,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("newTextRows: failed to create a data parser for the type '%s': %w", typ, err)
@@ -65,6 +82,7 @@ type textRows struct {
 	columns  []string
 	types    []string
 	parsers  []DataParser
+	rowsStats
 }
 
 func (r *textRows) Columns() []string {
@@ -77,7 +95,7 @@ func (r *textRows) Close() error {
 }
 
 func (r *textRows) Next(dest []driver.Value) error {
-	row, err := r.tsv.Read()
+	row, err := r.read()
 	if err != nil {
 		return err
 	}
@@ -85,27 +103,91 @@ func (r *textRows) Next(dest []driver.Value) error {
 	// skip row before WITH TOTALS,
 	// not but do not skip an empty line if it is part of the result
 	if len(row) == 1 && row[0] == "" {
-		row, err = r.tsv.Read()
+		row, err = r.read()
 		if err != nil {
 			return err
 		}
 	}
+	if len(row) != len(r.parsers) {
+		return fmt.Errorf("unexpected number of values %d in a row, expected %d", len(row), len(r.parsers))
+	}
 
 	for i, s := range row {
 		reader := strings.NewReader(s)
 		v, err := r.parsers[i].Parse(reader)
+		if err == nil {
+			if _, _, errRead := reader.ReadRune(); errRead != io.EOF {
+				err = fmt.Errorf("trailing data after parsing the value")
+			}
+		}
 		if err != nil {
+			if r.tsv.atEOF() {
+				if excErr := readException(r.tsv, row); excErr != nil {
+					return excErr
+				}
+			}
 			return err
 		}
-		if _, _, err := reader.ReadRune(); err != io.EOF {
-			return fmt.Errorf("trailing data after parsing the value")
-		}
 		dest[i] = v
 	}
 
 	return nil
 }
 
+// validate reads all the rows without parsing the values, so an exception
+// at the end of the response is found before any row is returned
+func (r *textRows) validate() error {
+	for {
+		row, err := r.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) != len(r.parsers) && !(len(row) == 1 && row[0] == "") {
+			return fmt.Errorf("unexpected number of values %d in a row, expected %d", len(row), len(r.parsers))
+		}
+	}
+}
+
+// read reads the next row and checks that it is not an exception written
+// by ClickHouse after a part of the result has been already sent
+func (r *textRows) read() ([]string, error) {
+	row, err := r.tsv.Read()
+	if err == io.EOF && len(row) == 1 && row[0] == "" {
+		return nil, err
+	}
+	// the exception either starts a new line or follows a partially written row,
+	// a row with the right number of values could be a valid one which just contains
+	// the text of an exception, so it is checked only if it fails to parse or if it is
+	// the last line starting with an exception like the one of a String column does
+	if err != nil || len(row) != len(r.columns) || exceptionStart(strings.Join(row, "\t")) == 0 && r.tsv.atEOF() {
+		if excErr := readException(r.tsv, row); excErr != nil {
+			return nil, excErr
+		}
+	}
+	return row, err
+}
+
+// readException returns the exception if the record and the rest of the data
+// are the exception written at the end of the response
+func readException(tsv dataReader, record []string) error {
+	line := strings.Join(record, "\t")
+	if !strings.Contains(line, exceptionMarker) {
+		return nil
+	}
+	if !tsv.atEOF() {
+		// the message of the exception could contain new lines
+		rest, err := tsv.readRest()
+		if err != nil {
+			return fmt.Errorf("failed to read the data after an exception: %w", err)
+		}
+		line += "\n" + rest
+	}
+	return exceptionTrailer(line)
+}
+
 // ColumnTypeScanType implements the driver.RowsColumnTypeScanType
 func (r *textRows) ColumnTypeScanType(index int) reflect.Type {
 	return r.parsers[index].Type()
@@ -115,3 +197,154 @@ func (r *textRows) ColumnTypeScanType(index int) reflect.Type {
 func (r *textRows) ColumnTypeDatabaseTypeName(index int) string {
 	return r.types[index]
 }
+
+// queryRows is implemented by textRows and binaryRows
+type queryRows interface {
+	driver.Rows
+	QueryStatsReporter
+	validate() error
+	setStats(header http.Header)
+}
+
+func newBinaryRows(c *conn, body io.ReadCloser, location *time.Location, useDBLocation bool, serverLocation *time.Location) (*binaryRows, error) {
+	reader := newBinaryReader(body)
+
+	n, err := reader.readUvarint()
+	if err != nil {
+		if excErr := readBinaryException(reader); excErr != nil {
+			return nil, excErr
+		}
+		return nil, fmt.Errorf("newBinaryRows: failed to read the number of columns: %w", err)
+	}
+	if n > maxBinaryStringSize {
+		return nil, fmt.Errorf("newBinaryRows: unexpected number of columns %d", n)
+	}
+	columns := make([]string, n)
+	types := make([]string, n)
+	for _, list := range [][]string{columns, types} {
+		for i := range list {
+			if list[i], err = reader.readString(); err != nil {
+				if excErr := readBinaryException(reader); excErr != nil {
+					return nil, excErr
+				}
+				return nil, fmt.Errorf("newBinaryRows: failed to read the list of columns: %w", err)
+			}
+		}
+	}
+
+	parsers := make([]binaryParser, len(types))
+	for i, typ := range types {
+		desc, err := ParseTypeDesc(typ)
+		if err != nil {
+			return nil, fmt.Errorf("newBinaryRows: failed to parse a description of the type '%s': %w", typ, err)
+		}
+
+		parsers[i], err = newBinaryParser(desc, &DataParserOptions{
+			Location:          location,
+			UseDBLocation:     useDBLocation,
+			UUIDAndIPAsString: c.uuidAndIPAsString,
+			JSONAsRaw:         c.jsonAsRaw,
+			DecimalAsString:   c.decimalAsString,
+		}, serverLocation)
+		if err != nil {
+			return nil, fmt.Errorf("newBinaryRows: failed to create a data parser for the type '%s': %w", typ, err)
+		}
+	}
+
+	return &binaryRows{
+		c:        c,
+		respBody: body,
+		reader:   reader,
+		columns:  columns,
+		types:    types,
+		parsers:  parsers,
+	}, nil
+}
+
+// binaryRows reads the result in RowBinaryWithNamesAndTypes format
+type binaryRows struct {
+	c        *conn
+	respBody io.ReadCloser
+	reader   *binaryReader
+	columns  []string
+	types    []string
+	parsers  []binaryParser
+	rowsStats
+}
+
+func (r *binaryRows) Columns() []string {
+	return r.columns
+}
+
+func (r *binaryRows) Close() error {
+	r.c.cancel = nil
+	return r.respBody.Close() // This also cancels the request context.
+}
+
+func (r *binaryRows) Next(dest []driver.Value) error {
+	if r.reader.atEOF() {
+		return io.EOF
+	}
+	if err := r.reader.exception(); err != nil {
+		return err
+	}
+	r.reader.startRow()
+	for i, p := range r.parsers {
+		v, err := p.decode(r.reader)
+		if err != nil {
+			if excErr := readBinaryException(r.reader); excErr != nil {
+				return excErr
+			}
+			return fmt.Errorf("failed to decode the value of the column %s: %w", r.columns[i], err)
+		}
+		dest[i] = v
+	}
+	return nil
+}
+
+// validate decodes all the rows, so an exception at the end of
+// the response is found before any row is returned
+func (r *binaryRows) validate() error {
+	dest := make([]driver.Value, len(r.parsers))
+	for {
+		err := r.Next(dest)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readBinaryException returns the exception if the last read data and the rest of
+// the data end with the exception written by ClickHouse after a part of the result
+func readBinaryException(reader *binaryReader) error {
+	data := string(reader.tail)
+	if !reader.atEOF() {
+		rest, err := reader.readRest()
+		if err != nil {
+			return fmt.Errorf("failed to read the data after an exception: %w", err)
+		}
+		data += rest
+	}
+	start := exceptionStart(data)
+	if start < 0 {
+		return nil
+	}
+	// the exception is a text, so it is not a string value followed by binary data
+	if strings.IndexFunc(data[start:], notText) >= 0 {
+		return nil
+	}
+	return newError(data[start:])
+}
+
+// ColumnTypeScanType implements the driver.RowsColumnTypeScanType
+func (r *binaryRows) ColumnTypeScanType(index int) reflect.Type {
+	return r.parsers[index].Type()
+}
+
+// ColumnTypeDatabaseTypeName implements the driver.RowsColumnTypeDatabaseTypeName
+func (r *binaryRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index]
+}
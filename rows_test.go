@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"database/sql/driver"
 	"io"
+	"os"
 	"reflect"
 	"testing"
 	"time"
@@ -160,3 +161,259 @@ func TestFloat64RowsEmpty(t *testing.T) {
 	}
 	assert.Equal(t, []driver.Value{float64(1)}, dest)
 }
+
+func TestTextRowsException(t *testing.T) {
+	const exception = "Code: 241. DB::Exception: Memory limit (total) exceeded: would use 9.31 GiB. (MEMORY_LIMIT_EXCEEDED) (version 23.8.1.1)\n"
+	testCases := []struct {
+		name string
+		data string
+		rows int
+	}{
+		{"exception on a new line", "Number\tText\nInt32\tString\n1\thello\n" + exception, 1},
+		{"exception after a partial row", "Number\tText\nInt32\tString\n1\thello\n2" + exception, 1},
+		{"exception after a partial value", "Text\tNumber\nString\tInt32\nhello\t1\nworld\t2" + exception, 1},
+		{"multiline exception", "Number\tText\nInt32\tString\n1\thello\n" + "Code: 62. DB::Exception: Syntax error:\nfailed at position 1. (SYNTAX_ERROR)\n", 1},
+		{"exception without rows", "Number\tText\nInt32\tString\n" + exception, 0},
+		{"exception in a one column result", "Number\nInt32\n1\n" + exception, 1},
+		{"exception in a one column String result", "Text\nString\nhello\n" + exception, 1},
+		{"exception without rows in a one column String result", "Text\nString\n" + exception, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(tt *testing.T) {
+			buf := bytes.NewReader([]byte(tc.data))
+			rows, err := newTextRows(&conn{}, &bufReadCloser{buf}, time.Local, false)
+			if !assert.NoError(tt, err) {
+				return
+			}
+			dest := make([]driver.Value, 2)
+			for i := 0; i < tc.rows; i++ {
+				assert.NoError(tt, rows.Next(dest))
+			}
+			err = rows.Next(dest)
+			srvErr, ok := err.(*Error)
+			if assert.True(tt, ok, "unexpected error: %v", err) {
+				assert.NotZero(tt, srvErr.Code)
+			}
+		})
+	}
+}
+
+func TestTextRowsStreaming(t *testing.T) {
+	// a row which has been received is returned without waiting for the next one
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write([]byte("Number\tText\nInt32\tString\n1\thello\n"))
+	rows, err := newTextRows(&conn{}, pr, time.Local, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := make([]driver.Value, 2)
+	done := make(chan error, 1)
+	go func() { done <- rows.Next(dest) }()
+	select {
+	case err := <-done:
+		if assert.NoError(t, err) {
+			assert.Equal(t, []driver.Value{int32(1), "hello"}, dest)
+		}
+	case <-time.After(time.Second):
+		t.Error("the row is not returned until the response is finished")
+	}
+}
+
+func TestTextRowsExceptionInHeader(t *testing.T) {
+	buf := bytes.NewReader([]byte("Code: 60. DB::Exception: Unknown table expression identifier 'x'. (UNKNOWN_TABLE) (version 24.1.1.1)\n"))
+	_, err := newTextRows(&conn{}, &bufReadCloser{buf}, time.Local, false)
+	srvErr, ok := err.(*Error)
+	if assert.True(t, ok, "unexpected error: %v", err) {
+		assert.Equal(t, 60, srvErr.Code)
+	}
+
+	buf = bytes.NewReader([]byte("Number\tText\nCode: 60. DB::Exception: Unknown table expression identifier 'x'. (UNKNOWN_TABLE) (version 24.1.1.1)\n"))
+	_, err = newTextRows(&conn{}, &bufReadCloser{buf}, time.Local, false)
+	srvErr, ok = err.(*Error)
+	if assert.True(t, ok, "unexpected error: %v", err) {
+		assert.Equal(t, 60, srvErr.Code)
+	}
+}
+
+func TestTextRowsExceptionAsValue(t *testing.T) {
+	// the text of an exception could be a valid value, e.g. in system.query_log
+	buf := bytes.NewReader([]byte("query_id\texception\nString\tString\n1\tCode: 60. DB::Exception: Unknown table. (UNKNOWN_TABLE) (version 24.1.1.1)\n"))
+	rows, err := newTextRows(&conn{}, &bufReadCloser{buf}, time.Local, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := make([]driver.Value, 2)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{"1", "Code: 60. DB::Exception: Unknown table. (UNKNOWN_TABLE) (version 24.1.1.1)"}, dest)
+	}
+	assert.Equal(t, io.EOF, rows.Next(dest))
+}
+
+func TestTextRowsWrongNumberOfValues(t *testing.T) {
+	buf := bytes.NewReader([]byte("Number\tText\nInt32\tString\n1\thello\tworld\n"))
+	rows, err := newTextRows(&conn{}, &bufReadCloser{buf}, time.Local, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := make([]driver.Value, 2)
+	assert.EqualError(t, rows.Next(dest), "unexpected number of values 3 in a row, expected 2")
+}
+
+func TestBufferedRows(t *testing.T) {
+	const data = "Number\tText\nInt32\tString\n1\thello\n2\tworld\n"
+	for _, maxMemory := range []int64{0, 10, 1 << 20} {
+		c := &conn{waitEndOfQuery: true, bufferMaxMemory: maxMemory}
+		queryRows, err := c.bufferedRows(&bufReadCloser{bytes.NewReader([]byte(data))}, nil)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		rows := queryRows.(*textRows)
+		var fileName string
+		if f := rows.respBody.(*bufferedBody).file; f != nil {
+			fileName = f.Name()
+		}
+		assert.Equal(t, maxMemory == 10, fileName != "")
+
+		dest := make([]driver.Value, 2)
+		if assert.NoError(t, rows.Next(dest)) {
+			assert.Equal(t, []driver.Value{int32(1), "hello"}, dest)
+		}
+		if assert.NoError(t, rows.Next(dest)) {
+			assert.Equal(t, []driver.Value{int32(2), "world"}, dest)
+		}
+		assert.Equal(t, io.EOF, rows.Next(dest))
+		assert.NoError(t, rows.Close())
+		if fileName != "" {
+			_, err = os.Stat(fileName)
+			assert.True(t, os.IsNotExist(err))
+		}
+
+		// no rows are returned if the query has failed
+		_, err = c.bufferedRows(&bufReadCloser{bytes.NewReader([]byte(data + "3\tfoo\n4" +
+			"Code: 241. DB::Exception: Memory limit (total) exceeded. (MEMORY_LIMIT_EXCEEDED) (version 23.8.1.1)\n"))}, nil)
+		srvErr, ok := err.(*Error)
+		if assert.True(t, ok, "unexpected error: %v", err) {
+			assert.Equal(t, 241, srvErr.Code)
+		}
+
+		// the exception is found even if it looks like a row of a String column
+		_, err = c.bufferedRows(&bufReadCloser{bytes.NewReader([]byte("Text\nString\nhello\n" +
+			"Code: 241. DB::Exception: Memory limit (total) exceeded. (MEMORY_LIMIT_EXCEEDED) (version 23.8.1.1)\n"))}, nil)
+		srvErr, ok = err.(*Error)
+		if assert.True(t, ok, "unexpected error: %v", err) {
+			assert.Equal(t, 241, srvErr.Code)
+		}
+	}
+}
+
+func TestBinaryRows(t *testing.T) {
+	data := rowBinary(uint8(2), "Number", "Text", "Int32", "String",
+		int32(1), "hello", int32(2), "world")
+	rows, err := newBinaryRows(&conn{}, &bufReadCloser{bytes.NewReader(data)}, time.Local, false, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"Number", "Text"}, rows.Columns())
+	assert.Equal(t, reflect.TypeOf(int32(0)), rows.ColumnTypeScanType(0))
+	assert.Equal(t, reflect.TypeOf(""), rows.ColumnTypeScanType(1))
+	assert.Equal(t, "Int32", rows.ColumnTypeDatabaseTypeName(0))
+	assert.Equal(t, "String", rows.ColumnTypeDatabaseTypeName(1))
+
+	dest := make([]driver.Value, 2)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{int32(1), "hello"}, dest)
+	}
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{int32(2), "world"}, dest)
+	}
+	assert.Equal(t, io.EOF, rows.Next(dest))
+	assert.NoError(t, rows.Close())
+}
+
+func TestBinaryRowsException(t *testing.T) {
+	const exception = "Code: 241. DB::Exception: Memory limit (total) exceeded. (MEMORY_LIMIT_EXCEEDED) (version 23.8.1.1)\n"
+	header := rowBinary(uint8(2), "Number", "Text", "Int32", "String")
+	testCases := [][]byte{
+		// after a row
+		append(rowBinary(header, int32(1), "hello"), exception...),
+		// in the middle of a row
+		append(rowBinary(header, int32(1), "hello", int32(2)), exception...),
+		// instead of the header
+		[]byte(exception),
+		// after a row of fixed size values, which the text could be decoded as
+		append(rowBinary(uint8(1), "Number", "UInt32", uint32(1)), exception...),
+		append(rowBinary(uint8(1), "Number", "UInt32", uint32(1), uint8(2)), exception...),
+	}
+	for _, data := range testCases {
+		var err error
+		rows, err := newBinaryRows(&conn{}, &bufReadCloser{bytes.NewReader(data)}, time.Local, false, nil)
+		if err == nil {
+			dest := make([]driver.Value, 2)
+			for err == nil {
+				err = rows.Next(dest)
+			}
+		}
+		srvErr, ok := err.(*Error)
+		if assert.True(t, ok, "unexpected error: %v", err) {
+			assert.Equal(t, 241, srvErr.Code)
+			assert.Equal(t, "Memory limit (total) exceeded", srvErr.Message)
+		}
+	}
+}
+
+func TestBinaryRowsExceptionAsValue(t *testing.T) {
+	const exception = "Code: 60. DB::Exception: Unknown table. (UNKNOWN_TABLE) (version 24.1.1.1)"
+	data := rowBinary(uint8(2), "query_id", "exception", "UInt32", "String", uint32(1), exception)
+	rows, err := newBinaryRows(&conn{}, &bufReadCloser{bytes.NewReader(data)}, time.Local, false, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := make([]driver.Value, 2)
+	if assert.NoError(t, rows.Next(dest)) {
+		assert.Equal(t, []driver.Value{uint32(1), exception}, dest)
+	}
+	assert.Equal(t, io.EOF, rows.Next(dest))
+}
+
+func TestBinaryRowsStreaming(t *testing.T) {
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	go pw.Write(rowBinary(uint8(2), "Number", "Text", "Int32", "String", int32(1), "hello"))
+	rows, err := newBinaryRows(&conn{}, pr, time.Local, false, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	dest := make([]driver.Value, 2)
+	done := make(chan error, 1)
+	go func() { done <- rows.Next(dest) }()
+	select {
+	case err := <-done:
+		if assert.NoError(t, err) {
+			assert.Equal(t, []driver.Value{int32(1), "hello"}, dest)
+		}
+	case <-time.After(time.Second):
+		t.Error("the row is not returned until the response is finished")
+	}
+}
+
+func TestBufferedBinaryRows(t *testing.T) {
+	data := rowBinary(uint8(1), "Number", "Int32", int32(1), int32(2))
+	c := &conn{waitEndOfQuery: true, rowBinary: true}
+	rows, err := c.bufferedRows(&bufReadCloser{bytes.NewReader(data)}, nil)
+	if assert.NoError(t, err) {
+		dest := make([]driver.Value, 1)
+		assert.NoError(t, rows.Next(dest))
+		assert.NoError(t, rows.Next(dest))
+		assert.Equal(t, []driver.Value{int32(2)}, dest)
+		assert.Equal(t, io.EOF, rows.Next(dest))
+		assert.NoError(t, rows.Close())
+	}
+
+	data = append(data, "Code: 241. DB::Exception: Memory limit (total) exceeded. (MEMORY_LIMIT_EXCEEDED)\n"...)
+	_, err = c.bufferedRows(&bufReadCloser{bytes.NewReader(data)}, nil)
+	srvErr, ok := err.(*Error)
+	if assert.True(t, ok, "unexpected error: %v", err) {
+		assert.Equal(t, 241, srvErr.Code)
+	}
+}
@@ -0,0 +1,324 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// structTagName is the tag which sets the column name of a struct field
+// and its options, the fields tagged with "-" are skipped
+const structTagName = "ch"
+
+var (
+	reflectTypeScanner = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	reflectTypeValuer  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+	// structFieldsCache keeps the fields of the scanned struct types
+	structFieldsCache sync.Map // map[reflect.Type][]structField
+)
+
+// Querier is implemented by sql.DB, sql.Conn and sql.Tx
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// structField is a field of a struct which corresponds to a column
+type structField struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool // the zero value is not inserted, so the column gets its default
+	isDefault bool // the column is never inserted, its value is computed by ClickHouse
+	tuple     bool // the struct is a tuple instead of a group of columns
+}
+
+// structFields returns the fields of the struct type including the fields of
+// embedded and nested structs, the fields of the outer struct hide the embedded ones.
+// The columns of a nested struct are prefixed with the name of its field and _.
+func structFields(t reflect.Type) []structField {
+	if fields, ok := structFieldsCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields := appendStructFields(nil, t, nil, "", make(map[string]bool))
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+func appendStructFields(fields []structField, t reflect.Type, index []int, prefix string, names map[string]bool) []structField {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, options, _ := strings.Cut(f.Tag.Get(structTagName), ",")
+		if name == "-" {
+			continue
+		}
+		field := structField{typ: f.Type, index: append(append([]int(nil), index...), i)}
+		for _, option := range strings.Split(options, ",") {
+			switch option {
+			case "omitempty":
+				field.omitEmpty = true
+			case "default":
+				field.isDefault = true
+			case "tuple":
+				field.tuple = true
+			}
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, f)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		if !field.tuple && flattened(ft) {
+			fields = appendStructFields(fields, ft, field.index, prefix+name+"_", names)
+			continue
+		}
+		field.name = prefix + name
+		if names[field.name] {
+			continue
+		}
+		names[field.name] = true
+		fields = append(fields, field)
+	}
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			if !f.IsExported() {
+				// a pointer to an unexported struct can't be allocated
+				continue
+			}
+			ft = ft.Elem()
+		}
+		fields = appendStructFields(fields, ft, append(append([]int(nil), index...), f.Index...), prefix, names)
+	}
+	return fields
+}
+
+// flattened reports whether the fields of the nested struct are separate columns,
+// the structs which are values themselves like time.Time, Decimal or netip.Addr
+// and the structs without exported fields like big.Int are a single column
+func flattened(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == reflectTypeTime ||
+		reflect.PtrTo(t).Implements(reflectTypeScanner) || t.Implements(reflectTypeValuer) {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// snakeCase converts the name of a field to the name of a column, e.g. UserID to user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// structScanner scans the rows into the fields of a struct type
+type structScanner struct {
+	fields []structField      // the fields of the columns
+	enums  []map[string]int16 // the values of the Enum columns scanned into integer fields
+	tuples []bool             // the Tuple columns scanned into struct fields
+}
+
+// newStructScanner matches the columns of the rows with the fields of the struct type
+// and checks that the values of the columns can be scanned into the fields
+func newStructScanner(rows *sql.Rows, t reflect.Type) (*structScanner, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("clickhouse: expected a struct, got %s", t)
+	}
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]structField)
+	for _, f := range structFields(t) {
+		byName[f.name] = f
+	}
+	s := &structScanner{
+		fields: make([]structField, len(columns)),
+		enums:  make([]map[string]int16, len(columns)),
+		tuples: make([]bool, len(columns)),
+	}
+	for i, column := range columns {
+		f, ok := byName[column.Name()]
+		if !ok {
+			return nil, fmt.Errorf("clickhouse: no field for the column %s in %s", column.Name(), t)
+		}
+		s.fields[i] = f
+		if values := enumValues(column.DatabaseTypeName()); values != nil && isIntegerKind(indirectType(f.typ).Kind()) {
+			// the names of the values are scanned into integer fields as their codes
+			s.enums[i] = values
+			continue
+		}
+		scanType := column.ScanType()
+		if scanType != nil && !scannable(scanType, f.typ) && tupleColumn(column.DatabaseTypeName()) {
+			// the tuples are scanned into structs, the elements are checked when they are scanned
+			s.tuples[i] = true
+			continue
+		}
+		if scanType != nil && !scannable(scanType, f.typ) {
+			return nil, fmt.Errorf("clickhouse: the column %s of type %s can't be scanned into the field %s of type %s",
+				column.Name(), column.DatabaseTypeName(), t.FieldByIndex(f.index).Name, f.typ)
+		}
+	}
+	return s, nil
+}
+
+// enumValues returns the values of an Enum column or nil for other types
+func enumValues(typ string) map[string]int16 {
+	desc, err := ParseTypeDesc(typ)
+	if err != nil {
+		return nil
+	}
+	if desc = enumType(desc); desc == nil {
+		return nil
+	}
+	return desc.Enum
+}
+
+// indirectType returns the type the pointer type points to
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// scan scans the current row into the struct v, which must be addressable
+func (s *structScanner) scan(rows *sql.Rows, v reflect.Value) error {
+	dest := make([]interface{}, len(s.fields))
+	for i, f := range s.fields {
+		dest[i] = fieldByIndex(v, f.index).Addr().Interface()
+		if s.enums[i] != nil {
+			dest[i] = &enumCode{values: s.enums[i], dest: dest[i]}
+		}
+		if s.tuples[i] {
+			dest[i] = &tupleStruct{dest: dest[i]}
+		}
+	}
+	return rows.Scan(dest...)
+}
+
+// fieldByIndex returns the nested field, allocating the nil embedded structs
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// scannable reports whether sql.Rows.Scan converts the values of src type to dst type
+func scannable(src, dst reflect.Type) bool {
+	if src == reflectTypeEmptyStruct || reflect.PtrTo(dst).Implements(reflectTypeScanner) {
+		// the values of Nothing columns are NULL
+		return true
+	}
+	if src.AssignableTo(dst) {
+		// the pointers like *big.Int
+		return true
+	}
+	for dst.Kind() == reflect.Ptr {
+		dst = dst.Elem()
+	}
+	if src.AssignableTo(dst) || dst.Kind() == reflect.Interface && src.Implements(dst) {
+		return true
+	}
+	switch {
+	case isFloatKind(dst.Kind()):
+		return isIntegerKind(src.Kind()) || isFloatKind(src.Kind()) || src == reflectTypeDecimal
+	case isIntegerKind(dst.Kind()):
+		// the fractional values are not converted to integers
+		return isIntegerKind(src.Kind())
+	case dst.Kind() == reflect.Bool:
+		return src.Kind() == reflect.Bool || isIntegerKind(src.Kind())
+	case dst.Kind() == reflect.String, dst.Kind() == reflect.Slice && dst.Elem().Kind() == reflect.Uint8:
+		return src == reflectTypeTime || src.Kind() == reflect.String || src.Kind() == reflect.Bool || isNumericKind(src.Kind())
+	}
+	return false
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	return isIntegerKind(k) || isFloatKind(k)
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// ScanStruct scans the current row into the struct pointed to by dst.
+// The columns are matched with the fields by the ch tag or by the snake_case
+// name of the field, the fields of embedded and nested structs are matched as well.
+func ScanStruct(rows *sql.Rows, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("clickhouse: expected a pointer to a struct, got %T", dst)
+	}
+	s, err := newStructScanner(rows, v.Type().Elem())
+	if err != nil {
+		return err
+	}
+	return s.scan(rows, v.Elem())
+}
+
+// Select executes the query and scans all rows of the result into structs like ScanStruct does
+func Select[T any](ctx context.Context, q Querier, query string, args ...interface{}) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	s, err := newStructScanner(rows, reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return nil, err
+	}
+	var result []T
+	for rows.Next() {
+		var item T
+		if err = s.scan(rows, reflect.ValueOf(&item).Elem()); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
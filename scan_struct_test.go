@@ -0,0 +1,148 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStructServer(t *testing.T) *sql.DB {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("user_id\tname\tevent_time\tscore\ttags\tcomment\n" +
+			"UInt64\tString\tDateTime\tFloat64\tArray(String)\tNullable(String)\n" +
+			"1\talice\t2011-03-06 06:20:00\t1.5\t['a','b']\t\\N\n" +
+			"2\tbob\t2011-03-07 06:20:00\t2\t[]\tok\n"))
+	}))
+	t.Cleanup(srv.Close)
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+type ScanEvent struct {
+	EventTime time.Time
+	Score     float64
+	Tags      []string
+}
+
+type structUser struct {
+	UserID   uint64
+	Login    string `ch:"name"`
+	Internal string `ch:"-"`
+	Comment  *string
+	*ScanEvent
+}
+
+func TestScanStruct(t *testing.T) {
+	db := newStructServer(t)
+	rows, err := db.Query("SELECT * FROM users")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	require.True(t, rows.Next())
+	var user structUser
+	require.NoError(t, ScanStruct(rows, &user))
+	assert.Equal(t, uint64(1), user.UserID)
+	assert.Equal(t, "alice", user.Login)
+	assert.Nil(t, user.Comment)
+	if assert.NotNil(t, user.ScanEvent) {
+		assert.Equal(t, time.Date(2011, 3, 6, 6, 20, 0, 0, time.UTC), user.EventTime)
+		assert.Equal(t, 1.5, user.Score)
+		assert.Equal(t, []string{"a", "b"}, user.Tags)
+	}
+
+	assert.EqualError(t, ScanStruct(rows, user), "clickhouse: expected a pointer to a struct, got clickhouse.structUser")
+}
+
+func TestSelect(t *testing.T) {
+	db := newStructServer(t)
+	users, err := Select[structUser](context.Background(), db, "SELECT * FROM users")
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "bob", users[1].Login)
+	assert.Equal(t, "ok", *users[1].Comment)
+	assert.Equal(t, []string{}, users[1].Tags)
+
+	// the values are converted like sql.Rows.Scan does
+	type converted struct {
+		UserID    string
+		Name      []byte
+		EventTime string
+		Score     float32
+		Tags      interface{}
+		Comment   sql.NullString
+	}
+	_, err = Select[converted](context.Background(), db, "SELECT * FROM users")
+	assert.NoError(t, err)
+
+	type missing struct {
+		UserID uint64
+	}
+	_, err = Select[missing](context.Background(), db, "SELECT * FROM users")
+	assert.EqualError(t, err, "clickhouse: no field for the column name in clickhouse.missing")
+
+	type incompatible struct {
+		structUser
+		Tags []int
+	}
+	_, err = Select[incompatible](context.Background(), db, "SELECT * FROM users")
+	assert.EqualError(t, err, "clickhouse: the column tags of type Array(String) can't be scanned into the field Tags of type []int")
+
+	type fractional struct {
+		structUser
+		Score int64
+	}
+	_, err = Select[fractional](context.Background(), db, "SELECT * FROM users")
+	assert.EqualError(t, err, "clickhouse: the column score of type Float64 can't be scanned into the field Score of type int64")
+
+	_, err = Select[int](context.Background(), db, "SELECT * FROM users")
+	assert.EqualError(t, err, "clickhouse: expected a struct, got int")
+}
+
+func TestSelectValueTypes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("addr\tbig\tamount\n" +
+			"IPv4\tInt128\tDecimal(9, 2)\n" +
+			"127.0.0.1\t-170141183460469231731687303715884105728\t1.5\n"))
+	}))
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the structs without exported fields and the values of the driver are single columns
+	type values struct {
+		Addr   netip.Addr
+		Big    *big.Int
+		Amount Decimal
+	}
+	rows, err := Select[values](context.Background(), db, "SELECT * FROM values")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, netip.MustParseAddr("127.0.0.1"), rows[0].Addr)
+	expected, _ := new(big.Int).SetString("-170141183460469231731687303715884105728", 10)
+	assert.Equal(t, expected, rows[0].Big)
+	assert.Equal(t, "1.5", rows[0].Amount.String())
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, expected := range map[string]string{
+		"Name":      "name",
+		"UserID":    "user_id",
+		"HTTPCode":  "http_code",
+		"EventTime": "event_time",
+		"Field1":    "field1",
+		"X":         "x",
+	} {
+		assert.Equal(t, expected, snakeCase(name), name)
+	}
+}
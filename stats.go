@@ -0,0 +1,88 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const summaryHeader = "X-ClickHouse-Summary"
+
+// QueryStats contains the statistics of a query reported by ClickHouse
+// in the X-ClickHouse-Summary header. For a SELECT the header is sent
+// before the result, so the statistics are final only if the
+// wait_end_of_query setting is enabled.
+type QueryStats struct {
+	ReadRows        uint64
+	ReadBytes       uint64
+	WrittenRows     uint64
+	WrittenBytes    uint64
+	TotalRowsToRead uint64
+	ResultRows      uint64
+	ResultBytes     uint64
+	Elapsed         time.Duration
+}
+
+// QueryStatsReporter is implemented by the driver.Result and driver.Rows
+// returned by the driver. They are available through sql.Conn.Raw.
+type QueryStatsReporter interface {
+	// QueryStats returns the statistics of the query and false
+	// if the server has not reported them.
+	QueryStats() (QueryStats, bool)
+}
+
+// parseCounters decodes the JSON object with counters sent by ClickHouse
+// in X-ClickHouse-Summary and X-ClickHouse-Progress headers.
+// The counters are sent as strings, but numbers are accepted as well.
+func parseCounters(s string) (map[string]uint64, error) {
+	var raw map[string]json.Number
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, err
+	}
+	counters := make(map[string]uint64, len(raw))
+	for k, v := range raw {
+		n, err := strconv.ParseUint(v.String(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		counters[k] = n
+	}
+	return counters, nil
+}
+
+func parseQueryStats(header http.Header) (QueryStats, bool) {
+	summary := header.Get(summaryHeader)
+	if summary == "" {
+		return QueryStats{}, false
+	}
+	counters, err := parseCounters(summary)
+	if err != nil {
+		return QueryStats{}, false
+	}
+	return QueryStats{
+		ReadRows:        counters["read_rows"],
+		ReadBytes:       counters["read_bytes"],
+		WrittenRows:     counters["written_rows"],
+		WrittenBytes:    counters["written_bytes"],
+		TotalRowsToRead: counters["total_rows_to_read"],
+		ResultRows:      counters["result_rows"],
+		ResultBytes:     counters["result_bytes"],
+		Elapsed:         time.Duration(counters["elapsed_ns"]),
+	}, true
+}
+
+// rowsStats keeps the statistics of the query which returned the rows
+type rowsStats struct {
+	stats    QueryStats
+	hasStats bool
+}
+
+// QueryStats implements QueryStatsReporter
+func (r *rowsStats) QueryStats() (QueryStats, bool) {
+	return r.stats, r.hasStats
+}
+
+func (r *rowsStats) setStats(header http.Header) {
+	r.stats, r.hasStats = parseQueryStats(header)
+}
@@ -0,0 +1,87 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ QueryStatsReporter = new(result)
+	_ QueryStatsReporter = noResult{}
+	_ QueryStatsReporter = new(textRows)
+)
+
+func TestParseQueryStats(t *testing.T) {
+	header := http.Header{}
+	_, ok := parseQueryStats(header)
+	assert.False(t, ok)
+
+	header.Set(summaryHeader, `{"read_rows":"10","read_bytes":"80","written_rows":"3","written_bytes":"24",`+
+		`"total_rows_to_read":"100","result_rows":"3","result_bytes":"24","elapsed_ns":"1500000"}`)
+	stats, ok := parseQueryStats(header)
+	assert.True(t, ok)
+	assert.Equal(t, QueryStats{
+		ReadRows:        10,
+		ReadBytes:       80,
+		WrittenRows:     3,
+		WrittenBytes:    24,
+		TotalRowsToRead: 100,
+		ResultRows:      3,
+		ResultBytes:     24,
+		Elapsed:         1500 * time.Microsecond,
+	}, stats)
+
+	header.Set(summaryHeader, `{"read_rows":1,"written_rows":2}`)
+	stats, ok = parseQueryStats(header)
+	assert.True(t, ok)
+	assert.Equal(t, QueryStats{ReadRows: 1, WrittenRows: 2}, stats)
+
+	header.Set(summaryHeader, `{"read_rows":"x"}`)
+	_, ok = parseQueryStats(header)
+	assert.False(t, ok)
+}
+
+func TestQueryStatsFromResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set(summaryHeader, `{"read_rows":"2","written_rows":"2","elapsed_ns":"1000"}`)
+		_, _ = w.Write([]byte("n\nUInt8\n1\n2\n"))
+	}))
+	defer srv.Close()
+
+	cfg, err := ParseDSN(srv.URL + "/default")
+	require.NoError(t, err)
+	cn := newConn(cfg)
+	defer cn.Close()
+
+	res, err := cn.exec(context.Background(), "INSERT INTO t VALUES (1), (2)", nil)
+	require.NoError(t, err)
+	affected, err := res.RowsAffected()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, affected)
+	stats, ok := res.(QueryStatsReporter).QueryStats()
+	assert.True(t, ok)
+	assert.Equal(t, QueryStats{ReadRows: 2, WrittenRows: 2, Elapsed: time.Microsecond}, stats)
+
+	// the written rows of other statements are not the rows affected
+	res, err = cn.exec(context.Background(), "ALTER TABLE t UPDATE n = 1 WHERE 1", nil)
+	require.NoError(t, err)
+	_, err = res.RowsAffected()
+	assert.Equal(t, ErrNoRowsAffected, err)
+	_, ok = res.(QueryStatsReporter).QueryStats()
+	assert.True(t, ok)
+
+	rows, err := cn.query(context.Background(), "SELECT n FROM t", nil)
+	require.NoError(t, err)
+	stats, ok = rows.(QueryStatsReporter).QueryStats()
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, stats.ReadRows)
+	assert.NoError(t, rows.Close())
+}
@@ -1,9 +1,9 @@
 package clickhouse
 
 import (
-	"bytes"
 	"context"
 	"database/sql/driver"
+	"fmt"
 	"regexp"
 	"strings"
 	"sync/atomic"
@@ -20,11 +20,24 @@ type stmt struct {
 	pattern   string
 	index     []int
 	batchMode bool
-	args      [][]driver.Value
+	// batch streams the rows of the current batch INSERT to ClickHouse
+	batch *batchWriter
+	// columns are set if the rows of the batch are encoded in RowBinary,
+	// enums check the values of the Enum columns if the rows are encoded as text,
+	// described is set when they have been looked up
+	columns   []binaryColumn
+	enums     []binaryColumn
+	described bool
+	row       []byte
+	// err fails the rest of the batch after a failed write, since its rows are lost
+	err error
+	// hasQueryParams is set if the query has {name:Type} parameters,
+	// their number is not checked by database/sql
+	hasQueryParams bool
 }
 
 func newStmt(query string) *stmt {
-	s := &stmt{pattern: query}
+	s := &stmt{pattern: query, hasQueryParams: hasQueryParams(query)}
 	index := splitInsertRe.FindStringSubmatchIndex(strings.ToUpper(query))
 	if len(index) == 6 {
 		s.prefix = query[index[2]:index[3]]
@@ -51,6 +64,8 @@ func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
 // Close closes the statement.
 func (s *stmt) Close() error {
 	if atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		// the rows which are not committed yet are dropped
+		s.abort()
 		s.c = nil
 	}
 	return nil
@@ -58,6 +73,9 @@ func (s *stmt) Close() error {
 
 // NumInput returns the number of placeholder parameters.
 func (s *stmt) NumInput() int {
+	if s.hasQueryParams {
+		return -1
+	}
 	return len(s.index)
 }
 
@@ -72,8 +90,7 @@ func (s *stmt) query(ctx context.Context, args []driver.Value) (driver.Rows, err
 
 func (s *stmt) exec(ctx context.Context, args []driver.Value) (driver.Result, error) {
 	if s.batchMode {
-		s.args = append(s.args, args)
-		return emptyResult, nil
+		return emptyResult, s.append(ctx, args)
 	}
 	q, err := interpolateParams2(s.pattern, args, s.index)
 	if err != nil {
@@ -83,33 +100,106 @@ func (s *stmt) exec(ctx context.Context, args []driver.Value) (driver.Result, er
 	return s.c.exec(ctx, s.prefix+q, nil)
 }
 
-func (s *stmt) commit(ctx context.Context) error {
-	if atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
-		// statement is not usable after commit
-		// this code will not run if statement has been closed
-		args := s.args
-		con := s.c
-		s.args = nil
-		s.c = nil
-		if len(args) == 0 {
-			return nil
+// append writes the row to the batch. When the batch reaches the limits of
+// the connection, it is sent and the next row starts a new one.
+func (s *stmt) append(ctx context.Context, args []driver.Value) error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.c.txCtx != nil {
+		// the batch lives as long as the transaction
+		ctx = s.c.txCtx
+	}
+	if !s.described {
+		s.columns, s.enums = s.c.describeInsert(ctx, s.prefix, s.pattern)
+		s.described = true
+	}
+	query, separator := s.prefix, ", "
+	if s.columns != nil {
+		if err := s.encodeRow(args); err != nil {
+			return err
 		}
-		buf := bytes.NewBufferString(s.prefix)
-		var (
-			p   string
-			err error
-		)
-		for i, arg := range args {
-			if i > 0 {
-				buf.WriteString(", ")
+		query, separator = strings.TrimSpace(s.prefix[:len(s.prefix)-len("VALUES")])+" FORMAT RowBinary\n", ""
+	} else {
+		for i, column := range s.enums {
+			if i < len(args) {
+				if err := column.checkEnum(args[i]); err != nil {
+					return err
+				}
 			}
-			if p, err = interpolateParams2(s.pattern, arg, s.index); err != nil {
+		}
+		var err error
+		if s.c.rowBinaryInsert {
+			// the original values are passed by CheckNamedValue
+			if args, err = convertValues(args); err != nil {
 				return err
 			}
-			buf.WriteString(p)
 		}
-		_, err = con.exec(ctx, buf.String(), nil)
+		row, err := interpolateParams2(s.pattern, args, s.index)
+		if err != nil {
+			return err
+		}
+		s.row = append(s.row[:0], row...)
+	}
+
+	if s.batch == nil {
+		var err error
+		if s.batch, err = s.c.startBatch(ctx, query, separator); err != nil {
+			return err
+		}
+	}
+	batch := s.batch
+	if err := batch.append(s.row); err != nil {
+		s.batch = nil
+		s.err = err
 		return err
 	}
+	if batch.full(s.c.batchMaxRows, s.c.batchMaxBytes) {
+		s.batch = nil
+		if err := batch.finish(); err != nil {
+			s.err = err
+			return err
+		}
+	}
 	return nil
 }
+
+// encodeRow encodes the values of the row in RowBinary
+func (s *stmt) encodeRow(args []driver.Value) (err error) {
+	if len(args) != len(s.columns) {
+		return ErrPlaceholderCount
+	}
+	s.row = s.row[:0]
+	for i, column := range s.columns {
+		if s.row, err = encodeBinary(column.encoder, s.row, args[i]); err != nil {
+			return fmt.Errorf("clickhouse: failed to encode the value of the column %s: %w", column.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *stmt) commit() error {
+	if atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		// statement is not usable after commit
+		// this code will not run if statement has been closed
+		batch := s.batch
+		s.batch = nil
+		s.c = nil
+		if s.err != nil {
+			return s.err
+		}
+		if batch == nil {
+			return nil
+		}
+		return batch.finish()
+	}
+	return nil
+}
+
+// abort interrupts the current batch, its rows are not inserted
+func (s *stmt) abort() {
+	if s.batch != nil {
+		s.batch.abort()
+		s.batch = nil
+	}
+}
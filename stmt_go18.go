@@ -9,17 +9,21 @@ import (
 )
 
 func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
-	values, err := namedValueToValue(args)
+	values, params, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
 	}
-	return s.query(ctx, values)
+	return s.query(withQueryParams(ctx, params), values)
 }
 
 func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
-	values, err := namedValueToValue(args)
+	values, params, err := namedValueToValue(args)
 	if err != nil {
 		return nil, err
 	}
-	return s.exec(ctx, values)
+	if s.batchMode && len(params) > 0 {
+		// the rows of a batch are sent in one query, so they can't have their own parameters
+		return nil, ErrBatchNameParams
+	}
+	return s.exec(withQueryParams(ctx, params), values)
 }
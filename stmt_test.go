@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -234,3 +235,10 @@ func (s *stmtSuite) TestFixDoubleInterpolateInStmt() {
 func TestStmt(t *testing.T) {
 	suite.Run(t, new(stmtSuite))
 }
+
+func TestStmtNumInput(t *testing.T) {
+	assert.Equal(t, 2, newStmt("SELECT ?, ?").NumInput())
+	assert.Equal(t, -1, newStmt("SELECT ?, {name:String}").NumInput())
+	assert.Equal(t, -1, newStmt("SELECT { id : UInt64 }").NumInput())
+	assert.Equal(t, 0, newStmt("SELECT map('a', 1)").NumInput())
+}
@@ -34,6 +34,14 @@ func DeregisterTLSConfig(key string) {
 	tlsConfigLock.Unlock()
 }
 
+// getTLSConfig returns the registered tls.Config itself, it must not be modified
+func getTLSConfig(key string) (config *tls.Config) {
+	tlsConfigLock.RLock()
+	config = tlsConfigRegistry[key]
+	tlsConfigLock.RUnlock()
+	return
+}
+
 func getTLSConfigClone(key string) (config *tls.Config) {
 	tlsConfigLock.RLock()
 	if v, ok := tlsConfigRegistry[key]; ok {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"unicode"
 )
 
 const (
@@ -102,6 +103,38 @@ loop:
 	return &token{'q', data.String()}, nil
 }
 
+// readBackquoted reads an identifier quoted with backquotes like the names
+// of the elements of tuples, the quote is escaped by a backslash or by doubling it
+func readBackquoted(s io.RuneScanner) (*token, error) {
+	var data bytes.Buffer
+
+loop:
+	for {
+		r := read(s)
+
+		switch r {
+		case eof:
+			return nil, fmt.Errorf("unexpected eof inside quoted identifier")
+		case '\\':
+			escaped, err := readEscaped(s)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect escaping in quoted identifier: %v", err)
+			}
+			r = escaped
+		case '`':
+			if read(s) == '`' {
+				break
+			}
+			_ = s.UnreadRune()
+			break loop
+		}
+
+		data.WriteRune(r)
+	}
+
+	return &token{'i', data.String()}, nil
+}
+
 func readNumberOrID(s io.RuneScanner) *token {
 	var data bytes.Buffer
 
@@ -148,6 +181,11 @@ loop:
 			if err != nil {
 				return nil, err
 			}
+		case '`':
+			t, err = readBackquoted(s)
+			if err != nil {
+				return nil, err
+			}
 		default:
 			_ = s.UnreadRune()
 			t = readNumberOrID(s)
@@ -165,3 +203,159 @@ loop:
 func tokenizeString(s string) ([]*token, error) {
 	return tokenize(strings.NewReader(s))
 }
+
+// queryToken is a part of a query found by tokenizeQuery, it spans query[pos:end]
+type queryToken struct {
+	kind rune
+	pos  int
+	end  int
+}
+
+// tokenizeQuery finds the parts of a query which matter for placeholders:
+// string literals ('q'), quoted identifiers ('i'), comments ('c'), heredocs ('h'),
+// ? placeholders ('?') and {name:Type} query parameters ('{').
+// The rest of the query is skipped. An unterminated token ends at the end of the query.
+func tokenizeQuery(query string) []queryToken {
+	var tokens []queryToken
+	for i := 0; i < len(query); {
+		var (
+			start = i
+			kind  rune
+			ok    bool
+		)
+		switch c := query[i]; {
+		case c == '\'':
+			kind, i = 'q', skipQuoted(query, i)
+		case c == '"', c == '`':
+			kind, i = 'i', skipQuoted(query, i)
+		case c == '-' && strings.HasPrefix(query[i:], "--"),
+			c == '#' && (strings.HasPrefix(query[i:], "# ") || strings.HasPrefix(query[i:], "#!")):
+			kind, i = 'c', skipLine(query, i)
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			kind, i = 'c', skipBlockComment(query, i)
+		case c == '$':
+			if i, ok = skipHeredoc(query, i); !ok {
+				i = start + 1
+				continue
+			}
+			kind = 'h'
+		case c == '{':
+			if i, ok = skipQueryParam(query, i); !ok {
+				i = start + 1
+				continue
+			}
+			kind = '{'
+		case c == '?':
+			kind, i = '?', i+1
+		default:
+			i++
+			continue
+		}
+		tokens = append(tokens, queryToken{kind: kind, pos: start, end: i})
+	}
+	return tokens
+}
+
+// skipQuoted returns the end of a string literal or an identifier starting at query[i].
+// The quote is escaped either by a backslash or by doubling it.
+func skipQuoted(query string, i int) int {
+	quote := query[i]
+	for i++; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// skipLine returns the end of a single line comment starting at query[i]
+func skipLine(query string, i int) int {
+	if n := strings.IndexByte(query[i:], '\n'); n >= 0 {
+		return i + n + 1
+	}
+	return len(query)
+}
+
+// skipBlockComment returns the end of a /* */ comment starting at query[i],
+// the comments may be nested
+func skipBlockComment(query string, i int) int {
+	depth := 0
+	for i < len(query)-1 {
+		switch query[i : i+2] {
+		case "/*":
+			depth++
+			i += 2
+		case "*/":
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(query)
+}
+
+// skipHeredoc returns the end of a $tag$...$tag$ string starting at query[i]
+func skipHeredoc(query string, i int) (int, bool) {
+	n := strings.IndexByte(query[i+1:], '$')
+	if n < 0 {
+		return 0, false
+	}
+	for _, c := range query[i+1 : i+1+n] {
+		if !isWordChar(c) {
+			return 0, false
+		}
+	}
+	delim := query[i : i+n+2]
+	end := strings.Index(query[i+len(delim):], delim)
+	if end < 0 {
+		return len(query), true
+	}
+	return i + len(delim) + end + len(delim), true
+}
+
+// skipQueryParam returns the end of a {name:Type} parameter starting at query[i]
+func skipQueryParam(query string, i int) (int, bool) {
+	j := i + 1
+	for j < len(query) && unicode.IsSpace(rune(query[j])) {
+		j++
+	}
+	name := j
+	for j < len(query) && isWordChar(rune(query[j])) {
+		j++
+	}
+	if j == name {
+		return 0, false
+	}
+	for j < len(query) && unicode.IsSpace(rune(query[j])) {
+		j++
+	}
+	if j == len(query) || query[j] != ':' {
+		return 0, false
+	}
+	for j++; j < len(query); {
+		switch query[j] {
+		case '}':
+			return j + 1, true
+		case '\'', '"', '`':
+			j = skipQuoted(query, j)
+		default:
+			j++
+		}
+	}
+	return 0, false
+}
+
+func isWordChar(c rune) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -54,11 +54,31 @@ func TestTokenize(t *testing.T) {
 				{eof, ""},
 			},
 		},
+		{
+			name:  "backquoted names",
+			input: "Tuple(`a b` String, `c``d\\`e` UInt8)",
+			output: []*token{
+				{'s', "Tuple"},
+				{'(', ""},
+				{'i', "a b"},
+				{'s', "String"},
+				{',', ""},
+				{'i', "c`d`e"},
+				{'s', "UInt8"},
+				{')', ""},
+				{eof, ""},
+			},
+		},
 		{
 			name:  "unclosed quote",
 			input: "Array(')",
 			fail:  true,
 		},
+		{
+			name:  "unclosed backquote",
+			input: "Tuple(`a String)",
+			fail:  true,
+		},
 		{
 			name:  "unfinished escape",
 			input: `Array('\`,
@@ -78,3 +98,28 @@ func TestTokenize(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenizeQuery(t *testing.T) {
+	query := "SELECT 'a?', \"b\", `c`, $$d$$, ? -- e\n/* f */ {g:String} # h"
+	expected := []struct {
+		kind rune
+		data string
+	}{
+		{'q', "'a?'"},
+		{'i', `"b"`},
+		{'i', "`c`"},
+		{'h', "$$d$$"},
+		{'?', "?"},
+		{'c', "-- e\n"},
+		{'c', "/* f */"},
+		{'{', "{g:String}"},
+		{'c', "# h"},
+	}
+	tokens := tokenizeQuery(query)
+	if assert.Len(t, tokens, len(expected)) {
+		for i, e := range expected {
+			assert.Equal(t, e.kind, tokens[i].kind)
+			assert.Equal(t, e.data, query[tokens[i].pos:tokens[i].end])
+		}
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"strings"
 )
 
+// maxExceptionSize limits the size of the exception read from the end of the response
+const maxExceptionSize = 1 << 20
+
 type tsvReader struct {
 	r         *bufio.Reader
 	rawBuffer []byte
@@ -45,3 +48,15 @@ func (r *tsvReader) Read() (record []string, err error) {
 	}
 	return strings.Split(string(line), "\t"), errRead
 }
+
+// atEOF reports whether all the data has been read
+func (r *tsvReader) atEOF() bool {
+	_, err := r.r.Peek(1)
+	return err == io.EOF
+}
+
+// readRest returns the rest of the data, but not more than maxExceptionSize
+func (r *tsvReader) readRest() (string, error) {
+	rest, err := io.ReadAll(io.LimitReader(r.r, maxExceptionSize))
+	return string(rest), err
+}
@@ -0,0 +1,155 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// tupleStruct scans a tuple into a struct
+type tupleStruct struct {
+	dest interface{}
+}
+
+// TupleStruct returns a sql.Scanner which scans the values of a Tuple column
+// into the struct pointed to by dest. The elements of named tuples are matched
+// with the fields by the ch tag or by the snake_case name of the field, the
+// elements of other tuples are assigned to the exported fields in order.
+// dest may also point to a slice of structs for Nested and Array(Tuple) columns:
+//
+//	var point struct{ X, Y float64 }
+//	err := rows.Scan(clickhouse.TupleStruct(&point))
+func TupleStruct(dest interface{}) sql.Scanner {
+	return &tupleStruct{dest: dest}
+}
+
+// Scan implements sql.Scanner
+func (t *tupleStruct) Scan(src interface{}) error {
+	v := reflect.ValueOf(t.dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("clickhouse: expected a pointer to a struct, got %T", t.dest)
+	}
+	if err := assignTuple(v.Elem(), src); err != nil {
+		return fmt.Errorf("clickhouse: %w", err)
+	}
+	return nil
+}
+
+// assignTuple assigns a tuple, an array of tuples or an element of a tuple to dst
+func assignTuple(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return fmt.Errorf("can't scan NULL into %s", dst.Type())
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignTuple(dst.Elem(), src)
+	}
+	if reflect.PtrTo(dst.Type()).Implements(reflectTypeScanner) {
+		return dst.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+	switch {
+	case dst.Kind() == reflect.Struct && sv.Type() == reflectTypeNamedTuple:
+		byName := make(map[string]structField)
+		for _, f := range tupleStructFields(dst.Type(), nil, nil) {
+			byName[f.name] = f
+		}
+		for name, v := range src.(map[string]interface{}) {
+			f, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("no field for the tuple element %s in %s", name, dst.Type())
+			}
+			if err := assignTuple(fieldByIndex(dst, f.index), v); err != nil {
+				return fmt.Errorf("tuple element %s: %w", name, err)
+			}
+		}
+		return nil
+	case dst.Kind() == reflect.Struct && sv.Kind() == reflect.Struct:
+		fields := tupleStructFields(dst.Type(), nil, nil)
+		if len(fields) != sv.NumField() {
+			return fmt.Errorf("tuple of %d elements can't be scanned into %s of %d fields", sv.NumField(), dst.Type(), len(fields))
+		}
+		for i, f := range fields {
+			if err := assignTuple(fieldByIndex(dst, f.index), sv.Field(i).Interface()); err != nil {
+				return fmt.Errorf("tuple element %d: %w", i+1, err)
+			}
+		}
+		return nil
+	case dst.Kind() == reflect.Slice && sv.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(dst.Type(), sv.Len(), sv.Len())
+		for i := 0; i < sv.Len(); i++ {
+			if err := assignTuple(slice.Index(i), sv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case isIntegerKind(dst.Kind()) && isIntegerKind(sv.Kind()):
+		n, negative, _ := integerValue(src)
+		if dst.Kind() <= reflect.Int64 {
+			if !negative && n > math.MaxInt64 || dst.OverflowInt(int64(n)) {
+				return fmt.Errorf("value %v overflows %s", src, dst.Type())
+			}
+			dst.SetInt(int64(n))
+			return nil
+		}
+		if negative || dst.OverflowUint(n) {
+			return fmt.Errorf("value %v overflows %s", src, dst.Type())
+		}
+		dst.SetUint(n)
+		return nil
+	case isFloatKind(dst.Kind()) && isNumericKind(sv.Kind()),
+		dst.Kind() == reflect.String && sv.Kind() == reflect.String:
+		dst.Set(sv.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("can't scan %T into %s", src, dst.Type())
+}
+
+// tupleStructFields returns the exported fields of a struct type in the order
+// the elements of a tuple are assigned to them, the fields of embedded structs
+// are added as the fields of the struct like tupleFields does
+func tupleStructFields(t reflect.Type, index []int, fields []structField) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = tupleStructFields(f.Type, fieldIndex, fields)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get(structTagName), ",")
+		if name == "" {
+			name = snakeCase(f.Name)
+		}
+		fields = append(fields, structField{name: name, index: fieldIndex, typ: f.Type})
+	}
+	return fields
+}
+
+// tupleColumn reports whether the values of a column are tuples or arrays of tuples
+func tupleColumn(typ string) bool {
+	desc, err := ParseTypeDesc(typ)
+	if err != nil {
+		return false
+	}
+	for (desc.Name == "Nullable" || desc.Name == "LowCardinality" || desc.Name == "Array") && len(desc.Args) == 1 {
+		desc = desc.Args[0]
+	}
+	return desc.Name == "Tuple" || desc.Name == "Nested"
+}
@@ -0,0 +1,96 @@
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTupleStruct(t *testing.T) {
+	type point struct {
+		X float64
+		Y float64 `ch:"y_coord"`
+	}
+	var p point
+	require.NoError(t, TupleStruct(&p).Scan(map[string]interface{}{"x": float32(1.5), "y_coord": 2.0}))
+	assert.Equal(t, point{1.5, 2}, p)
+
+	require.NoError(t, TupleStruct(&p).Scan(struct {
+		Field0 int32
+		Field1 float64
+	}{3, 4}))
+	assert.Equal(t, point{3, 4}, p)
+
+	type item struct {
+		ID    uint8
+		Name  *string
+		Point point
+	}
+	var items []item
+	require.NoError(t, TupleStruct(&items).Scan([]map[string]interface{}{
+		{"id": uint64(1), "name": "a", "point": map[string]interface{}{"x": 1.0, "y_coord": 2.0}},
+		{"id": int16(2), "name": nil, "point": map[string]interface{}{}},
+	}))
+	name := "a"
+	assert.Equal(t, []item{{1, &name, point{1, 2}}, {2, nil, point{}}}, items)
+
+	testCases := []struct {
+		dest interface{}
+		src  interface{}
+		err  string
+	}{
+		{p, map[string]interface{}{}, "clickhouse: expected a pointer to a struct, got clickhouse.point"},
+		{&p, map[string]interface{}{"z": 1.0}, "clickhouse: no field for the tuple element z in clickhouse.point"},
+		{&p, map[string]interface{}{"x": "a"}, "clickhouse: tuple element x: can't scan string into float64"},
+		{&p, nil, "clickhouse: can't scan NULL into clickhouse.point"},
+		{&p, struct{ Field0 float64 }{1}, "clickhouse: tuple of 1 elements can't be scanned into clickhouse.point of 2 fields"},
+		{&items, []map[string]interface{}{{"id": 256}}, "clickhouse: tuple element id: value 256 overflows uint8"},
+		{&items, []map[string]interface{}{{"id": -1}}, "clickhouse: tuple element id: value -1 overflows uint8"},
+	}
+	for _, tc := range testCases {
+		assert.EqualError(t, TupleStruct(tc.dest).Scan(tc.src), tc.err)
+	}
+}
+
+func TestSelectTuple(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("id\tpoint\titems\n" +
+			"UInt64\tTuple(x Float64, `y coord` Float64)\tNested(name String, count UInt32)\n" +
+			"1\t(1.5,2)\t[('a',1),('b',2)]\n"))
+	}))
+	defer srv.Close()
+	db, err := sql.Open("chhttp", srv.URL+"/default")
+	require.NoError(t, err)
+	defer db.Close()
+
+	type point struct {
+		X float64
+		Y float64 `ch:"y coord"`
+	}
+	type item struct {
+		Name  string
+		Count int
+	}
+	type row struct {
+		ID    uint64
+		Point point `ch:"point,tuple"`
+		Items []item
+	}
+	rows, err := Select[row](context.Background(), db, "SELECT id, point, items FROM t")
+	require.NoError(t, err)
+	assert.Equal(t, []row{{1, point{1.5, 2}, []item{{"a", 1}, {"b", 2}}}}, rows)
+
+	var (
+		id    uint64
+		m     map[string]interface{}
+		items []map[string]interface{}
+	)
+	require.NoError(t, db.QueryRow("SELECT id, point, items FROM t").Scan(&id, &m, &items))
+	assert.Equal(t, map[string]interface{}{"x": 1.5, "y coord": 2.0}, m)
+	assert.Equal(t, []map[string]interface{}{{"name": "a", "count": uint32(1)}, {"name": "b", "count": uint32(2)}}, items)
+}
@@ -2,12 +2,23 @@ package clickhouse
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // TypeDesc describes a (possibly nested) data type returned by ClickHouse.
 type TypeDesc struct {
 	Name string
 	Args []*TypeDesc
+
+	// Enum maps the names of the values of Enum8 and Enum16 to their codes,
+	// it is nil for other types
+	Enum map[string]int16
+
+	// ElemName is the name of an element of a named Tuple or of a column of
+	// Nested, e.g. id of Tuple(id UInt64), it is empty for other types
+	ElemName string
 }
 
 func parseTypeDesc(tokens []*token) (*TypeDesc, []*token, error) {
@@ -31,23 +42,47 @@ func parseTypeDesc(tokens []*token) (*TypeDesc, []*token, error) {
 	}
 
 	if name == "Enum8" || name == "Enum16" {
-		// TODO: an Enum's arguments get completely ignored
 		for i := range tokens {
 			if tokens[i].kind == ')' {
+				bitSize := 8
+				if name == "Enum16" {
+					bitSize = 16
+				}
+				values, err := parseEnumValues(tokens[:i], bitSize)
+				if err != nil {
+					return nil, nil, fmt.Errorf("malformed values of %s: %v", name, err)
+				}
+				desc.Enum = values
 				return &desc, tokens[i+1:], nil
 			}
 		}
 		return nil, nil, fmt.Errorf("unfinished enum type description")
 	}
 
+	if name == "JSON" {
+		args, tail, err := parseJSONArgs(tokens)
+		if err != nil {
+			return nil, nil, fmt.Errorf("malformed arguments of JSON: %v", err)
+		}
+		desc.Args = args
+		return &desc, tail, nil
+	}
+
+	named := name == "Tuple" || name == "Nested"
 	for {
 		var arg *TypeDesc
 		var err error
 
+		var elemName string
+		if named && isElemName(tokens) {
+			elemName = tokens[0].data
+			tokens = tokens[1:]
+		}
 		arg, tokens, err = parseTypeDesc(tokens)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to parse subtype: %v", err)
 		}
+		arg.ElemName = elemName
 		desc.Args = append(desc.Args, arg)
 
 		switch tokens[0].kind {
@@ -60,6 +95,205 @@ func parseTypeDesc(tokens []*token) (*TypeDesc, []*token, error) {
 	}
 }
 
+// isElemName reports whether the tokens start with the name of an element
+// of a named Tuple or Nested, which is followed by the type of the element
+func isElemName(tokens []*token) bool {
+	return (tokens[0].kind == 's' || tokens[0].kind == 'i') && len(tokens) > 1 &&
+		(tokens[1].kind == 's' || tokens[1].kind == 'q')
+}
+
+// parseJSONArgs parses the arguments of JSON up to the closing parenthesis.
+// The typed paths are the types with the paths as ElemName, the settings like
+// max_dynamic_paths=10 are the names of the arguments without types, and the
+// skipped paths are the arguments SKIP(path) and SKIP REGEXP('regexp').
+func parseJSONArgs(tokens []*token) ([]*TypeDesc, []*token, error) {
+	var args []*TypeDesc
+	for {
+		switch {
+		case tokens[0].kind == 's' && tokens[0].data == "SKIP" && len(tokens) > 2 &&
+			tokens[1].kind == 's' && tokens[1].data == "REGEXP" && tokens[2].kind == 'q':
+			args = append(args, &TypeDesc{Name: "SKIP REGEXP", Args: []*TypeDesc{{Name: tokens[2].data}}})
+			tokens = tokens[3:]
+		case tokens[0].kind == 's' && tokens[0].data == "SKIP" && (tokens[1].kind == 's' || tokens[1].kind == 'i'):
+			args = append(args, &TypeDesc{Name: "SKIP", Args: []*TypeDesc{{Name: tokens[1].data}}})
+			tokens = tokens[2:]
+		case tokens[0].kind == 's' && (strings.Contains(tokens[0].data, "=") || strings.HasPrefix(tokens[1].data, "=")):
+			// the tokenizer splits "= 1" and "=1" differently, so join them back
+			var setting strings.Builder
+			for tokens[0].kind == 's' {
+				setting.WriteString(tokens[0].data)
+				tokens = tokens[1:]
+			}
+			args = append(args, &TypeDesc{Name: setting.String()})
+		case isElemName(tokens):
+			path := tokens[0].data
+			arg, tail, err := parseTypeDesc(tokens[1:])
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse the type of %s: %v", path, err)
+			}
+			arg.ElemName = path
+			args = append(args, arg)
+			tokens = tail
+		default:
+			return nil, nil, fmt.Errorf("unexpected token '%s'", tokens[0].data)
+		}
+
+		switch tokens[0].kind {
+		case ',':
+			tokens = tokens[1:]
+		case ')':
+			return args, tokens[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("expected a comma between the arguments")
+		}
+	}
+}
+
+// elemNames returns the names of the elements of a named Tuple or Nested,
+// or nil if the elements are not named
+func elemNames(t *TypeDesc) []string {
+	names := make([]string, len(t.Args))
+	for i, arg := range t.Args {
+		if arg.ElemName == "" {
+			return nil
+		}
+		names[i] = arg.ElemName
+	}
+	return names
+}
+
+// parseEnumValues parses the elements of an enum: 'name' = value, ...
+// The values must fit into integers of bitSize bits.
+func parseEnumValues(tokens []*token, bitSize int) (map[string]int16, error) {
+	values := make(map[string]int16)
+	codes := make(map[int16]bool)
+	for len(tokens) > 0 {
+		if tokens[0].kind != 'q' {
+			return nil, fmt.Errorf("expected a quoted name, got '%s'", tokens[0].data)
+		}
+		name := tokens[0].data
+		tokens = tokens[1:]
+		// the tokenizer splits "= 1" and "=1" differently, so join them back
+		var value strings.Builder
+		for len(tokens) > 0 && tokens[0].kind == 's' {
+			value.WriteString(tokens[0].data)
+			tokens = tokens[1:]
+		}
+		if !strings.HasPrefix(value.String(), "=") {
+			return nil, fmt.Errorf("no value of '%s'", name)
+		}
+		v, err := strconv.ParseInt(strings.TrimPrefix(value.String(), "="), 10, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("malformed value of '%s': %v", name, err)
+		}
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("duplicate name '%s'", name)
+		}
+		if codes[int16(v)] {
+			return nil, fmt.Errorf("duplicate value %d", v)
+		}
+		values[name] = int16(v)
+		codes[int16(v)] = true
+		if len(tokens) > 0 {
+			if tokens[0].kind != ',' {
+				return nil, fmt.Errorf("expected a comma after the value of '%s'", name)
+			}
+			tokens = tokens[1:]
+		}
+	}
+	return values, nil
+}
+
+// enumType returns the Enum type of a column, which may be Nullable
+// or LowCardinality, or nil if the column is not an Enum
+func enumType(t *TypeDesc) *TypeDesc {
+	for (t.Name == "Nullable" || t.Name == "LowCardinality") && len(t.Args) == 1 {
+		t = t.Args[0]
+	}
+	if t.Enum == nil {
+		return nil
+	}
+	return t
+}
+
+// String formats the type description like ClickHouse does
+func (t *TypeDesc) String() string {
+	var b strings.Builder
+	t.format(&b)
+	return b.String()
+}
+
+func (t *TypeDesc) format(b *strings.Builder) {
+	if t.ElemName != "" {
+		b.WriteString(quoteElemName(t.ElemName))
+		b.WriteByte(' ')
+	}
+	b.WriteString(t.Name)
+	switch {
+	case t.Name == "SKIP" && len(t.Args) == 1:
+		// a skipped path of JSON
+		b.WriteString(" " + quoteElemName(t.Args[0].Name))
+		return
+	case t.Name == "SKIP REGEXP" && len(t.Args) == 1:
+		b.WriteString(" " + quote(escape(t.Args[0].Name)))
+		return
+	case t.Enum != nil:
+		names := make([]string, 0, len(t.Enum))
+		for name := range t.Enum {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return t.Enum[names[i]] < t.Enum[names[j]] })
+		b.WriteByte('(')
+		for i, name := range names {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(b, "%s = %d", quote(escape(name)), t.Enum[name])
+		}
+		b.WriteByte(')')
+		return
+	case len(t.Args) == 0:
+		return
+	}
+	b.WriteByte('(')
+	for i, arg := range t.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if quotedArg(t.Name, arg) {
+			b.WriteString(quote(escape(arg.Name)))
+			continue
+		}
+		arg.format(b)
+	}
+	b.WriteByte(')')
+}
+
+// quotedArg reports whether the argument of the type is a string literal
+// like the time zone of DateTime
+func quotedArg(name string, arg *TypeDesc) bool {
+	if len(arg.Args) != 0 || arg.ElemName != "" {
+		return false
+	}
+	switch name {
+	case "DateTime", "DateTime64", "Object":
+		_, err := strconv.Atoi(arg.Name)
+		return err != nil
+	}
+	return false
+}
+
+// quoteElemName quotes the name of an element of a tuple with backquotes
+// unless it consists of letters, digits, underscores and dots
+func quoteElemName(name string) string {
+	for i, c := range name {
+		if !isWordChar(c) && c != '.' || i == 0 && c >= '0' && c <= '9' {
+			return quoteIdentifier(name)
+		}
+	}
+	return name
+}
+
 /*
 ParseTypeDesc parses the type description that ClickHouse provides.
 
@@ -70,14 +304,20 @@ The grammar is quite simple:
 	    name()
 	    name(args)
 	args
+	    arg
+	    arg, args
+	arg
 	    desc
-	    desc, args
+	    elemName desc   (in Tuple, Nested and JSON)
 
 Examples:
 
 	String
 	Nullable(Nothing)
 	Array(Tuple(Tuple(String, String), Tuple(String, UInt64)))
+	Tuple(id UInt64, `user name` String)
+	Nested(a String, b Array(Int32))
+	JSON(max_dynamic_paths=16, a.b UInt32, SKIP a.c)
 */
 func ParseTypeDesc(s string) (*TypeDesc, error) {
 	tokens, err := tokenizeString(s)
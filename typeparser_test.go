@@ -41,9 +41,34 @@ func TestParseTypeDesc(t *testing.T) {
 			},
 		},
 		{
-			name:   "args are ignored for Enum",
-			input:  "Enum8(you can = put, 'whatever' here)",
-			output: &TypeDesc{Name: "Enum8"},
+			name:   "enum values",
+			input:  "Enum8('hello' = 1, 'it\\'s' = -2,'a=b'=3)",
+			output: &TypeDesc{Name: "Enum8", Enum: map[string]int16{"hello": 1, "it's": -2, "a=b": 3}},
+		},
+		{
+			name:   "enum16 values",
+			input:  "Nullable(Enum16('a' = -1000, 'b' = 1000))",
+			output: &TypeDesc{Name: "Nullable", Args: []*TypeDesc{{Name: "Enum16", Enum: map[string]int16{"a": -1000, "b": 1000}}}},
+		},
+		{
+			name:  "malformed enum",
+			input: "Enum8(you can = put, 'whatever' here)",
+			fail:  true,
+		},
+		{
+			name:  "enum without a value",
+			input: "Enum8('a', 'b')",
+			fail:  true,
+		},
+		{
+			name:  "enum8 value out of range",
+			input: "Enum8('a' = 128)",
+			fail:  true,
+		},
+		{
+			name:  "duplicate enum value",
+			input: "Enum16('a' = 1, 'b' = 1)",
+			fail:  true,
 		},
 		{
 			name:  "quoted arg",
@@ -107,6 +132,48 @@ func TestParseTypeDesc(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "named tuple",
+			input: "Tuple(id UInt64, `user name` Nullable(String), `a,b` Tuple(x Float64))",
+			output: &TypeDesc{
+				Name: "Tuple",
+				Args: []*TypeDesc{
+					{Name: "UInt64", ElemName: "id"},
+					{Name: "Nullable", Args: []*TypeDesc{{Name: "String"}}, ElemName: "user name"},
+					{Name: "Tuple", Args: []*TypeDesc{{Name: "Float64", ElemName: "x"}}, ElemName: "a,b"},
+				},
+			},
+		},
+		{
+			name:  "nested",
+			input: "Nested(a String, b Enum8('x' = 1))",
+			output: &TypeDesc{
+				Name: "Nested",
+				Args: []*TypeDesc{
+					{Name: "String", ElemName: "a"},
+					{Name: "Enum8", Enum: map[string]int16{"x": 1}, ElemName: "b"},
+				},
+			},
+		},
+		{
+			name:  "json",
+			input: "JSON(max_dynamic_paths = 10, a.b UInt32, `c d` Array(String), SKIP e, SKIP REGEXP '^f')",
+			output: &TypeDesc{
+				Name: "JSON",
+				Args: []*TypeDesc{
+					{Name: "max_dynamic_paths=10"},
+					{Name: "UInt32", ElemName: "a.b"},
+					{Name: "Array", Args: []*TypeDesc{{Name: "String"}}, ElemName: "c d"},
+					{Name: "SKIP", Args: []*TypeDesc{{Name: "e"}}},
+					{Name: "SKIP REGEXP", Args: []*TypeDesc{{Name: "^f"}}},
+				},
+			},
+		},
+		{
+			name:  "element name without type",
+			input: "Tuple(`a`)",
+			fail:  true,
+		},
 		{
 			name:  "unfinished arg list",
 			input: "Array(Tuple(Tuple(String, String), Tuple(String, UInt64))",
@@ -146,3 +213,22 @@ func TestParseTypeDesc(t *testing.T) {
 		})
 	}
 }
+
+func TestTypeDescString(t *testing.T) {
+	testCases := []string{
+		"UInt64",
+		"Nullable(String)",
+		"DateTime64(3, 'Europe/Moscow')",
+		"Enum8('a' = 1, 'b\\'c' = 2)",
+		"Tuple(id UInt64, `user name` Nullable(String))",
+		"Map(String, Array(Decimal(9, 2)))",
+		"Variant(String, UInt64)",
+		"JSON(max_dynamic_paths=10, a.b UInt32, SKIP c, SKIP REGEXP '^d')",
+	}
+	for _, typ := range testCases {
+		desc, err := ParseTypeDesc(typ)
+		if assert.NoError(t, err, typ) {
+			assert.Equal(t, typ, desc.String())
+		}
+	}
+}
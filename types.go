@@ -3,6 +3,7 @@ package clickhouse
 import (
 	"database/sql/driver"
 	"fmt"
+	"math/big"
 	"net"
 	"strconv"
 	"time"
@@ -58,6 +59,61 @@ func (u bigUint64) Value() (driver.Value, error) {
 	return []byte(strconv.FormatUint(uint64(u), 10)), nil
 }
 
+// Int128 returns the value of Int128, which is checked to fit into 128 bits
+func Int128(v *big.Int) driver.Valuer {
+	return bigInt{v: v, signed: true, bitSize: 128}
+}
+
+// UInt128 returns the value of UInt128, which is checked to fit into 128 bits
+func UInt128(v *big.Int) driver.Valuer {
+	return bigInt{v: v, bitSize: 128}
+}
+
+// Int256 returns the value of Int256, which is checked to fit into 256 bits
+func Int256(v *big.Int) driver.Valuer {
+	return bigInt{v: v, signed: true, bitSize: 256}
+}
+
+// UInt256 returns the value of UInt256, which is checked to fit into 256 bits
+func UInt256(v *big.Int) driver.Valuer {
+	return bigInt{v: v, bitSize: 256}
+}
+
+type bigInt struct {
+	v       *big.Int
+	signed  bool
+	bitSize int
+}
+
+// Value implements driver.Valuer
+func (i bigInt) Value() (driver.Value, error) {
+	if i.v == nil {
+		return nil, nil
+	}
+	if err := checkBigIntRange(i.v, i.signed, i.bitSize); err != nil {
+		return nil, err
+	}
+	return []byte(i.v.String()), nil
+}
+
+// checkBigIntRange checks that the integer fits into the given number of bits
+func checkBigIntRange(n *big.Int, signed bool, bitSize int) error {
+	var overflow bool
+	switch {
+	case !signed:
+		overflow = n.Sign() < 0 || n.BitLen() > bitSize
+	case n.Sign() < 0:
+		// -2^(bitSize-1) is the minimal value
+		overflow = new(big.Int).Add(n, big.NewInt(1)).BitLen() > bitSize-1
+	default:
+		overflow = n.BitLen() > bitSize-1
+	}
+	if overflow {
+		return fmt.Errorf("value %s is out of range of %d bit integer", n, bitSize)
+	}
+	return nil
+}
+
 // Decimal32 converts value to Decimal32 of precision S.
 // The value can be a number or a string. The S (scale) parameter specifies the number of decimal places.
 func Decimal32(v interface{}, s int32) driver.Valuer {
@@ -76,6 +132,12 @@ func Decimal128(v interface{}, s int32) driver.Valuer {
 	return decimal{128, s, v}
 }
 
+// Decimal256 converts value to Decimal256 of precision S.
+// The value can be a number or a string. The S (scale) parameter specifies the number of decimal places.
+func Decimal256(v interface{}, s int32) driver.Valuer {
+	return decimal{256, s, v}
+}
+
 type decimal struct {
 	p int32
 	s int32
@@ -84,7 +146,18 @@ type decimal struct {
 
 // Value implements driver.Valuer
 func (d decimal) Value() (driver.Value, error) {
-	return []byte(fmt.Sprintf("toDecimal%d('%v', %d)", d.p, d.v, d.s)), nil
+	return []byte(fmt.Sprintf("toDecimal%d('%s', %d)", d.p, d.digits(), d.s)), nil
+}
+
+// digits formats the value, the floats are formatted without an exponent
+func (d decimal) digits() string {
+	switch v := d.v.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(d.v)
 }
 
 // IP returns compatible database format for net.IP
@@ -2,6 +2,7 @@ package clickhouse
 
 import (
 	"database/sql/driver"
+	"math/big"
 	"net"
 	"testing"
 	"time"
@@ -61,6 +62,10 @@ func TestDecimal(t *testing.T) {
 	if assert.NoError(t, err) {
 		assert.Equal(t, []byte("toDecimal128('100.01', 1)"), dv)
 	}
+	dv, err = Decimal256(0.1, 2).Value()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte("toDecimal256('0.1', 2)"), dv)
+	}
 }
 
 func TestIP(t *testing.T) {
@@ -75,3 +80,31 @@ func TestIP(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "2001:44c8:129:2632:33:0:252:2", dv)
 }
+
+func TestBigInt(t *testing.T) {
+	maxInt128 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+	minInt128 := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 127))
+	maxUInt256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	testCases := []struct {
+		value    driver.Valuer
+		expected driver.Value
+		err      string
+	}{
+		{value: Int128(maxInt128), expected: []byte(maxInt128.String())},
+		{value: Int128(minInt128), expected: []byte(minInt128.String())},
+		{value: Int128(new(big.Int).Add(maxInt128, big.NewInt(1))), err: "value 170141183460469231731687303715884105728 is out of range of 128 bit integer"},
+		{value: Int128(new(big.Int).Sub(minInt128, big.NewInt(1))), err: "value -170141183460469231731687303715884105729 is out of range of 128 bit integer"},
+		{value: UInt128(big.NewInt(-1)), err: "value -1 is out of range of 128 bit integer"},
+		{value: UInt256(maxUInt256), expected: []byte(maxUInt256.String())},
+		{value: UInt256(new(big.Int).Add(maxUInt256, big.NewInt(1))), err: "value 115792089237316195423570985008687907853269984665640564039457584007913129639936 is out of range of 256 bit integer"},
+		{value: Int256(nil), expected: nil},
+	}
+	for _, tc := range testCases {
+		dv, err := tc.value.Value()
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+		} else if assert.NoError(t, err) {
+			assert.Equal(t, tc.expected, dv)
+		}
+	}
+}
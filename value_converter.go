@@ -2,6 +2,8 @@ package clickhouse
 
 import (
 	"database/sql/driver"
+	"math/big"
+	"net/netip"
 	"reflect"
 	"strconv"
 )
@@ -11,15 +13,56 @@ func (stmt *stmt) ColumnConverter(idx int) driver.ValueConverter {
 }
 
 func (c *conn) CheckNamedValue(nv *driver.NamedValue) (err error) {
+	if nv.Name != "" {
+		// named parameters are encoded by paramEncoder, which needs the original value
+		return nil
+	}
 	nv.Value, err = converter{}.ConvertValue(nv.Value)
 	return
 }
 
+// CheckNamedValue implements driver.NamedValueChecker
+func (stmt *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if stmt.batchMode && stmt.c.rowBinaryInsert && nv.Name == "" {
+		// the rows of a batch may be encoded in RowBinary, which needs the original value,
+		// it is converted by the batch if the rows are encoded as text
+		return nil
+	}
+	return stmt.c.CheckNamedValue(nv)
+}
+
+// convertValues converts the original values like CheckNamedValue does
+func convertValues(values []driver.Value) ([]driver.Value, error) {
+	converted := make([]driver.Value, len(values))
+	for i, v := range values {
+		var err error
+		if converted[i], err = (converter{}).ConvertValue(v); err != nil {
+			return nil, err
+		}
+	}
+	return converted, nil
+}
+
 type converter struct{}
 
 const maxAllowedUInt64 = 1<<63 - 1
 
 func (c converter) ConvertValue(v interface{}) (driver.Value, error) {
+	switch x := v.(type) {
+	case *big.Int:
+		if x == nil {
+			return nil, nil
+		}
+		return []byte(x.String()), nil
+	case big.Int:
+		return []byte(x.String()), nil
+	case netip.Addr:
+		return x.String(), nil
+	case netip.Prefix:
+		return x.String(), nil
+	case Variant:
+		return c.ConvertValue(x.Value)
+	}
 	if driver.IsValue(v) {
 		return v, nil
 	}
@@ -3,9 +3,12 @@ package clickhouse
 import (
 	"database/sql/driver"
 	"math"
+	"math/big"
+	"net/netip"
 	"reflect"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,6 +37,15 @@ func TestConverter(t *testing.T) {
 		{uint64(maxAllowedUInt64), uint64(9223372036854775807), "uint64(maxAllowedUInt64)"},
 		{uint64(maxAllowedUInt64 + 1), []byte("9223372036854775808"), "uint64(maxAllowedUInt64+1)"},
 		{uint64(maxAllowedUInt64*2 + 1), []byte("18446744073709551615"), "uint64(maxUInt64)"},
+		// big.Int
+		{new(big.Int).Lsh(big.NewInt(1), 127), []byte("170141183460469231731687303715884105728"), "*big.Int(1<<127)"},
+		{*big.NewInt(-1), []byte("-1"), "big.Int(-1)"},
+		{(*big.Int)(nil), nil, "*big.Int(nil)"},
+		// netip
+		{netip.MustParseAddr("127.0.0.1"), "127.0.0.1", "netip.Addr"},
+		{netip.MustParsePrefix("10.0.0.0/8"), "10.0.0.0/8", "netip.Prefix"},
+		// uuid.UUID implements driver.Valuer
+		{uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"), "123e4567-e89b-12d3-a456-426614174000", "uuid.UUID"},
 	}
 
 	for _, tc := range testCases {
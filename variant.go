@@ -0,0 +1,223 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Variant is a value of a Variant or Dynamic column together with its type.
+// When it is passed as a parameter, the Value is passed and the Type is used
+// only by the RowBinary encoding of batch inserts.
+type Variant struct {
+	// Type is the type of the value, e.g. UInt64. It is empty for the values
+	// of Dynamic columns read in TSV format, which are returned as text.
+	Type  string
+	Value interface{}
+}
+
+// Scan implements sql.Scanner
+func (v *Variant) Scan(src interface{}) error {
+	switch x := src.(type) {
+	case Variant:
+		*v = x
+	case nil:
+		*v = Variant{}
+	default:
+		*v = Variant{Value: src}
+	}
+	return nil
+}
+
+// readVariantText reads the text of a value whose type is not known in advance.
+// The whole input is read at the top level, and in arrays, tuples and maps
+// the value ends at a comma or a closing bracket outside of quotes and brackets.
+func readVariantText(s io.RuneScanner, unquote bool) string {
+	if !unquote {
+		return readRaw(s).String()
+	}
+	var (
+		b       strings.Builder
+		depth   int
+		inQuote bool
+	)
+	for {
+		r := read(s)
+		if r == eof {
+			return b.String()
+		}
+		if inQuote {
+			b.WriteRune(r)
+			switch r {
+			case '\\':
+				if escaped := read(s); escaped != eof {
+					b.WriteRune(escaped)
+				}
+			case '\'':
+				inQuote = false
+			}
+			continue
+		}
+		switch r {
+		case '\'':
+			inQuote = true
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}', ',':
+			if depth == 0 {
+				_ = s.UnreadRune()
+				return b.String()
+			}
+			if r != ',' {
+				depth--
+			}
+		}
+		b.WriteRune(r)
+	}
+}
+
+// isNullText reports whether the text of a value is NULL
+func isNullText(s string, unquote bool) bool {
+	if unquote {
+		return s == "NULL"
+	}
+	return s == `\N`
+}
+
+// variantArgs returns the types of a Variant in the order of their discriminators,
+// ClickHouse sorts the types by their names
+func variantArgs(t *TypeDesc) []*TypeDesc {
+	args := append([]*TypeDesc(nil), t.Args...)
+	sort.SliceStable(args, func(i, j int) bool {
+		return args[i].String() < args[j].String()
+	})
+	return args
+}
+
+// stringVariant reports whether the values of the type are strings,
+// they are tried last because any text is a string
+func stringVariant(t *TypeDesc) bool {
+	for t.Name == "LowCardinality" && len(t.Args) == 1 {
+		t = t.Args[0]
+	}
+	return t.Name == "String" || t.Name == "FixedString"
+}
+
+// inferType returns the type of a Dynamic value
+func inferType(v interface{}) (*TypeDesc, error) {
+	switch x := v.(type) {
+	case bool:
+		return &TypeDesc{Name: "Bool"}, nil
+	case int, int64:
+		return &TypeDesc{Name: "Int64"}, nil
+	case int8:
+		return &TypeDesc{Name: "Int8"}, nil
+	case int16:
+		return &TypeDesc{Name: "Int16"}, nil
+	case int32:
+		return &TypeDesc{Name: "Int32"}, nil
+	case uint, uint64:
+		return &TypeDesc{Name: "UInt64"}, nil
+	case uint8:
+		return &TypeDesc{Name: "UInt8"}, nil
+	case uint16:
+		return &TypeDesc{Name: "UInt16"}, nil
+	case uint32:
+		return &TypeDesc{Name: "UInt32"}, nil
+	case float32:
+		return &TypeDesc{Name: "Float32"}, nil
+	case float64:
+		return &TypeDesc{Name: "Float64"}, nil
+	case json.Number:
+		if _, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			return &TypeDesc{Name: "Int64"}, nil
+		}
+		if _, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			return &TypeDesc{Name: "UInt64"}, nil
+		}
+		return &TypeDesc{Name: "Float64"}, nil
+	case string, []byte:
+		return &TypeDesc{Name: "String"}, nil
+	case time.Time:
+		return &TypeDesc{Name: "DateTime64", Args: []*TypeDesc{{Name: "9"}}}, nil
+	case uuid.UUID:
+		return &TypeDesc{Name: "UUID"}, nil
+	case netip.Addr:
+		if x.Is4() {
+			return &TypeDesc{Name: "IPv4"}, nil
+		}
+		return &TypeDesc{Name: "IPv6"}, nil
+	case *big.Int:
+		return &TypeDesc{Name: "Int256"}, nil
+	case Point, Ring, Polygon, MultiPolygon:
+		return &TypeDesc{Name: reflect.TypeOf(v).Name()}, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("unsupported type %T of a Dynamic value", v)
+	}
+	var (
+		elem     *TypeDesc
+		nullable bool
+		empty    bool
+	)
+	for i := 0; i < rv.Len(); i++ {
+		x, err := binaryValue(rv.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		if x == nil {
+			nullable = true
+			continue
+		}
+		if rx := reflect.ValueOf(x); rx.Kind() == reflect.Slice && rx.Len() == 0 && rx.Type().Elem().Kind() != reflect.Uint8 {
+			// the empty arrays get the type of the other elements
+			empty = true
+			continue
+		}
+		t, err := inferType(x)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case elem == nil || elem.String() == t.String():
+			elem = t
+		case numericVariant(elem) && numericVariant(t):
+			// the numbers of different types are converted to floats like ClickHouse does
+			elem = &TypeDesc{Name: "Float64"}
+		default:
+			return nil, fmt.Errorf("array of values of different types %s and %s", elem, t)
+		}
+	}
+	switch {
+	case elem == nil && empty:
+		elem = &TypeDesc{Name: "Array", Args: []*TypeDesc{{Name: "Nullable", Args: []*TypeDesc{{Name: "String"}}}}}
+	case elem == nil:
+		// the types of the elements of an empty array are unknown
+		elem, nullable = &TypeDesc{Name: "String"}, true
+	case empty && elem.Name != "Array":
+		return nil, fmt.Errorf("array of values of different types %s and Array", elem)
+	}
+	if nullable && elem.Name != "Array" {
+		elem = &TypeDesc{Name: "Nullable", Args: []*TypeDesc{elem}}
+	}
+	return &TypeDesc{Name: "Array", Args: []*TypeDesc{elem}}, nil
+}
+
+// numericVariant reports whether the values of the type are numbers
+func numericVariant(t *TypeDesc) bool {
+	switch t.Name {
+	case "Int8", "Int16", "Int32", "Int64", "UInt8", "UInt16", "UInt32", "UInt64", "Float32", "Float64":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantScan(t *testing.T) {
+	var v Variant
+	require.NoError(t, v.Scan(Variant{Type: "UInt8", Value: uint8(1)}))
+	assert.Equal(t, Variant{Type: "UInt8", Value: uint8(1)}, v)
+	require.NoError(t, v.Scan("a"))
+	assert.Equal(t, Variant{Value: "a"}, v)
+	require.NoError(t, v.Scan(nil))
+	assert.Equal(t, Variant{}, v)
+}
+
+func TestInferType(t *testing.T) {
+	testCases := []struct {
+		value interface{}
+		typ   string
+	}{
+		{true, "Bool"},
+		{-1, "Int64"},
+		{uint16(1), "UInt16"},
+		{1.5, "Float64"},
+		{json.Number("18446744073709551615"), "UInt64"},
+		{json.Number("1e3"), "Float64"},
+		{"a", "String"},
+		{time.Now(), "DateTime64(9)"},
+		{netip.MustParseAddr("::1"), "IPv6"},
+		{big.NewInt(1), "Int256"},
+		{[]int{}, "Array(Nullable(String))"},
+		{[]interface{}{1, 1.5}, "Array(Float64)"},
+		{[]interface{}{"a", nil}, "Array(Nullable(String))"},
+		{[][]int8{{1}, nil}, "Array(Array(Int8))"},
+		{[][]string{{}}, "Array(Array(Nullable(String)))"},
+	}
+	for _, tc := range testCases {
+		typ, err := inferType(tc.value)
+		if assert.NoError(t, err, tc.typ) {
+			assert.Equal(t, tc.typ, typ.String())
+		}
+	}
+	_, err := inferType(map[string]int{})
+	assert.EqualError(t, err, "unsupported type map[string]int of a Dynamic value")
+}